@@ -0,0 +1,253 @@
+// Code generated by internal/gen from ../../../cadence. DO NOT EDIT.
+
+package kittyitems
+
+import (
+	"context"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+)
+
+// Paths of the Cadence sources, relative to the cadence directory
+const (
+	KibbleContract                         = "kibble/contracts/Kibble.cdc"
+	KibbleGetBalanceScript                 = "kibble/scripts/get_balance.cdc"
+	KibbleGetMinterAllowanceScript         = "kibble/scripts/get_minter_allowance.cdc"
+	KibbleGetSupplyScript                  = "kibble/scripts/get_supply.cdc"
+	KibbleBurnTokensTransaction            = "kibble/transactions/burn_tokens.cdc"
+	KibbleCreateMinterTransaction          = "kibble/transactions/create_minter.cdc"
+	KibbleMintTokensTransaction            = "kibble/transactions/mint_tokens.cdc"
+	KibbleMintTokensWithMinterTransaction  = "kibble/transactions/mint_tokens_with_minter.cdc"
+	KibbleSetupAccountTransaction          = "kibble/transactions/setup_account.cdc"
+	KibbleTopUpMinterTransaction           = "kibble/transactions/top_up_minter.cdc"
+	KibbleTransferTokensTransaction        = "kibble/transactions/transfer_tokens.cdc"
+	KittyItemsContract                     = "kittyItems/contracts/KittyItems.cdc"
+	NonFungibleTokenContract               = "kittyItems/contracts/NonFungibleToken.cdc"
+	KittyItemsReadCollectionIDsScript      = "kittyItems/scripts/read_collection_ids.cdc"
+	KittyItemsReadCollectionLengthScript   = "kittyItems/scripts/read_collection_length.cdc"
+	KittyItemsReadKittyItemTypeIDScript    = "kittyItems/scripts/read_kitty_item_type_id.cdc"
+	KittyItemsReadKittyItemsSupplyScript   = "kittyItems/scripts/read_kitty_items_supply.cdc"
+	KittyItemsMintKittyItemTransaction     = "kittyItems/transactions/mint_kitty_item.cdc"
+	KittyItemsSetupAccountTransaction      = "kittyItems/transactions/setup_account.cdc"
+	KittyItemsTransferKittyItemTransaction = "kittyItems/transactions/transfer_kitty_item.cdc"
+	KittyItemsMarketContract               = "kittyItemsMarket/contracts/KittyItemsMarket.cdc"
+	MarketReadCollectionIDsScript          = "kittyItemsMarket/scripts/read_collection_ids.cdc"
+	MarketReadCollectionLengthScript       = "kittyItemsMarket/scripts/read_collection_length.cdc"
+	MarketReadSaleOfferDetailsScript       = "kittyItemsMarket/scripts/read_sale_offer_details.cdc"
+	MarketBuyMarketItemTransaction         = "kittyItemsMarket/transactions/buy_market_item.cdc"
+	MarketRemoveMarketItemTransaction      = "kittyItemsMarket/transactions/remove_market_item.cdc"
+	MarketSellMarketItemTransaction        = "kittyItemsMarket/transactions/sell_market_item.cdc"
+	MarketSetupAccountTransaction          = "kittyItemsMarket/transactions/setup_account.cdc"
+)
+
+// KibbleGetBalance runs kibble/scripts/get_balance.cdc
+func (c Contracts) KibbleGetBalance(ctx context.Context, executor ScriptExecutor, account flow.Address) (result cadence.UFix64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KibbleGetBalanceScript), []cadence.Value{cadence.NewAddress(account)})
+	if err != nil {
+		return result, err
+	}
+	return decodeUFix64(value)
+}
+
+// KibbleGetMinterAllowance runs kibble/scripts/get_minter_allowance.cdc
+func (c Contracts) KibbleGetMinterAllowance(ctx context.Context, executor ScriptExecutor, account flow.Address) (result cadence.UFix64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KibbleGetMinterAllowanceScript), []cadence.Value{cadence.NewAddress(account)})
+	if err != nil {
+		return result, err
+	}
+	return decodeUFix64(value)
+}
+
+// KibbleGetSupply runs kibble/scripts/get_supply.cdc
+func (c Contracts) KibbleGetSupply(ctx context.Context, executor ScriptExecutor) (result cadence.UFix64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KibbleGetSupplyScript), nil)
+	if err != nil {
+		return result, err
+	}
+	return decodeUFix64(value)
+}
+
+// KibbleBurnTokens builds kibble/transactions/burn_tokens.cdc, authorized by signer
+func (c Contracts) KibbleBurnTokens(signer flow.Address, amount cadence.UFix64) (*flow.Transaction, error) {
+	return c.Transaction(KibbleBurnTokensTransaction, []flow.Address{signer}, amount)
+}
+
+// KibbleCreateMinter builds kibble/transactions/create_minter.cdc, authorized by signer
+func (c Contracts) KibbleCreateMinter(signer flow.Address, allowedAmount cadence.UFix64) (*flow.Transaction, error) {
+	return c.Transaction(KibbleCreateMinterTransaction, []flow.Address{signer}, allowedAmount)
+}
+
+// KibbleMintTokens builds kibble/transactions/mint_tokens.cdc, authorized by signer
+func (c Contracts) KibbleMintTokens(signer flow.Address, recipient flow.Address, amount cadence.UFix64) (*flow.Transaction, error) {
+	return c.Transaction(KibbleMintTokensTransaction, []flow.Address{signer}, cadence.NewAddress(recipient), amount)
+}
+
+// KibbleMintTokensWithMinter builds kibble/transactions/mint_tokens_with_minter.cdc, authorized by signer
+func (c Contracts) KibbleMintTokensWithMinter(signer flow.Address, recipient flow.Address, amount cadence.UFix64) (*flow.Transaction, error) {
+	return c.Transaction(KibbleMintTokensWithMinterTransaction, []flow.Address{signer}, cadence.NewAddress(recipient), amount)
+}
+
+// KibbleSetupAccount builds kibble/transactions/setup_account.cdc, authorized by signer
+func (c Contracts) KibbleSetupAccount(signer flow.Address) (*flow.Transaction, error) {
+	return c.Transaction(KibbleSetupAccountTransaction, []flow.Address{signer})
+}
+
+// KibbleTopUpMinter builds kibble/transactions/top_up_minter.cdc, authorized by signer
+func (c Contracts) KibbleTopUpMinter(signer flow.Address, amount cadence.UFix64) (*flow.Transaction, error) {
+	return c.Transaction(KibbleTopUpMinterTransaction, []flow.Address{signer}, amount)
+}
+
+// KibbleTransferTokens builds kibble/transactions/transfer_tokens.cdc, authorized by signer
+func (c Contracts) KibbleTransferTokens(signer flow.Address, amount cadence.UFix64, to flow.Address) (*flow.Transaction, error) {
+	return c.Transaction(KibbleTransferTokensTransaction, []flow.Address{signer}, amount, cadence.NewAddress(to))
+}
+
+// KittyItemsReadCollectionIDs runs kittyItems/scripts/read_collection_ids.cdc
+func (c Contracts) KittyItemsReadCollectionIDs(ctx context.Context, executor ScriptExecutor, account flow.Address) (result []uint64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KittyItemsReadCollectionIDsScript), []cadence.Value{cadence.NewAddress(account)})
+	if err != nil {
+		return result, err
+	}
+	values, err := decodeArray(value)
+	if err != nil {
+		return nil, err
+	}
+	result = make([]uint64, len(values))
+	for i, element := range values {
+		if result[i], err = decodeUInt64(element); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// KittyItemsReadCollectionLength runs kittyItems/scripts/read_collection_length.cdc
+func (c Contracts) KittyItemsReadCollectionLength(ctx context.Context, executor ScriptExecutor, account flow.Address) (result int, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KittyItemsReadCollectionLengthScript), []cadence.Value{cadence.NewAddress(account)})
+	if err != nil {
+		return result, err
+	}
+	return decodeInt(value)
+}
+
+// KittyItemsReadKittyItemTypeID runs kittyItems/scripts/read_kitty_item_type_id.cdc
+func (c Contracts) KittyItemsReadKittyItemTypeID(ctx context.Context, executor ScriptExecutor, account flow.Address, itemID uint64) (result uint64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KittyItemsReadKittyItemTypeIDScript), []cadence.Value{cadence.NewAddress(account), cadence.NewUInt64(itemID)})
+	if err != nil {
+		return result, err
+	}
+	return decodeUInt64(value)
+}
+
+// KittyItemsReadKittyItemsSupply runs kittyItems/scripts/read_kitty_items_supply.cdc
+func (c Contracts) KittyItemsReadKittyItemsSupply(ctx context.Context, executor ScriptExecutor) (result uint64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(KittyItemsReadKittyItemsSupplyScript), nil)
+	if err != nil {
+		return result, err
+	}
+	return decodeUInt64(value)
+}
+
+// KittyItemsMintKittyItem builds kittyItems/transactions/mint_kitty_item.cdc, authorized by signer
+func (c Contracts) KittyItemsMintKittyItem(signer flow.Address, recipient flow.Address, typeID uint64) (*flow.Transaction, error) {
+	return c.Transaction(KittyItemsMintKittyItemTransaction, []flow.Address{signer}, cadence.NewAddress(recipient), cadence.NewUInt64(typeID))
+}
+
+// KittyItemsSetupAccount builds kittyItems/transactions/setup_account.cdc, authorized by acct
+func (c Contracts) KittyItemsSetupAccount(acct flow.Address) (*flow.Transaction, error) {
+	return c.Transaction(KittyItemsSetupAccountTransaction, []flow.Address{acct})
+}
+
+// KittyItemsTransferKittyItem builds kittyItems/transactions/transfer_kitty_item.cdc, authorized by acct
+func (c Contracts) KittyItemsTransferKittyItem(acct flow.Address, recipient flow.Address, withdrawID uint64) (*flow.Transaction, error) {
+	return c.Transaction(KittyItemsTransferKittyItemTransaction, []flow.Address{acct}, cadence.NewAddress(recipient), cadence.NewUInt64(withdrawID))
+}
+
+// MarketReadCollectionIDs runs kittyItemsMarket/scripts/read_collection_ids.cdc
+func (c Contracts) MarketReadCollectionIDs(ctx context.Context, executor ScriptExecutor, account flow.Address) (result []uint64, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(MarketReadCollectionIDsScript), []cadence.Value{cadence.NewAddress(account)})
+	if err != nil {
+		return result, err
+	}
+	values, err := decodeArray(value)
+	if err != nil {
+		return nil, err
+	}
+	result = make([]uint64, len(values))
+	for i, element := range values {
+		if result[i], err = decodeUInt64(element); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// MarketReadCollectionLength runs kittyItemsMarket/scripts/read_collection_length.cdc
+func (c Contracts) MarketReadCollectionLength(ctx context.Context, executor ScriptExecutor, account flow.Address) (result int, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(MarketReadCollectionLengthScript), []cadence.Value{cadence.NewAddress(account)})
+	if err != nil {
+		return result, err
+	}
+	return decodeInt(value)
+}
+
+// MarketSaleOfferDetails is the SaleOfferDetails struct returned by kittyItemsMarket/scripts/read_sale_offer_details.cdc
+type MarketSaleOfferDetails struct {
+	SaleCompleted bool
+	SaleItemID    uint64
+	SalePrice     cadence.UFix64
+}
+
+func decodeMarketSaleOfferDetails(value cadence.Value) (decoded MarketSaleOfferDetails, err error) {
+	fields, err := decodeStruct(value, 3)
+	if err != nil {
+		return decoded, err
+	}
+	if decoded.SaleCompleted, err = decodeBool(fields[0]); err != nil {
+		return decoded, err
+	}
+	if decoded.SaleItemID, err = decodeUInt64(fields[1]); err != nil {
+		return decoded, err
+	}
+	if decoded.SalePrice, err = decodeUFix64(fields[2]); err != nil {
+		return decoded, err
+	}
+	return decoded, nil
+}
+
+// MarketReadSaleOfferDetails runs kittyItemsMarket/scripts/read_sale_offer_details.cdc, the result is nil when the script returns nil
+func (c Contracts) MarketReadSaleOfferDetails(ctx context.Context, executor ScriptExecutor, account flow.Address, saleItemID uint64) (result *MarketSaleOfferDetails, err error) {
+	value, err := executor.ExecuteScript(ctx, c.Code(MarketReadSaleOfferDetailsScript), []cadence.Value{cadence.NewAddress(account), cadence.NewUInt64(saleItemID)})
+	if err != nil {
+		return result, err
+	}
+	if value = optionalValue(value); value == nil {
+		return nil, nil
+	}
+	decoded, err := decodeMarketSaleOfferDetails(value)
+	if err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// MarketBuyMarketItem builds kittyItemsMarket/transactions/buy_market_item.cdc, authorized by acct
+func (c Contracts) MarketBuyMarketItem(acct flow.Address, saleItemID uint64, marketCollectionAddress flow.Address) (*flow.Transaction, error) {
+	return c.Transaction(MarketBuyMarketItemTransaction, []flow.Address{acct}, cadence.NewUInt64(saleItemID), cadence.NewAddress(marketCollectionAddress))
+}
+
+// MarketRemoveMarketItem builds kittyItemsMarket/transactions/remove_market_item.cdc, authorized by acct
+func (c Contracts) MarketRemoveMarketItem(acct flow.Address, saleItemID uint64) (*flow.Transaction, error) {
+	return c.Transaction(MarketRemoveMarketItemTransaction, []flow.Address{acct}, cadence.NewUInt64(saleItemID))
+}
+
+// MarketSellMarketItem builds kittyItemsMarket/transactions/sell_market_item.cdc, authorized by acct
+func (c Contracts) MarketSellMarketItem(acct flow.Address, saleItemID uint64, saleItemPrice cadence.UFix64) (*flow.Transaction, error) {
+	return c.Transaction(MarketSellMarketItemTransaction, []flow.Address{acct}, cadence.NewUInt64(saleItemID), saleItemPrice)
+}
+
+// MarketSetupAccount builds kittyItemsMarket/transactions/setup_account.cdc, authorized by acct
+func (c Contracts) MarketSetupAccount(acct flow.Address) (*flow.Transaction, error) {
+	return c.Transaction(MarketSetupAccountTransaction, []flow.Address{acct})
+}
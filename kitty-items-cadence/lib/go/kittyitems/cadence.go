@@ -0,0 +1,1367 @@
+// Code generated by internal/gen from ../../../cadence. DO NOT EDIT.
+
+package kittyitems
+
+var cadenceSources = map[string]string{
+	"kibble/contracts/Kibble.cdc": "import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"\n" +
+		"pub contract Kibble: FungibleToken {\n" +
+		"    // TokensInitialized\n" +
+		"    //\n" +
+		"    // The event that is emitted when the contract is created\n" +
+		"    pub event TokensInitialized(initialSupply: UFix64)\n" +
+		"\n" +
+		"    // TokensWithdrawn\n" +
+		"    //\n" +
+		"    // The event that is emitted when tokens are withdrawn from a Vault\n" +
+		"    pub event TokensWithdrawn(amount: UFix64, from: Address?)\n" +
+		"\n" +
+		"    // TokensDeposited\n" +
+		"    //\n" +
+		"    // The event that is emitted when tokens are deposited to a Vault\n" +
+		"    pub event TokensDeposited(amount: UFix64, to: Address?)\n" +
+		"\n" +
+		"    // TokensMinted\n" +
+		"    //\n" +
+		"    // The event that is emitted when new tokens are minted\n" +
+		"    pub event TokensMinted(amount: UFix64)\n" +
+		"\n" +
+		"    // TokensBurned\n" +
+		"    //\n" +
+		"    // The event that is emitted when tokens are destroyed\n" +
+		"    pub event TokensBurned(amount: UFix64)\n" +
+		"\n" +
+		"    // MinterCreated\n" +
+		"    //\n" +
+		"    // The event that is emitted when a new minter resource is created\n" +
+		"    pub event MinterCreated(allowedAmount: UFix64)\n" +
+		"\n" +
+		"    // Named paths\n" +
+		"    //\n" +
+		"    pub let VaultStoragePath: Path\n" +
+		"    pub let ReceiverPublicPath: Path\n" +
+		"    pub let BalancePublicPath: Path\n" +
+		"    pub let AdminStoragePath: Path\n" +
+		"\n" +
+		"    // Total supply of Kibbles in existence\n" +
+		"    pub var totalSupply: UFix64\n" +
+		"\n" +
+		"    // Vault\n" +
+		"    //\n" +
+		"    // Each user stores an instance of only the Vault in their storage\n" +
+		"    // The functions in the Vault and governed by the pre and post conditions\n" +
+		"    // in FungibleToken when they are called.\n" +
+		"    // The checks happen at runtime whenever a function is called.\n" +
+		"    //\n" +
+		"    // Resources can only be created in the context of the contract that they\n" +
+		"    // are defined in, so there is no way for a malicious user to create Vaults\n" +
+		"    // out of thin air. A special Minter resource needs to be defined to mint\n" +
+		"    // new tokens.\n" +
+		"    //\n" +
+		"    pub resource Vault: FungibleToken.Provider, FungibleToken.Receiver, FungibleToken.Balance {\n" +
+		"\n" +
+		"        // The total balance of this vault\n" +
+		"        pub var balance: UFix64\n" +
+		"\n" +
+		"        // initialize the balance at resource creation time\n" +
+		"        init(balance: UFix64) {\n" +
+		"            self.balance = balance\n" +
+		"        }\n" +
+		"\n" +
+		"        // withdraw\n" +
+		"        //\n" +
+		"        // Function that takes an amount as an argument\n" +
+		"        // and withdraws that amount from the Vault.\n" +
+		"        //\n" +
+		"        // It creates a new temporary Vault that is used to hold\n" +
+		"        // the money that is being transferred. It returns the newly\n" +
+		"        // created Vault to the context that called so it can be deposited\n" +
+		"        // elsewhere.\n" +
+		"        //\n" +
+		"        pub fun withdraw(amount: UFix64): @FungibleToken.Vault {\n" +
+		"            self.balance = self.balance - amount\n" +
+		"            emit TokensWithdrawn(amount: amount, from: self.owner?.address)\n" +
+		"            return <-create Vault(balance: amount)\n" +
+		"        }\n" +
+		"\n" +
+		"        // deposit\n" +
+		"        //\n" +
+		"        // Function that takes a Vault object as an argument and adds\n" +
+		"        // its balance to the balance of the owners Vault.\n" +
+		"        //\n" +
+		"        // It is allowed to destroy the sent Vault because the Vault\n" +
+		"        // was a temporary holder of the tokens. The Vault's balance has\n" +
+		"        // been consumed and therefore can be destroyed.\n" +
+		"        //\n" +
+		"        pub fun deposit(from: @FungibleToken.Vault) {\n" +
+		"            let vault <- from as! @Kibble.Vault\n" +
+		"            self.balance = self.balance + vault.balance\n" +
+		"            emit TokensDeposited(amount: vault.balance, to: self.owner?.address)\n" +
+		"            vault.balance = 0.0\n" +
+		"            destroy vault\n" +
+		"        }\n" +
+		"\n" +
+		"        destroy() {\n" +
+		"            Kibble.totalSupply = Kibble.totalSupply - self.balance\n" +
+		"            if(self.balance > 0.0) {\n" +
+		"                emit TokensBurned(amount: self.balance)\n" +
+		"            }\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // createEmptyVault\n" +
+		"    //\n" +
+		"    // Function that creates a new Vault with a balance of zero\n" +
+		"    // and returns it to the calling context. A user must call this function\n" +
+		"    // and store the returned Vault in their storage in order to allow their\n" +
+		"    // account to be able to receive deposits of this token type.\n" +
+		"    //\n" +
+		"    pub fun createEmptyVault(): @Vault {\n" +
+		"        return <-create Vault(balance: 0.0)\n" +
+		"    }\n" +
+		"\n" +
+		"    pub resource Administrator {\n" +
+		"\n" +
+		"        // createNewMinter\n" +
+		"        //\n" +
+		"        // Function that creates and returns a new minter resource\n" +
+		"        //\n" +
+		"        pub fun createNewMinter(allowedAmount: UFix64): @Minter {\n" +
+		"            emit MinterCreated(allowedAmount: allowedAmount)\n" +
+		"            return <-create Minter(allowedAmount: allowedAmount)\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // MinterPublic\n" +
+		"    //\n" +
+		"    // Read-only view of a Minter so that its remaining allowance\n" +
+		"    // can be checked from a script.\n" +
+		"    //\n" +
+		"    pub resource interface MinterPublic {\n" +
+		"        pub var allowedAmount: UFix64\n" +
+		"    }\n" +
+		"\n" +
+		"    // Minter\n" +
+		"    //\n" +
+		"    // Resource object that token admin accounts can hold to mint new tokens.\n" +
+		"    //\n" +
+		"    pub resource Minter: MinterPublic {\n" +
+		"\n" +
+		"        // The amount of tokens that the minter is allowed to mint\n" +
+		"        pub var allowedAmount: UFix64\n" +
+		"\n" +
+		"        // mintTokens\n" +
+		"        //\n" +
+		"        // Function that mints new tokens, adds them to the total supply,\n" +
+		"        // and returns them to the calling context.\n" +
+		"        //\n" +
+		"        pub fun mintTokens(amount: UFix64): @Kibble.Vault {\n" +
+		"            pre {\n" +
+		"                amount > 0.0: \"Amount minted must be greater than zero\"\n" +
+		"                amount <= self.allowedAmount: \"Amount minted must be less than the allowed amount\"\n" +
+		"            }\n" +
+		"            Kibble.totalSupply = Kibble.totalSupply + amount\n" +
+		"            self.allowedAmount = self.allowedAmount - amount\n" +
+		"            emit TokensMinted(amount: amount)\n" +
+		"            return <-create Vault(balance: amount)\n" +
+		"        }\n" +
+		"\n" +
+		"        init(allowedAmount: UFix64) {\n" +
+		"            self.allowedAmount = allowedAmount\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    init() {\n" +
+		"        // Set our named paths.\n" +
+		"        //FIXME: REMOVE SUFFIX BEFORE RELEASE\n" +
+		"        self.VaultStoragePath = /storage/KibbleVault000\n" +
+		"        self.ReceiverPublicPath = /public/KibbleReceiver000\n" +
+		"        self.BalancePublicPath = /public/KibbleBalance000\n" +
+		"        self.AdminStoragePath = /storage/KibbleAdmin000\n" +
+		"\n" +
+		"        // Initialize contract state.\n" +
+		"        self.totalSupply = 0.0\n" +
+		"\n" +
+		"        // Create the one true Admin object and deposit it into the conttract account.\n" +
+		"        let admin <- create Administrator()\n" +
+		"        self.account.save(<-admin, to: self.AdminStoragePath)\n" +
+		"\n" +
+		"        // Emit an event that shows that the contract was initialized.\n" +
+		"        emit TokensInitialized(initialSupply: self.totalSupply)\n" +
+		"    }\n" +
+		"}\n",
+	"kibble/scripts/get_balance.cdc": "// This script reads the balance field of an account's Kibble Balance\n" +
+		"\n" +
+		"import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"pub fun main(account: Address): UFix64 {\n" +
+		"    let acct = getAccount(account)\n" +
+		"    let vaultRef = acct.getCapability(Kibble.BalancePublicPath)!.borrow<&Kibble.Vault{FungibleToken.Balance}>()\n" +
+		"        ?? panic(\"Could not borrow Balance reference to the Vault\")\n" +
+		"\n" +
+		"    return vaultRef.balance\n" +
+		"}\n",
+	"kibble/scripts/get_minter_allowance.cdc": "// This script reads the remaining allowance of the Minter stored in an account\n" +
+		"\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"pub fun main(account: Address): UFix64 {\n" +
+		"    let minterRef = getAccount(account)\n" +
+		"        .getCapability(/public/KibbleMinter000)!\n" +
+		"        .borrow<&Kibble.Minter{Kibble.MinterPublic}>()\n" +
+		"        ?? panic(\"Could not borrow a reference to the minter\")\n" +
+		"\n" +
+		"    return minterRef.allowedAmount\n" +
+		"}\n",
+	"kibble/scripts/get_supply.cdc": "// This script reads the total supply field\n" +
+		"// of the Kibble smart contract\n" +
+		"\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"pub fun main(): UFix64 {\n" +
+		"\n" +
+		"    let supply = Kibble.totalSupply\n" +
+		"\n" +
+		"    log(supply)\n" +
+		"\n" +
+		"    return supply\n" +
+		"}",
+	"kibble/transactions/burn_tokens.cdc": "// This transaction destroys tokens of the signer's vault,\n" +
+		"// which removes them from the total supply\n" +
+		"\n" +
+		"import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction(amount: UFix64) {\n" +
+		"\n" +
+		"    // The Vault resource that holds the tokens that are being burned\n" +
+		"    let burnedVault: @FungibleToken.Vault\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"\n" +
+		"        // Get a reference to the signer's stored vault\n" +
+		"        let vaultRef = signer.borrow<&Kibble.Vault>(from: Kibble.VaultStoragePath)\n" +
+		"            ?? panic(\"Could not borrow reference to the owner's Vault!\")\n" +
+		"\n" +
+		"        // Withdraw tokens from the signer's stored vault\n" +
+		"        self.burnedVault <- vaultRef.withdraw(amount: amount)\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"\n" +
+		"        // Destroying the vault subtracts its balance from the total supply\n" +
+		"        destroy self.burnedVault\n" +
+		"    }\n" +
+		"}\n",
+	"kibble/transactions/create_minter.cdc": "// This transaction creates a long-lived Minter resource with a bounded\n" +
+		"// allowance and stores it in the signer's account, which must hold\n" +
+		"// the Kibble Administrator resource.\n" +
+		"\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction(allowedAmount: UFix64) {\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"        let admin = signer.borrow<&Kibble.Administrator>(from: Kibble.AdminStoragePath)\n" +
+		"            ?? panic(\"Signer is not the token admin\")\n" +
+		"\n" +
+		"        if signer.borrow<&Kibble.Minter>(from: /storage/KibbleMinter000) != nil {\n" +
+		"            panic(\"A minter already exists, use top_up_minter to raise its allowance\")\n" +
+		"        }\n" +
+		"\n" +
+		"        signer.save(<-admin.createNewMinter(allowedAmount: allowedAmount), to: /storage/KibbleMinter000)\n" +
+		"\n" +
+		"        // Only expose the remaining allowance publicly\n" +
+		"        signer.link<&Kibble.Minter{Kibble.MinterPublic}>(\n" +
+		"            /public/KibbleMinter000,\n" +
+		"            target: /storage/KibbleMinter000\n" +
+		"        )\n" +
+		"    }\n" +
+		"}\n",
+	"kibble/transactions/mint_tokens.cdc": "import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction(recipient: Address, amount: UFix64) {\n" +
+		"    let tokenAdmin: &Kibble.Administrator\n" +
+		"    let tokenReceiver: &{FungibleToken.Receiver}\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"        self.tokenAdmin = signer\n" +
+		"        .borrow<&Kibble.Administrator>(from: Kibble.AdminStoragePath)\n" +
+		"        ?? panic(\"Signer is not the token admin\")\n" +
+		"\n" +
+		"        self.tokenReceiver = getAccount(recipient)\n" +
+		"        .getCapability(Kibble.ReceiverPublicPath)!\n" +
+		"        .borrow<&{FungibleToken.Receiver}>()\n" +
+		"        ?? panic(\"Unable to borrow receiver reference\")\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"        let minter <- self.tokenAdmin.createNewMinter(allowedAmount: amount)\n" +
+		"        let mintedVault <- minter.mintTokens(amount: amount)\n" +
+		"\n" +
+		"        self.tokenReceiver.deposit(from: <-mintedVault)\n" +
+		"\n" +
+		"        destroy minter\n" +
+		"    }\n" +
+		"}",
+	"kibble/transactions/mint_tokens_with_minter.cdc": "// This transaction mints tokens with the signer's stored Minter, so the\n" +
+		"// amount is deducted from the allowance it was provisioned with.\n" +
+		"\n" +
+		"import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction(recipient: Address, amount: UFix64) {\n" +
+		"    let minter: &Kibble.Minter\n" +
+		"    let tokenReceiver: &{FungibleToken.Receiver}\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"        self.minter = signer\n" +
+		"        .borrow<&Kibble.Minter>(from: /storage/KibbleMinter000)\n" +
+		"        ?? panic(\"Signer does not store a minter\")\n" +
+		"\n" +
+		"        self.tokenReceiver = getAccount(recipient)\n" +
+		"        .getCapability(Kibble.ReceiverPublicPath)!\n" +
+		"        .borrow<&{FungibleToken.Receiver}>()\n" +
+		"        ?? panic(\"Unable to borrow receiver reference\")\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"        let mintedVault <- self.minter.mintTokens(amount: amount)\n" +
+		"\n" +
+		"        self.tokenReceiver.deposit(from: <-mintedVault)\n" +
+		"    }\n" +
+		"}\n",
+	"kibble/transactions/setup_account.cdc": "\n" +
+		"// This transaction is a template for a transaction\n" +
+		"// to add a Vault resource to their account\n" +
+		"// so that they can use the Kibble\n" +
+		"\n" +
+		"import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction {\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"\n" +
+		"        if signer.borrow<&Kibble.Vault>(from: Kibble.VaultStoragePath) == nil {\n" +
+		"            // Create a new Kibble Vault and put it in storage\n" +
+		"            signer.save(<-Kibble.createEmptyVault(), to: Kibble.VaultStoragePath)\n" +
+		"\n" +
+		"            // Create a public capability to the Vault that only exposes\n" +
+		"            // the deposit function through the Receiver interface\n" +
+		"            signer.link<&Kibble.Vault{FungibleToken.Receiver}>(\n" +
+		"                Kibble.ReceiverPublicPath,\n" +
+		"                target: Kibble.VaultStoragePath\n" +
+		"            )\n" +
+		"\n" +
+		"            // Create a public capability to the Vault that only exposes\n" +
+		"            // the balance field through the Balance interface\n" +
+		"            signer.link<&Kibble.Vault{FungibleToken.Balance}>(\n" +
+		"                Kibble.BalancePublicPath,\n" +
+		"                target: Kibble.VaultStoragePath\n" +
+		"            )\n" +
+		"        }\n" +
+		"    }\n" +
+		"}\n" +
+		" ",
+	"kibble/transactions/top_up_minter.cdc": "// This transaction raises the allowance of the signer's stored Minter by\n" +
+		"// replacing it with a new Minter allowed to mint the remaining amount plus\n" +
+		"// the top up. The signer must hold the Kibble Administrator resource.\n" +
+		"\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction(amount: UFix64) {\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"        let admin = signer.borrow<&Kibble.Administrator>(from: Kibble.AdminStoragePath)\n" +
+		"            ?? panic(\"Signer is not the token admin\")\n" +
+		"\n" +
+		"        let oldMinter <- signer.load<@Kibble.Minter>(from: /storage/KibbleMinter000)\n" +
+		"            ?? panic(\"No minter to top up, use create_minter first\")\n" +
+		"        let allowedAmount = oldMinter.allowedAmount + amount\n" +
+		"        destroy oldMinter\n" +
+		"\n" +
+		"        signer.save(<-admin.createNewMinter(allowedAmount: allowedAmount), to: /storage/KibbleMinter000)\n" +
+		"    }\n" +
+		"}\n",
+	"kibble/transactions/transfer_tokens.cdc": "// This transaction is a template for a transaction that\n" +
+		"// could be used by anyone to send tokens to another account\n" +
+		"// that has been set up to receive tokens.\n" +
+		"//\n" +
+		"// The withdraw amount and the account from getAccount\n" +
+		"// would be the parameters to the transaction\n" +
+		"\n" +
+		"import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"\n" +
+		"transaction(amount: UFix64, to: Address) {\n" +
+		"\n" +
+		"    // The Vault resource that holds the tokens that are being transferred\n" +
+		"    let sentVault: @FungibleToken.Vault\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"\n" +
+		"        // Get a reference to the signer's stored vault\n" +
+		"        let vaultRef = signer.borrow<&Kibble.Vault>(from: Kibble.VaultStoragePath)\n" +
+		"\t\t\t?? panic(\"Could not borrow reference to the owner's Vault!\")\n" +
+		"\n" +
+		"        // Withdraw tokens from the signer's stored vault\n" +
+		"        self.sentVault <- vaultRef.withdraw(amount: amount)\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"\n" +
+		"        // Get the recipient's public account object\n" +
+		"        let recipient = getAccount(to)\n" +
+		"\n" +
+		"        // Get a reference to the recipient's Receiver\n" +
+		"        let receiverRef = recipient.getCapability(Kibble.ReceiverPublicPath)!.borrow<&{FungibleToken.Receiver}>()\n" +
+		"\t\t\t?? panic(\"Could not borrow receiver reference to the recipient's Vault\")\n" +
+		"\n" +
+		"        // Deposit the withdrawn tokens in the recipient's receiver\n" +
+		"        receiverRef.deposit(from: <-self.sentVault)\n" +
+		"    }\n" +
+		"}\n" +
+		" ",
+	"kittyItems/contracts/KittyItems.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"\n" +
+		"// KittyItems\n" +
+		"// NFT items for Kitties!\n" +
+		"//\n" +
+		"pub contract KittyItems: NonFungibleToken {\n" +
+		"\n" +
+		"    // Events\n" +
+		"    //\n" +
+		"    pub event ContractInitialized()\n" +
+		"    pub event Withdraw(id: UInt64, from: Address?)\n" +
+		"    pub event Deposit(id: UInt64, to: Address?)\n" +
+		"    pub event Minted(id: UInt64, typeID: UInt64)\n" +
+		"\n" +
+		"    // Named Paths\n" +
+		"    //\n" +
+		"    pub let CollectionStoragePath: Path\n" +
+		"    pub let CollectionPublicPath: Path\n" +
+		"    pub let MinterStoragePath: Path\n" +
+		"\n" +
+		"    // totalSupply\n" +
+		"    // The total number of KittyItems that have been minted\n" +
+		"    //\n" +
+		"    pub var totalSupply: UInt64\n" +
+		"\n" +
+		"    // NFT\n" +
+		"    // A Kitty Item as an NFT\n" +
+		"    //\n" +
+		"    pub resource NFT: NonFungibleToken.INFT {\n" +
+		"        // The token's ID\n" +
+		"        pub let id: UInt64\n" +
+		"        // The token's type, e.g. 3 == Hat\n" +
+		"        pub let typeID: UInt64\n" +
+		"\n" +
+		"        // initializer\n" +
+		"        //\n" +
+		"        init(initID: UInt64, initTypeID: UInt64) {\n" +
+		"            self.id = initID\n" +
+		"            self.typeID = initTypeID\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // This is the interface that users can cast their KittyItems Collection as\n" +
+		"    // to allow others to deposit KittyItems into their Collection. It also allows for reading\n" +
+		"    // the details of KittyItems in the Collection.\n" +
+		"    pub resource interface KittyItemsCollectionPublic {\n" +
+		"        pub fun deposit(token: @NonFungibleToken.NFT)\n" +
+		"        pub fun getIDs(): [UInt64]\n" +
+		"        pub fun borrowNFT(id: UInt64): &NonFungibleToken.NFT\n" +
+		"        pub fun borrowKittyItem(id: UInt64): &KittyItems.NFT? {\n" +
+		"            // If the result isn't nil, the id of the returned reference\n" +
+		"            // should be the same as the argument to the function\n" +
+		"            post {\n" +
+		"                (result == nil) || (result?.id == id):\n" +
+		"                    \"Cannot borrow KittyItem reference: The ID of the returned reference is incorrect\"\n" +
+		"            }\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // Collection\n" +
+		"    // A collection of KittyItem NFTs owned by an account\n" +
+		"    //\n" +
+		"    pub resource Collection: KittyItemsCollectionPublic, NonFungibleToken.Provider, NonFungibleToken.Receiver, NonFungibleToken.CollectionPublic {\n" +
+		"        // dictionary of NFT conforming tokens\n" +
+		"        // NFT is a resource type with an `UInt64` ID field\n" +
+		"        //\n" +
+		"        pub var ownedNFTs: @{UInt64: NonFungibleToken.NFT}\n" +
+		"\n" +
+		"        // withdraw\n" +
+		"        // Removes an NFT from the collection and moves it to the caller\n" +
+		"        //\n" +
+		"        pub fun withdraw(withdrawID: UInt64): @NonFungibleToken.NFT {\n" +
+		"            let token <- self.ownedNFTs.remove(key: withdrawID) ?? panic(\"missing NFT\")\n" +
+		"\n" +
+		"            emit Withdraw(id: token.id, from: self.owner?.address)\n" +
+		"\n" +
+		"            return <-token\n" +
+		"        }\n" +
+		"\n" +
+		"        // deposit\n" +
+		"        // Takes a NFT and adds it to the collections dictionary\n" +
+		"        // and adds the ID to the id array\n" +
+		"        //\n" +
+		"        pub fun deposit(token: @NonFungibleToken.NFT) {\n" +
+		"            let token <- token as! @KittyItems.NFT\n" +
+		"\n" +
+		"            let id: UInt64 = token.id\n" +
+		"\n" +
+		"            // add the new token to the dictionary which removes the old one\n" +
+		"            let oldToken <- self.ownedNFTs[id] <- token\n" +
+		"\n" +
+		"            emit Deposit(id: id, to: self.owner?.address)\n" +
+		"\n" +
+		"            destroy oldToken\n" +
+		"        }\n" +
+		"\n" +
+		"        // getIDs\n" +
+		"        // Returns an array of the IDs that are in the collection\n" +
+		"        //\n" +
+		"        pub fun getIDs(): [UInt64] {\n" +
+		"            return self.ownedNFTs.keys\n" +
+		"        }\n" +
+		"\n" +
+		"        // borrowNFT\n" +
+		"        // Gets a reference to an NFT in the collection\n" +
+		"        // so that the caller can read its metadata and call its methods\n" +
+		"        //\n" +
+		"        pub fun borrowNFT(id: UInt64): &NonFungibleToken.NFT {\n" +
+		"            return &self.ownedNFTs[id] as &NonFungibleToken.NFT\n" +
+		"        }\n" +
+		"\n" +
+		"        // borrowKittyItem\n" +
+		"        // Gets a reference to an NFT in the collection as a KittyItem,\n" +
+		"        // exposing all of its fields (including the typeID).\n" +
+		"        // This is safe as there are no functions that can be called on the KittyItem.\n" +
+		"        //\n" +
+		"        pub fun borrowKittyItem(id: UInt64): &KittyItems.NFT? {\n" +
+		"            if self.ownedNFTs[id] != nil {\n" +
+		"                let ref = &self.ownedNFTs[id] as auth &NonFungibleToken.NFT\n" +
+		"                return ref as! &KittyItems.NFT\n" +
+		"            } else {\n" +
+		"                return nil\n" +
+		"            }\n" +
+		"        }\n" +
+		"\n" +
+		"        // destructor\n" +
+		"        destroy() {\n" +
+		"            destroy self.ownedNFTs\n" +
+		"        }\n" +
+		"\n" +
+		"        // initializer\n" +
+		"        //\n" +
+		"        init () {\n" +
+		"            self.ownedNFTs <- {}\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // createEmptyCollection\n" +
+		"    // public function that anyone can call to create a new empty collection\n" +
+		"    //\n" +
+		"    pub fun createEmptyCollection(): @NonFungibleToken.Collection {\n" +
+		"        return <- create Collection()\n" +
+		"    }\n" +
+		"\n" +
+		"    // NFTMinter\n" +
+		"    // Resource that an admin or something similar would own to be\n" +
+		"    // able to mint new NFTs\n" +
+		"    //\n" +
+		"\tpub resource NFTMinter {\n" +
+		"\n" +
+		"\t\t// mintNFT\n" +
+		"        // Mints a new NFT with a new ID\n" +
+		"\t\t// and deposit it in the recipients collection using their collection reference\n" +
+		"        //\n" +
+		"\t\tpub fun mintNFT(recipient: &{NonFungibleToken.CollectionPublic}, typeID: UInt64) {\n" +
+		"            emit Minted(id: KittyItems.totalSupply, typeID: typeID)\n" +
+		"\n" +
+		"\t\t\t// deposit it in the recipient's account using their reference\n" +
+		"\t\t\trecipient.deposit(token: <-create KittyItems.NFT(initID: KittyItems.totalSupply, initTypeID: typeID))\n" +
+		"\n" +
+		"            KittyItems.totalSupply = KittyItems.totalSupply + (1 as UInt64)\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"\n" +
+		"    // fetch\n" +
+		"    // Get a reference to a KittyItem from an account's Collection, if available.\n" +
+		"    // If an account does not have a KittyItems.Collection, panic.\n" +
+		"    // If it has a collection but does not contain the itemId, return nil.\n" +
+		"    // If it has a collection and that collection contains the itemId, retrun a reference to that.\n" +
+		"    //\n" +
+		"    pub fun fetch(_ from: Address, itemID: UInt64): &KittyItems.NFT? {\n" +
+		"        let collection = getAccount(from)\n" +
+		"            .getCapability(KittyItems.CollectionPublicPath)!\n" +
+		"            .borrow<&KittyItems.Collection{KittyItems.KittyItemsCollectionPublic}>()\n" +
+		"            ?? panic(\"Couldn't get collection\")\n" +
+		"        // We trust KittyItems.Collection.borowKittyItem to get the correct itemID\n" +
+		"        // (it checks it before returning it).\n" +
+		"        return collection.borrowKittyItem(id: itemID)\n" +
+		"    }\n" +
+		"\n" +
+		"    // initializer\n" +
+		"    //\n" +
+		"\tinit() {\n" +
+		"        // Set our named paths\n" +
+		"        //FIXME: REMOVE SUFFIX BEFORE RELEASE\n" +
+		"        self.CollectionStoragePath = /storage/KittyItemsCollection000\n" +
+		"        self.CollectionPublicPath = /public/KittyItemsCollection000\n" +
+		"        self.MinterStoragePath = /storage/KittyItemsMinter000\n" +
+		"\n" +
+		"        // Initialize the total supply\n" +
+		"        self.totalSupply = 0\n" +
+		"\n" +
+		"        // Create a Minter resource and save it to storage\n" +
+		"        let minter <- create NFTMinter()\n" +
+		"        self.account.save(<-minter, to: self.MinterStoragePath)\n" +
+		"\n" +
+		"        emit ContractInitialized()\n" +
+		"\t}\n" +
+		"}\n",
+	"kittyItems/contracts/NonFungibleToken.cdc": "/**\n" +
+		"\n" +
+		"## The Flow Non-Fungible Token standard\n" +
+		"\n" +
+		"## `NonFungibleToken` contract interface\n" +
+		"\n" +
+		"The interface that all non-fungible token contracts could conform to.\n" +
+		"If a user wants to deploy a new nft contract, their contract would need\n" +
+		"to implement the NonFungibleToken interface.\n" +
+		"\n" +
+		"Their contract would have to follow all the rules and naming\n" +
+		"that the interface specifies.\n" +
+		"\n" +
+		"## `NFT` resource\n" +
+		"\n" +
+		"The core resource type that represents an NFT in the smart contract.\n" +
+		"\n" +
+		"## `Collection` Resource\n" +
+		"\n" +
+		"The resource that stores a user's NFT collection.\n" +
+		"It includes a few functions to allow the owner to easily\n" +
+		"move tokens in and out of the collection.\n" +
+		"\n" +
+		"## `Provider` and `Receiver` resource interfaces\n" +
+		"\n" +
+		"These interfaces declare functions with some pre and post conditions\n" +
+		"that require the Collection to follow certain naming and behavior standards.\n" +
+		"\n" +
+		"They are separate because it gives the user the ability to share a reference\n" +
+		"to their Collection that only exposes the fields and functions in one or more\n" +
+		"of the interfaces. It also gives users the ability to make custom resources\n" +
+		"that implement these interfaces to do various things with the tokens.\n" +
+		"\n" +
+		"By using resources and interfaces, users of NFT smart contracts can send\n" +
+		"and receive tokens peer-to-peer, without having to interact with a central ledger\n" +
+		"smart contract.\n" +
+		"\n" +
+		"To send an NFT to another user, a user would simply withdraw the NFT\n" +
+		"from their Collection, then call the deposit function on another user's\n" +
+		"Collection to complete the transfer.\n" +
+		"\n" +
+		"*/\n" +
+		"\n" +
+		"// The main NFT contract interface. Other NFT contracts will\n" +
+		"// import and implement this interface\n" +
+		"//\n" +
+		"pub contract interface NonFungibleToken {\n" +
+		"\n" +
+		"    // The total number of tokens of this type in existence\n" +
+		"    pub var totalSupply: UInt64\n" +
+		"\n" +
+		"    // Event that emitted when the NFT contract is initialized\n" +
+		"    //\n" +
+		"    pub event ContractInitialized()\n" +
+		"\n" +
+		"    // Event that is emitted when a token is withdrawn,\n" +
+		"    // indicating the owner of the collection that it was withdrawn from.\n" +
+		"    //\n" +
+		"    // If the collection is not in an account's storage, `from` will be `nil`.\n" +
+		"    //\n" +
+		"    pub event Withdraw(id: UInt64, from: Address?)\n" +
+		"\n" +
+		"    // Event that emitted when a token is deposited to a collection.\n" +
+		"    //\n" +
+		"    // It indicates the owner of the collection that it was deposited to.\n" +
+		"    //\n" +
+		"    pub event Deposit(id: UInt64, to: Address?)\n" +
+		"\n" +
+		"    // Interface that the NFTs have to conform to\n" +
+		"    //\n" +
+		"    pub resource interface INFT {\n" +
+		"        // The unique ID that each NFT has\n" +
+		"        pub let id: UInt64\n" +
+		"    }\n" +
+		"\n" +
+		"    // Requirement that all conforming NFT smart contracts have\n" +
+		"    // to define a resource called NFT that conforms to INFT\n" +
+		"    pub resource NFT: INFT {\n" +
+		"        pub let id: UInt64\n" +
+		"    }\n" +
+		"\n" +
+		"    // Interface to mediate withdraws from the Collection\n" +
+		"    //\n" +
+		"    pub resource interface Provider {\n" +
+		"        // withdraw removes an NFT from the collection and moves it to the caller\n" +
+		"        pub fun withdraw(withdrawID: UInt64): @NFT {\n" +
+		"            post {\n" +
+		"                result.id == withdrawID: \"The ID of the withdrawn token must be the same as the requested ID\"\n" +
+		"            }\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // Interface to mediate deposits to the Collection\n" +
+		"    //\n" +
+		"    pub resource interface Receiver {\n" +
+		"\n" +
+		"        // deposit takes an NFT as an argument and adds it to the Collection\n" +
+		"        //\n" +
+		"\t\tpub fun deposit(token: @NFT)\n" +
+		"    }\n" +
+		"\n" +
+		"    // Interface that an account would commonly \n" +
+		"    // publish for their collection\n" +
+		"    pub resource interface CollectionPublic {\n" +
+		"        pub fun deposit(token: @NFT)\n" +
+		"        pub fun getIDs(): [UInt64]\n" +
+		"        pub fun borrowNFT(id: UInt64): &NFT\n" +
+		"    }\n" +
+		"\n" +
+		"    // Requirement for the the concrete resource type\n" +
+		"    // to be declared in the implementing contract\n" +
+		"    //\n" +
+		"    pub resource Collection: Provider, Receiver, CollectionPublic {\n" +
+		"\n" +
+		"        // Dictionary to hold the NFTs in the Collection\n" +
+		"        pub var ownedNFTs: @{UInt64: NFT}\n" +
+		"\n" +
+		"        // withdraw removes an NFT from the collection and moves it to the caller\n" +
+		"        pub fun withdraw(withdrawID: UInt64): @NFT\n" +
+		"\n" +
+		"        // deposit takes a NFT and adds it to the collections dictionary\n" +
+		"        // and adds the ID to the id array\n" +
+		"        pub fun deposit(token: @NFT)\n" +
+		"\n" +
+		"        // getIDs returns an array of the IDs that are in the collection\n" +
+		"        pub fun getIDs(): [UInt64]\n" +
+		"\n" +
+		"        // Returns a borrowed reference to an NFT in the collection\n" +
+		"        // so that the caller can read data and call methods from it\n" +
+		"        pub fun borrowNFT(id: UInt64): &NFT {\n" +
+		"            pre {\n" +
+		"                self.ownedNFTs[id] != nil: \"NFT does not exist in the collection!\"\n" +
+		"            }\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // createEmptyCollection creates an empty Collection\n" +
+		"    // and returns it to the caller so that they can own NFTs\n" +
+		"    pub fun createEmptyCollection(): @Collection {\n" +
+		"        post {\n" +
+		"            result.getIDs().length == 0: \"The created collection must be empty!\"\n" +
+		"        }\n" +
+		"    }\n" +
+		"}",
+	"kittyItems/scripts/read_collection_ids.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"// This transaction returns an array of all the nft ids in the collection\n" +
+		"\n" +
+		"pub fun main(account: Address): [UInt64] {\n" +
+		"    let acct = getAccount(account)\n" +
+		"    let collectionRef = acct.getCapability(KittyItems.CollectionPublicPath)!.borrow<&{NonFungibleToken.CollectionPublic}>()\n" +
+		"        ?? panic(\"Could not borrow capability from public collection\")\n" +
+		"    \n" +
+		"    return collectionRef.getIDs()\n" +
+		"}\n" +
+		" ",
+	"kittyItems/scripts/read_collection_length.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"// This transaction gets the length of an account's nft collection\n" +
+		"\n" +
+		"pub fun main(account: Address): Int {\n" +
+		"    let acct = getAccount(account)\n" +
+		"    let collectionRef = acct.getCapability(KittyItems.CollectionPublicPath)!\n" +
+		"        .borrow<&{NonFungibleToken.CollectionPublic}>()\n" +
+		"        ?? panic(\"Could not borrow capability from public collection\")\n" +
+		"    \n" +
+		"    return collectionRef.getIDs().length\n" +
+		"}\n" +
+		" ",
+	"kittyItems/scripts/read_kitty_item_type_id.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"// This script reads metadata about an NFT in a user's collection\n" +
+		"pub fun main(account: Address, itemID: UInt64): UInt64 {\n" +
+		"\n" +
+		"    // Get the public account object of the owner of the token\n" +
+		"    let owner = getAccount(account)\n" +
+		"\n" +
+		"    let collectionBorrow = owner.getCapability(KittyItems.CollectionPublicPath)!\n" +
+		"        .borrow<&{KittyItems.KittyItemsCollectionPublic}>()\n" +
+		"        ?? panic(\"Could not borrow KittyItemsCollectionPublic\")\n" +
+		"\n" +
+		"    // Borrow a reference to a specific NFT in the collection\n" +
+		"    let kittyItem = collectionBorrow.borrowKittyItem(id: itemID)\n" +
+		"        ?? panic(\"No such itemID in that collection\")\n" +
+		"\n" +
+		"    return kittyItem.typeID\n" +
+		"}\n",
+	"kittyItems/scripts/read_kitty_items_supply.cdc": "import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"// This transaction gets the number of KittyItems currently in existence\n" +
+		"\n" +
+		"pub fun main(): UInt64 {    \n" +
+		"    return KittyItems.totalSupply\n" +
+		"}\n" +
+		" ",
+	"kittyItems/transactions/mint_kitty_item.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"// This script uses the NFTMinter resource to mint a new NFT\n" +
+		"// It must be run with the account that has the minter resource\n" +
+		"// stored in /storage/NFTMinter\n" +
+		"\n" +
+		"transaction(recipient: Address, typeID: UInt64) {\n" +
+		"    \n" +
+		"    // local variable for storing the minter reference\n" +
+		"    let minter: &KittyItems.NFTMinter\n" +
+		"\n" +
+		"    prepare(signer: AuthAccount) {\n" +
+		"\n" +
+		"        // borrow a reference to the NFTMinter resource in storage\n" +
+		"        self.minter = signer.borrow<&KittyItems.NFTMinter>(from: KittyItems.MinterStoragePath)\n" +
+		"            ?? panic(\"Could not borrow a reference to the NFT minter\")\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"        // Get the public account object for the recipient\n" +
+		"        let recipient = getAccount(recipient)\n" +
+		"\n" +
+		"        // Borrow the recipient's public NFT collection reference\n" +
+		"        let receiver = recipient\n" +
+		"            .getCapability(KittyItems.CollectionPublicPath)!\n" +
+		"            .borrow<&{NonFungibleToken.CollectionPublic}>()\n" +
+		"            ?? panic(\"Could not get receiver reference to the NFT Collection\")\n" +
+		"\n" +
+		"        // Mint the NFT and deposit it to the recipient's collection\n" +
+		"        self.minter.mintNFT(recipient: receiver, typeID: typeID)\n" +
+		"    }\n" +
+		"}\n",
+	"kittyItems/transactions/setup_account.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"// This transaction is what an account would run\n" +
+		"// to set itself up to receive NFTs\n" +
+		"\n" +
+		"transaction {\n" +
+		"    prepare(acct: AuthAccount) {\n" +
+		"\n" +
+		"        // If the account doesn't already have a collection\n" +
+		"        if acct.borrow<&KittyItems.Collection>(from: KittyItems.CollectionStoragePath) == nil {\n" +
+		"\n" +
+		"            // Create a new empty collection\n" +
+		"            let collection <- KittyItems.createEmptyCollection()\n" +
+		"            \n" +
+		"            // save it to the account\n" +
+		"            acct.save(<-collection, to: KittyItems.CollectionStoragePath)\n" +
+		"\n" +
+		"            // create a public capability for the collection\n" +
+		"            acct.link<&KittyItems.Collection{NonFungibleToken.CollectionPublic, KittyItems.KittyItemsCollectionPublic}>(KittyItems.CollectionPublicPath, target: KittyItems.CollectionStoragePath)\n" +
+		"        }\n" +
+		"    }\n" +
+		"}\n",
+	"kittyItems/transactions/transfer_kitty_item.cdc": "import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"// This transaction is for transferring and NFT from \n" +
+		"// one account to another\n" +
+		"\n" +
+		"transaction(recipient: Address, withdrawID: UInt64) {\n" +
+		"    prepare(acct: AuthAccount) {\n" +
+		"        \n" +
+		"        // get the recipients public account object\n" +
+		"        let recipient = getAccount(recipient)\n" +
+		"\n" +
+		"        // borrow a reference to the signer's NFT collection\n" +
+		"        let collectionRef = acct.borrow<&KittyItems.Collection>(from: KittyItems.CollectionStoragePath)\n" +
+		"            ?? panic(\"Could not borrow a reference to the owner's collection\")\n" +
+		"\n" +
+		"        // borrow a public reference to the receivers collection\n" +
+		"        let depositRef = recipient.getCapability(KittyItems.CollectionPublicPath)!.borrow<&{NonFungibleToken.CollectionPublic}>()!\n" +
+		"\n" +
+		"        // withdraw the NFT from the owner's collection\n" +
+		"        let nft <- collectionRef.withdraw(withdrawID: withdrawID)\n" +
+		"\n" +
+		"        // Deposit the NFT in the recipient's collection\n" +
+		"        depositRef.deposit(token: <-nft)\n" +
+		"    }\n" +
+		"}\n",
+	"kittyItemsMarket/contracts/KittyItemsMarket.cdc": "import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"\n" +
+		"/*\n" +
+		"    This is a simple KittyItems initial sale contract for the DApp to use\n" +
+		"    in order to list and sell KittyItems.\n" +
+		"\n" +
+		"    Its structure is neither what it would be if it was the simplest possible\n" +
+		"    marjet contract or if it was a complete general purpose market contract.\n" +
+		"    Rather it's the simplest possible version of a more general purpose\n" +
+		"    market contract that indicates how that contract might function in\n" +
+		"    broad strokes. This has been done so that integrating with this contract\n" +
+		"    is a useful preparatory exercise for code that will integrate with the\n" +
+		"    later more general purpose market contract.\n" +
+		"\n" +
+		"    It allows:\n" +
+		"    - Anyone to create Sale Offers and place them in a collection, making it\n" +
+		"      publicly accessible.\n" +
+		"    - Anyone to accept the offer and buy the item.\n" +
+		"\n" +
+		"    It notably does not handle:\n" +
+		"    - Multiple different sale NFT contracts.\n" +
+		"    - Multiple different payment FT contracts.\n" +
+		"    - Splitting sale payments to multiple recipients.\n" +
+		"\n" +
+		" */\n" +
+		"\n" +
+		"pub contract KittyItemsMarket {\n" +
+		"    // SaleOffer events.\n" +
+		"    //\n" +
+		"    // A sale offer has been created.\n" +
+		"    pub event SaleOfferCreated(itemID: UInt64, price: UFix64)\n" +
+		"    // Someone has purchased an item that was offered for sale.\n" +
+		"    pub event SaleOfferAccepted(itemID: UInt64)\n" +
+		"    // A sale offer has been destroyed, with or without being accepted.\n" +
+		"    pub event SaleOfferFinished(itemID: UInt64)\n" +
+		"    \n" +
+		"    // Collection events.\n" +
+		"    //\n" +
+		"    // A sale offer has been inserted into the collection of Address.\n" +
+		"    pub event CollectionInsertedSaleOffer(saleItemID: UInt64, saleItemCollection: Address)\n" +
+		"    // A sale offer has been removed from the collection of Address.\n" +
+		"    pub event CollectionRemovedSaleOffer(saleItemID: UInt64, saleItemCollection: Address)\n" +
+		"\n" +
+		"    // Named paths\n" +
+		"    //\n" +
+		"    pub let CollectionStoragePath: Path\n" +
+		"    pub let CollectionPublicPath: Path\n" +
+		"\n" +
+		"    // SaleOfferPublicView\n" +
+		"    // An interface providing a read-only view of a SaleOffer\n" +
+		"    //\n" +
+		"    pub resource interface SaleOfferPublicView {\n" +
+		"        pub var saleCompleted: Bool\n" +
+		"        pub let saleItemID: UInt64\n" +
+		"        pub let salePrice: UFix64\n" +
+		"    }\n" +
+		"\n" +
+		"    // SaleOffer\n" +
+		"    // A KittyItems NFT being offered to sale for a set fee paid in Kibble.\n" +
+		"    //\n" +
+		"    pub resource SaleOffer: SaleOfferPublicView {\n" +
+		"        // Whether the sale has completed with someone purchasing the item.\n" +
+		"        pub var saleCompleted: Bool\n" +
+		"\n" +
+		"        // The KittyItems NFT ID for sale.\n" +
+		"        pub let saleItemID: UInt64\n" +
+		"        // The collection containing that ID.\n" +
+		"        access(self) let sellerItemProvider: Capability<&KittyItems.Collection{NonFungibleToken.Provider}>\n" +
+		"\n" +
+		"        // The sale payment price.\n" +
+		"        pub let salePrice: UFix64\n" +
+		"        // The Kibble vault that will receive that payment if teh sale completes successfully.\n" +
+		"        access(self) let sellerPaymentReceiver: Capability<&Kibble.Vault{FungibleToken.Receiver}>\n" +
+		"\n" +
+		"        // Called by a purchaser to accept the sale offer.\n" +
+		"        // If they send the correct payment in Kibble, and if the item is still available,\n" +
+		"        // the KittyItems NFT will be placed in their KittyItems.Collection .\n" +
+		"        //\n" +
+		"        pub fun accept(\n" +
+		"            buyerCollection: &KittyItems.Collection{NonFungibleToken.Receiver},\n" +
+		"            buyerPayment: @FungibleToken.Vault\n" +
+		"        ) {\n" +
+		"            pre {\n" +
+		"                buyerPayment.balance == self.salePrice: \"payment does not equal offer price\"\n" +
+		"                self.saleCompleted == false: \"the sale offer has already been accepted\"\n" +
+		"            }\n" +
+		"\n" +
+		"            self.saleCompleted = true\n" +
+		"\n" +
+		"            self.sellerPaymentReceiver.borrow()!.deposit(from: <-buyerPayment)\n" +
+		"\n" +
+		"            let nft <- self.sellerItemProvider.borrow()!.withdraw(withdrawID: self.saleItemID)\n" +
+		"            buyerCollection.deposit(token: <-nft)\n" +
+		"\n" +
+		"            emit SaleOfferAccepted(itemID: self.saleItemID)\n" +
+		"        }\n" +
+		"\n" +
+		"        // destructor\n" +
+		"        //\n" +
+		"        destroy() {\n" +
+		"            // Whether the sale completed or not, publicize that it is being withdrawn.\n" +
+		"            emit SaleOfferFinished(itemID: self.saleItemID)\n" +
+		"        }\n" +
+		"\n" +
+		"        // initializer\n" +
+		"        // Take the information required to create a sale offer, notably the capability\n" +
+		"        // to transfer the KittyItems NFT and the capability to receive Kibble in payment.\n" +
+		"        //\n" +
+		"        init(\n" +
+		"            sellerItemProvider: Capability<&KittyItems.Collection{NonFungibleToken.Provider}>,\n" +
+		"            saleItemID: UInt64,\n" +
+		"            sellerPaymentReceiver: Capability<&Kibble.Vault{FungibleToken.Receiver}>,\n" +
+		"            salePrice: UFix64\n" +
+		"        ) {\n" +
+		"            pre {\n" +
+		"                sellerItemProvider.borrow() != nil: \"Cannot borrow seller\"\n" +
+		"                sellerPaymentReceiver.borrow() != nil: \"Cannot borrow sellerPaymentReceiver\"\n" +
+		"            }\n" +
+		"\n" +
+		"            self.saleCompleted = false\n" +
+		"\n" +
+		"            self.sellerItemProvider = sellerItemProvider\n" +
+		"            self.saleItemID = saleItemID\n" +
+		"\n" +
+		"            self.sellerPaymentReceiver = sellerPaymentReceiver\n" +
+		"            self.salePrice = salePrice\n" +
+		"\n" +
+		"            emit SaleOfferCreated(itemID: self.saleItemID, price: self.salePrice)\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // createSaleOffer\n" +
+		"    // Make creating a SaleOffer publicly accessible.\n" +
+		"    //\n" +
+		"    pub fun createSaleOffer (\n" +
+		"        sellerItemProvider: Capability<&KittyItems.Collection{NonFungibleToken.Provider}>,\n" +
+		"        saleItemID: UInt64,\n" +
+		"        sellerPaymentReceiver: Capability<&Kibble.Vault{FungibleToken.Receiver}>,\n" +
+		"        salePrice: UFix64\n" +
+		"    ): @SaleOffer {\n" +
+		"        return <-create SaleOffer(\n" +
+		"            sellerItemProvider: sellerItemProvider,\n" +
+		"            saleItemID: saleItemID,\n" +
+		"            sellerPaymentReceiver: sellerPaymentReceiver,\n" +
+		"            salePrice: salePrice\n" +
+		"        )\n" +
+		"    }\n" +
+		"\n" +
+		"    // CollectionManager\n" +
+		"    // An interface for adding and removing SaleOffers to a collection, intended for\n" +
+		"    // use by the collection's owner.\n" +
+		"    //\n" +
+		"    pub resource interface CollectionManager {\n" +
+		"        pub fun insert(offer: @KittyItemsMarket.SaleOffer)\n" +
+		"        pub fun remove(saleItemID: UInt64): @SaleOffer \n" +
+		"    }\n" +
+		"\n" +
+		"        // CollectionPurchaser\n" +
+		"    // An interface to allow purchasing items via SaleOffers in a collection.\n" +
+		"    // This function is also provided by CollectionPublic, it is here to support\n" +
+		"    // more fine-grained access to the collection for as yet unspecified future use cases.\n" +
+		"    //\n" +
+		"    pub resource interface CollectionPurchaser {\n" +
+		"        pub fun purchase(\n" +
+		"            saleItemID: UInt64,\n" +
+		"            buyerCollection: &KittyItems.Collection{NonFungibleToken.Receiver},\n" +
+		"            buyerPayment: @FungibleToken.Vault\n" +
+		"        )\n" +
+		"    }\n" +
+		"\n" +
+		"    // CollectionPublic\n" +
+		"    // An interface to allow listing and borrowing SaleOffers, and purchasing items via SaleOffers in a collection.\n" +
+		"    //\n" +
+		"    pub resource interface CollectionPublic {\n" +
+		"        pub fun getSaleOfferIDs(): [UInt64]\n" +
+		"        pub fun borrowSaleItem(saleItemID: UInt64): &SaleOffer{SaleOfferPublicView}?\n" +
+		"        pub fun purchase(\n" +
+		"            saleItemID: UInt64,\n" +
+		"            buyerCollection: &KittyItems.Collection{NonFungibleToken.Receiver},\n" +
+		"            buyerPayment: @FungibleToken.Vault\n" +
+		"        )\n" +
+		"   }\n" +
+		"\n" +
+		"    // Collection\n" +
+		"    // A resource that allows its owner to manage a list of SaleOffers, and purchasers to interact with them.\n" +
+		"    //\n" +
+		"    pub resource Collection : CollectionManager, CollectionPurchaser, CollectionPublic {\n" +
+		"        pub var saleOffers: @{UInt64: SaleOffer}\n" +
+		"\n" +
+		"        // insert\n" +
+		"        // Insert a SaleOffer into the collection, replacing one with the same saleItemID if present.\n" +
+		"        //\n" +
+		"         pub fun insert(offer: @KittyItemsMarket.SaleOffer) {\n" +
+		"            let id: UInt64 = offer.saleItemID\n" +
+		"\n" +
+		"            // add the new offer to the dictionary which removes the old one\n" +
+		"            let oldOffer <- self.saleOffers[id] <- offer\n" +
+		"            destroy oldOffer\n" +
+		"\n" +
+		"            emit CollectionInsertedSaleOffer(saleItemID: id, saleItemCollection: self.owner?.address!)\n" +
+		"        }\n" +
+		"\n" +
+		"        // remove\n" +
+		"        // Remove and return a SaleOffer from the collection.\n" +
+		"        pub fun remove(saleItemID: UInt64): @SaleOffer {\n" +
+		"            emit CollectionRemovedSaleOffer(saleItemID: saleItemID, saleItemCollection: self.owner?.address!)\n" +
+		"            return <-(self.saleOffers.remove(key: saleItemID) ?? panic(\"missing SaleOffer\"))\n" +
+		"        }\n" +
+		" \n" +
+		"        // purchase\n" +
+		"        // If the caller passes a valid saleItemID and the item is still for sale, and passes a Kibble vault\n" +
+		"        // typed as a FungibleToken.Vault (Kibble.deposit() handles the type safety of this)\n" +
+		"        // containing the correct payment amount, this will transfer the KittyItem to the caller's\n" +
+		"        // KittyItems collection.\n" +
+		"        // It will then remove and destroy the offer.\n" +
+		"        // Note that is means that events will be emitted in this order:\n" +
+		"        //   1. Collection.CollectionRemovedSaleOffer\n" +
+		"        //   2. KittyItems.Withdraw\n" +
+		"        //   3. KittyItems.Deposit\n" +
+		"        //   4. SaleOffer.SaleOfferFinished\n" +
+		"        //\n" +
+		"        pub fun purchase(\n" +
+		"            saleItemID: UInt64,\n" +
+		"            buyerCollection: &KittyItems.Collection{NonFungibleToken.Receiver},\n" +
+		"            buyerPayment: @FungibleToken.Vault\n" +
+		"        ) {\n" +
+		"            pre {\n" +
+		"                self.saleOffers[saleItemID] != nil: \"SaleOffer does not exist in the collection!\"\n" +
+		"            }\n" +
+		"            let offer <- self.remove(saleItemID: saleItemID)\n" +
+		"            offer.accept(buyerCollection: buyerCollection, buyerPayment: <-buyerPayment)\n" +
+		"            //FIXME: Is this correct? Or should we return it to the caller to dispose of?\n" +
+		"            destroy offer\n" +
+		"        }\n" +
+		"\n" +
+		"        // getSaleOfferIDs\n" +
+		"        // Returns an array of the IDs that are in the collection\n" +
+		"        //\n" +
+		"        pub fun getSaleOfferIDs(): [UInt64] {\n" +
+		"            return self.saleOffers.keys\n" +
+		"        }\n" +
+		"\n" +
+		"        // borrowSaleItem\n" +
+		"        // Returns an Optional read-only view of the SaleItem for the given saleItemID if it is contained by this collection.\n" +
+		"        // The optional will be nil if the provided saleItemID is not present in the collection.\n" +
+		"        //\n" +
+		"        pub fun borrowSaleItem(saleItemID: UInt64): &SaleOffer{SaleOfferPublicView}? {\n" +
+		"            if self.saleOffers[saleItemID] == nil {\n" +
+		"                return nil\n" +
+		"            } else {\n" +
+		"                return &self.saleOffers[saleItemID] as &SaleOffer{SaleOfferPublicView}\n" +
+		"            }\n" +
+		"        }\n" +
+		"\n" +
+		"        // destructor\n" +
+		"        //\n" +
+		"        destroy () {\n" +
+		"            destroy self.saleOffers\n" +
+		"        }\n" +
+		"\n" +
+		"        // constructor\n" +
+		"        //\n" +
+		"        init () {\n" +
+		"            self.saleOffers <- {}\n" +
+		"        }\n" +
+		"    }\n" +
+		"\n" +
+		"    // createEmptyCollection\n" +
+		"    // Make creating a Collection publicly accessible.\n" +
+		"    //\n" +
+		"    pub fun createEmptyCollection(): @Collection {\n" +
+		"        return <-create Collection()\n" +
+		"    }\n" +
+		"\n" +
+		"    init () {\n" +
+		"        //FIXME: REMOVE SUFFIX BEFORE RELEASE\n" +
+		"        self.CollectionStoragePath = /storage/KittyItemsMarketCollection000\n" +
+		"        self.CollectionPublicPath = /public/KittyItemsMarketCollection000\n" +
+		"    }\n" +
+		"}\n",
+	"kittyItemsMarket/scripts/read_collection_ids.cdc": "import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"\n" +
+		"// This script returns an array of all the nft ids for sale in the collection\n" +
+		"\n" +
+		"pub fun main(account: Address): [UInt64] {\n" +
+		"    let marketCollectionRef = getAccount(account)\n" +
+		"        .getCapability<&KittyItemsMarket.Collection{KittyItemsMarket.CollectionPublic}>(\n" +
+		"            KittyItemsMarket.CollectionPublicPath\n" +
+		"        )!\n" +
+		"        .borrow()\n" +
+		"        ?? panic(\"Could not borrow market collection from market address\")\n" +
+		"    \n" +
+		"    return marketCollectionRef.getSaleOfferIDs()\n" +
+		"}\n",
+	"kittyItemsMarket/scripts/read_collection_length.cdc": "import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"\n" +
+		"// This script gets the number of sale offers in an account's market collection\n" +
+		"\n" +
+		"pub fun main(account: Address): Int {\n" +
+		"    let marketCollectionRef = getAccount(account)\n" +
+		"        .getCapability<&KittyItemsMarket.Collection{KittyItemsMarket.CollectionPublic}>(\n" +
+		"             KittyItemsMarket.CollectionPublicPath\n" +
+		"        )!\n" +
+		"        .borrow()\n" +
+		"        ?? panic(\"Could not borrow market collection from market address\")\n" +
+		"    \n" +
+		"    return marketCollectionRef.getSaleOfferIDs().length\n" +
+		"}\n",
+	"kittyItemsMarket/scripts/read_sale_offer_details.cdc": "import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"\n" +
+		"// This script returns the details of the sale offer for an item in the market collection of an account,\n" +
+		"// or nil when the collection has no offer for that item\n" +
+		"\n" +
+		"pub struct SaleOfferDetails {\n" +
+		"    pub let saleCompleted: Bool\n" +
+		"    pub let saleItemID: UInt64\n" +
+		"    pub let salePrice: UFix64\n" +
+		"\n" +
+		"    init(saleCompleted: Bool, saleItemID: UInt64, salePrice: UFix64) {\n" +
+		"        self.saleCompleted = saleCompleted\n" +
+		"        self.saleItemID = saleItemID\n" +
+		"        self.salePrice = salePrice\n" +
+		"    }\n" +
+		"}\n" +
+		"\n" +
+		"pub fun main(account: Address, saleItemID: UInt64): SaleOfferDetails? {\n" +
+		"    let marketCollectionRef = getAccount(account)\n" +
+		"        .getCapability<&KittyItemsMarket.Collection{KittyItemsMarket.CollectionPublic}>(\n" +
+		"            KittyItemsMarket.CollectionPublicPath\n" +
+		"        )!\n" +
+		"        .borrow()\n" +
+		"        ?? panic(\"Could not borrow market collection from market address\")\n" +
+		"\n" +
+		"    if let saleOffer = marketCollectionRef.borrowSaleItem(saleItemID: saleItemID) {\n" +
+		"        return SaleOfferDetails(\n" +
+		"            saleCompleted: saleOffer.saleCompleted,\n" +
+		"            saleItemID: saleOffer.saleItemID,\n" +
+		"            salePrice: saleOffer.salePrice\n" +
+		"        )\n" +
+		"    }\n" +
+		"\n" +
+		"    return nil\n" +
+		"}\n",
+	"kittyItemsMarket/transactions/buy_market_item.cdc": "import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"\n" +
+		"transaction(saleItemID: UInt64, marketCollectionAddress: Address) {\n" +
+		"    let paymentVault: @FungibleToken.Vault\n" +
+		"    let kittyItemsCollection: &KittyItems.Collection{NonFungibleToken.Receiver}\n" +
+		"    let marketCollection: &KittyItemsMarket.Collection{KittyItemsMarket.CollectionPublic}\n" +
+		"\n" +
+		"    prepare(acct: AuthAccount) {\n" +
+		"        self.marketCollection = getAccount(marketCollectionAddress)\n" +
+		"            .getCapability<&KittyItemsMarket.Collection{KittyItemsMarket.CollectionPublic}>(\n" +
+		"                KittyItemsMarket.CollectionPublicPath\n" +
+		"            )!\n" +
+		"            .borrow()\n" +
+		"            ?? panic(\"Could not borrow market collection from market address\")\n" +
+		"\n" +
+		"        let saleItem = self.marketCollection.borrowSaleItem(saleItemID: saleItemID)\n" +
+		"                    ?? panic(\"No item with that ID\")\n" +
+		"        let price = saleItem.salePrice\n" +
+		"\n" +
+		"        let mainKibbleVault = acct.borrow<&Kibble.Vault>(from: Kibble.VaultStoragePath)\n" +
+		"            ?? panic(\"Cannot borrow Kibble vault from acct storage\")\n" +
+		"        self.paymentVault <- mainKibbleVault.withdraw(amount: price)\n" +
+		"\n" +
+		"        self.kittyItemsCollection = acct.borrow<&KittyItems.Collection{NonFungibleToken.Receiver}>(\n" +
+		"            from: KittyItems.CollectionStoragePath\n" +
+		"        ) ?? panic(\"Cannot borrow KittyItems collection receiver from acct\")\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"        self.marketCollection.purchase(\n" +
+		"            saleItemID: saleItemID,\n" +
+		"            buyerCollection: self.kittyItemsCollection,\n" +
+		"            buyerPayment: <- self.paymentVault\n" +
+		"        )\n" +
+		"    }\n" +
+		"}",
+	"kittyItemsMarket/transactions/remove_market_item.cdc": "import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"\n" +
+		"transaction(saleItemID: UInt64) {\n" +
+		"    let marketCollection: &KittyItemsMarket.Collection\n" +
+		"\n" +
+		"    prepare(acct: AuthAccount) {\n" +
+		"        self.marketCollection = acct.borrow<&KittyItemsMarket.Collection>(from: KittyItemsMarket.CollectionStoragePath)\n" +
+		"            ?? panic(\"Missing or mis-typed KittyItemsMarket Collection\")\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"        let offer <-self.marketCollection.remove(saleItemID: saleItemID)\n" +
+		"        destroy offer\n" +
+		"    }\n" +
+		"}",
+	"kittyItemsMarket/transactions/sell_market_item.cdc": "import FungibleToken from 0xFUNGIBLETOKENADDRESS\n" +
+		"import NonFungibleToken from 0xNONFUNGIBLETOKEN\n" +
+		"import Kibble from 0xKIBBLE\n" +
+		"import KittyItems from 0xKITTYITEMS\n" +
+		"import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"\n" +
+		"transaction(saleItemID: UInt64, saleItemPrice: UFix64) {\n" +
+		"    let kibbleVault: Capability<&Kibble.Vault{FungibleToken.Receiver}>\n" +
+		"    let kittyItemsCollection: Capability<&KittyItems.Collection{NonFungibleToken.Provider}>\n" +
+		"    let marketCollection: &KittyItemsMarket.Collection\n" +
+		"\n" +
+		"    prepare(acct: AuthAccount) {\n" +
+		"        // We need a provider capability, but one is not provided by default so we create one.\n" +
+		"        let KittyItemsCollectionProviderPrivatePath = /private/KittyItemsCollectionProvider\n" +
+		"\n" +
+		"        self.kibbleVault = acct.getCapability<&Kibble.Vault{FungibleToken.Receiver}>(Kibble.ReceiverPublicPath)!\n" +
+		"        assert(self.kibbleVault.borrow() != nil, message: \"Missing or mis-typed Kibble receiver\")\n" +
+		"\n" +
+		"        if !acct.getCapability<&KittyItems.Collection{NonFungibleToken.Provider}>(KittyItemsCollectionProviderPrivatePath)!.check() {\n" +
+		"            acct.link<&KittyItems.Collection{NonFungibleToken.Provider}>(KittyItemsCollectionProviderPrivatePath, target: KittyItems.CollectionStoragePath)\n" +
+		"        }\n" +
+		"\n" +
+		"        self.kittyItemsCollection = acct.getCapability<&KittyItems.Collection{NonFungibleToken.Provider}>(KittyItemsCollectionProviderPrivatePath)!\n" +
+		"        assert(self.kittyItemsCollection.borrow() != nil, message: \"Missing or mis-typed KittyItemsCollection provider\")\n" +
+		"\n" +
+		"        self.marketCollection = acct.borrow<&KittyItemsMarket.Collection>(from: KittyItemsMarket.CollectionStoragePath)\n" +
+		"            ?? panic(\"Missing or mis-typed KittyItemsMarket Collection\")\n" +
+		"    }\n" +
+		"\n" +
+		"    execute {\n" +
+		"        let offer <- KittyItemsMarket.createSaleOffer (\n" +
+		"            sellerItemProvider: self.kittyItemsCollection,\n" +
+		"            saleItemID: saleItemID,\n" +
+		"            sellerPaymentReceiver: self.kibbleVault,\n" +
+		"            salePrice: saleItemPrice\n" +
+		"        )\n" +
+		"        self.marketCollection.insert(offer: <-offer)\n" +
+		"    }\n" +
+		"}",
+	"kittyItemsMarket/transactions/setup_account.cdc": "import KittyItemsMarket from 0xKITTYMARKET\n" +
+		"// This transaction is what an account would run\n" +
+		"// to hold SaleOffer items.\n" +
+		"\n" +
+		"transaction {\n" +
+		"    prepare(acct: AuthAccount) {\n" +
+		"\n" +
+		"        // If the account doesn't already have a collection\n" +
+		"        if acct.borrow<&KittyItemsMarket.Collection>(from: KittyItemsMarket.CollectionStoragePath) == nil {\n" +
+		"\n" +
+		"            // Create a new empty collection\n" +
+		"            let collection <- KittyItemsMarket.createEmptyCollection() as! @KittyItemsMarket.Collection\n" +
+		"            \n" +
+		"            // save it to the account\n" +
+		"            acct.save(<-collection, to: KittyItemsMarket.CollectionStoragePath)\n" +
+		"\n" +
+		"            // create a public capability for the collection\n" +
+		"            acct.link<&KittyItemsMarket.Collection{KittyItemsMarket.CollectionPublic}>(KittyItemsMarket.CollectionPublicPath, target: KittyItemsMarket.CollectionStoragePath)\n" +
+		"        }\n" +
+		"    }\n" +
+		"}\n",
+}
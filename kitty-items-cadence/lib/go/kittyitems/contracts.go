@@ -0,0 +1,94 @@
+// Package kittyitems deploys and drives the Kibble, KittyItems and KittyItemsMarket contracts.
+//
+// Contracts has a typed binding for every transaction and script, generated from their Cadence parameter lists
+// with `go generate`. Transactions are built for any client to sign and send, scripts are run by any
+// ScriptExecutor. Emulator runs them on an in-process emulator, for tests and local development.
+package kittyitems
+
+//go:generate go run ./internal/gen -src ../../../cadence -sources cadence.go -bindings bindings.go
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+)
+
+// DefaultGasLimit is the gas limit of the transactions without a measured limit, see GasLimit
+const DefaultGasLimit = 100
+
+// Placeholders for the contract addresses in the Cadence sources
+const (
+	FungibleTokenAddressPlaceholder    = "0xFUNGIBLETOKENADDRESS"
+	KibbleAddressPlaceholder           = "0xKIBBLE"
+	NonFungibleTokenAddressPlaceholder = "0xNONFUNGIBLETOKEN"
+	KittyItemsAddressPlaceholder       = "0xKITTYITEMS"
+	KittyItemsMarketAddressPlaceholder = "0xKITTYMARKET"
+)
+
+// Contracts holds the addresses the contracts are deployed to. Addresses left empty are not substituted.
+type Contracts struct {
+	FungibleToken    flow.Address
+	Kibble           flow.Address
+	NonFungibleToken flow.Address
+	KittyItems       flow.Address
+	KittyItemsMarket flow.Address
+}
+
+// Code returns the Cadence source at path with the contract addresses filled in.
+// It panics when path is not one of the paths in bindings.go, which are compiled into the package.
+func (c Contracts) Code(path string) []byte {
+	source, ok := cadenceSources[path]
+	if !ok {
+		panic(fmt.Sprintf("kittyitems: no cadence source at %s", path))
+	}
+
+	substitutions := []struct {
+		placeholder string
+		address     flow.Address
+	}{
+		{FungibleTokenAddressPlaceholder, c.FungibleToken},
+		{KibbleAddressPlaceholder, c.Kibble},
+		{NonFungibleTokenAddressPlaceholder, c.NonFungibleToken},
+		{KittyItemsAddressPlaceholder, c.KittyItems},
+		{KittyItemsMarketAddressPlaceholder, c.KittyItemsMarket},
+	}
+	for _, s := range substitutions {
+		if s.address == flow.EmptyAddress {
+			continue
+		}
+		source = strings.ReplaceAll(source, s.placeholder, "0x"+s.address.Hex())
+	}
+
+	return []byte(source)
+}
+
+// GasLimit returns the gas limit of the transaction at path: the most computation the Cadence tests measured it
+// to use, with some headroom, or DefaultGasLimit when it was not measured
+func GasLimit(path string) uint64 {
+	if limit, ok := gasLimits[path]; ok {
+		return limit
+	}
+	return DefaultGasLimit
+}
+
+// Transaction builds the transaction at path. The caller sets the reference block, proposal key and payer,
+// then signs it.
+func (c Contracts) Transaction(path string, authorizers []flow.Address, arguments ...cadence.Value) (*flow.Transaction, error) {
+	tx := flow.NewTransaction().
+		SetScript(c.Code(path)).
+		SetGasLimit(GasLimit(path))
+
+	for _, authorizer := range authorizers {
+		tx.AddAuthorizer(authorizer)
+	}
+
+	for _, argument := range arguments {
+		if err := tx.AddArgument(argument); err != nil {
+			return nil, fmt.Errorf("error encoding argument of %s = %w", path, err)
+		}
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,99 @@
+package kittyitems
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+)
+
+// ScriptExecutor runs the scripts of the bindings, e.g. an Emulator or a flow client
+type ScriptExecutor interface {
+	ExecuteScript(ctx context.Context, script []byte, arguments []cadence.Value) (cadence.Value, error)
+}
+
+// ScriptExecutorFunc adapts a function to a ScriptExecutor
+type ScriptExecutorFunc func(ctx context.Context, script []byte, arguments []cadence.Value) (cadence.Value, error)
+
+func (f ScriptExecutorFunc) ExecuteScript(ctx context.Context, script []byte, arguments []cadence.Value) (cadence.Value, error) {
+	return f(ctx, script, arguments)
+}
+
+// The decoders below read the values returned by the scripts, see the bindings generated by internal/gen
+
+func decodeAddress(value cadence.Value) (flow.Address, error) {
+	v, ok := value.(cadence.Address)
+	if !ok {
+		return flow.EmptyAddress, unexpected("Address", value)
+	}
+	return flow.BytesToAddress(v.Bytes()), nil
+}
+
+func decodeBool(value cadence.Value) (bool, error) {
+	v, ok := value.(cadence.Bool)
+	if !ok {
+		return false, unexpected("Bool", value)
+	}
+	return bool(v), nil
+}
+
+func decodeInt(value cadence.Value) (int, error) {
+	v, ok := value.(cadence.Int)
+	if !ok {
+		return 0, unexpected("Int", value)
+	}
+	return v.Int(), nil
+}
+
+func decodeString(value cadence.Value) (string, error) {
+	v, ok := value.(cadence.String)
+	if !ok {
+		return "", unexpected("String", value)
+	}
+	return string(v), nil
+}
+
+func decodeUFix64(value cadence.Value) (cadence.UFix64, error) {
+	v, ok := value.(cadence.UFix64)
+	if !ok {
+		return 0, unexpected("UFix64", value)
+	}
+	return v, nil
+}
+
+func decodeUInt64(value cadence.Value) (uint64, error) {
+	v, ok := value.(cadence.UInt64)
+	if !ok {
+		return 0, unexpected("UInt64", value)
+	}
+	return uint64(v), nil
+}
+
+func decodeArray(value cadence.Value) ([]cadence.Value, error) {
+	v, ok := value.(cadence.Array)
+	if !ok {
+		return nil, unexpected("array", value)
+	}
+	return v.Values, nil
+}
+
+func decodeStruct(value cadence.Value, fields int) ([]cadence.Value, error) {
+	v, ok := value.(cadence.Struct)
+	if !ok || len(v.Fields) != fields {
+		return nil, unexpected(fmt.Sprintf("struct with %d fields", fields), value)
+	}
+	return v.Fields, nil
+}
+
+// optionalValue returns the value held by an optional, or nil
+func optionalValue(value cadence.Value) cadence.Value {
+	if optional, ok := value.(cadence.Optional); ok {
+		return optional.Value
+	}
+	return value
+}
+
+func unexpected(expected string, value cadence.Value) error {
+	return fmt.Errorf("unexpected script result, expected %s, got %v", expected, value)
+}
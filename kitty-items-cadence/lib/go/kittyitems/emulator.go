@@ -0,0 +1,572 @@
+package kittyitems
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	emulator "github.com/onflow/flow-emulator"
+	"github.com/onflow/flow-emulator/types"
+	ft_contracts "github.com/onflow/flow-ft/lib/go/contracts"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/crypto"
+	sdktemplates "github.com/onflow/flow-go-sdk/templates"
+	"github.com/onflow/flow-go/fvm"
+	flowgo "github.com/onflow/flow-go/model/flow"
+	nft_contracts "github.com/onflow/flow-nft/lib/go/contracts"
+)
+
+var (
+	// ErrTransactionReverted is wrapped by the errors of transactions that executed and failed
+	ErrTransactionReverted = errors.New("transaction reverted")
+	// ErrScriptReverted is wrapped by the errors of scripts that executed and failed
+	ErrScriptReverted = errors.New("script reverted")
+)
+
+// RevertedError is the error of a transaction that executed and failed. It matches ErrTransactionReverted
+// and unwraps to the Cadence error the transaction failed with, when there is one.
+type RevertedError struct {
+	Result *types.TransactionResult
+}
+
+func (e *RevertedError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrTransactionReverted, e.Result.Error)
+}
+
+func (e *RevertedError) Is(target error) bool {
+	return target == ErrTransactionReverted
+}
+
+func (e *RevertedError) Unwrap() error {
+	var flowErr *types.FlowError
+	if errors.As(e.Result.Error, &flowErr) {
+		if executionErr, ok := flowErr.FlowError.(*fvm.ExecutionError); ok {
+			return executionErr.Err
+		}
+	}
+	return e.Result.Error
+}
+
+// Account is an emulator account along with the key it signs with
+type Account struct {
+	Address    flow.Address
+	Key        *flow.AccountKey
+	PrivateKey crypto.PrivateKey
+	Signer     crypto.Signer
+}
+
+// Emulator runs the contracts on an in-process emulator. Every transaction is paid for by the service account
+// and committed in a block of its own.
+type Emulator struct {
+	Blockchain *emulator.Blockchain
+	// Contracts is filled in as the contracts get deployed
+	Contracts Contracts
+}
+
+// NewEmulator starts an emulator with no contract deployed besides the ones of the service account
+func NewEmulator(opts ...emulator.Option) (*Emulator, error) {
+	b, err := emulator.NewBlockchain(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error starting emulator = %w", err)
+	}
+	return &Emulator{Blockchain: b}, nil
+}
+
+// CreateAccount creates an account with a new key and deploys contracts to it
+func (e *Emulator) CreateAccount(contracts ...sdktemplates.Contract) (Account, error) {
+	seed := make([]byte, crypto.MinSeedLength)
+	if _, err := rand.Read(seed); err != nil {
+		return Account{}, err
+	}
+	privateKey, err := crypto.GeneratePrivateKey(crypto.ECDSA_P256, seed)
+	if err != nil {
+		return Account{}, fmt.Errorf("error generating account key = %w", err)
+	}
+
+	key := flow.NewAccountKey().
+		FromPrivateKey(privateKey).
+		SetHashAlgo(crypto.SHA3_256).
+		SetWeight(flow.AccountKeyWeightThreshold)
+
+	address, err := e.Blockchain.CreateAccount([]*flow.AccountKey{key}, contracts)
+	if err != nil {
+		return Account{}, fmt.Errorf("error creating account = %w", err)
+	}
+	if _, err := e.Blockchain.CommitBlock(); err != nil {
+		return Account{}, fmt.Errorf("error committing block = %w", err)
+	}
+
+	return Account{
+		Address:    address,
+		Key:        key,
+		PrivateKey: privateKey,
+		Signer:     crypto.NewInMemorySigner(privateKey, key.HashAlgo),
+	}, nil
+}
+
+// Submit pays for tx with the service account, signs it with authorizers, executes it and commits the block.
+// The result is returned even when the transaction reverted, along with a *RevertedError.
+func (e *Emulator) Submit(tx *flow.Transaction, authorizers ...Account) (*types.TransactionResult, error) {
+	result, err := e.execute(tx, authorizers, true)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Reverted() {
+		return result, &RevertedError{result}
+	}
+	return result, nil
+}
+
+// MeasureComputation returns the computation tx uses, signed like Submit does, without committing it. Cadence
+// does not report the computation used, it only reverts the transactions exceeding their gas limit, so this is
+// the least gas limit tx executes within, found by executing it with a range of limits. The gas limit of tx is
+// left unchanged. It fails when tx reverts within the maximum gas limit.
+func (e *Emulator) MeasureComputation(tx *flow.Transaction, authorizers ...Account) (uint64, error) {
+	gasLimit := tx.GasLimit
+	defer tx.SetGasLimit(gasLimit)
+
+	exceeds := func(limit uint64) (bool, error) {
+		result, err := e.execute(tx.SetGasLimit(limit), authorizers, false)
+		if err != nil {
+			return false, err
+		}
+		if !result.Reverted() {
+			return false, nil
+		}
+
+		reverted := &RevertedError{result}
+		if errors.As(reverted, new(runtime.ComputationLimitExceededError)) {
+			return true, nil
+		}
+		return false, reverted
+	}
+
+	// The computation used is above low and at most high, found doubling high from a guess below the usual
+	// computation of a transaction and then bisecting. A limit of 0 disables the metering.
+	low, high := uint64(0), uint64(16)
+	for {
+		exceeded, err := exceeds(high)
+		if err != nil {
+			return 0, err
+		}
+		if !exceeded {
+			break
+		}
+		if high == flowgo.DefaultMaxGasLimit {
+			return 0, fmt.Errorf("transaction exceeds the maximum gas limit %d", high)
+		}
+		low, high = high, 2*high
+		if high > flowgo.DefaultMaxGasLimit {
+			high = flowgo.DefaultMaxGasLimit
+		}
+	}
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		exceeded, err := exceeds(middle)
+		if err != nil {
+			return 0, err
+		}
+		if exceeded {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return high, nil
+}
+
+// execute pays for tx with the service account, signs it with authorizers and executes it in the pending block,
+// which is committed when commit is set and discarded otherwise
+func (e *Emulator) execute(tx *flow.Transaction, authorizers []Account, commit bool) (*types.TransactionResult, error) {
+	serviceKey := e.Blockchain.ServiceKey()
+	tx.SetProposalKey(serviceKey.Address, serviceKey.Index, serviceKey.SequenceNumber).
+		SetPayer(serviceKey.Address)
+
+	// Signatures of an earlier execution don't match the transaction anymore
+	tx.PayloadSignatures, tx.EnvelopeSignatures = nil, nil
+	for _, authorizer := range authorizers {
+		if authorizer.Address == serviceKey.Address {
+			continue
+		}
+		if err := tx.SignPayload(authorizer.Address, authorizer.Key.Index, authorizer.Signer); err != nil {
+			return nil, fmt.Errorf("error signing transaction = %w", err)
+		}
+	}
+	if err := tx.SignEnvelope(serviceKey.Address, serviceKey.Index, serviceKey.Signer()); err != nil {
+		return nil, fmt.Errorf("error signing transaction = %w", err)
+	}
+
+	if err := e.Blockchain.AddTransaction(*tx); err != nil {
+		return nil, fmt.Errorf("error adding transaction = %w", err)
+	}
+	result, err := e.Blockchain.ExecuteNextTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("error executing transaction = %w", err)
+	}
+
+	if !commit {
+		if err := e.Blockchain.ResetPendingBlock(); err != nil {
+			return result, fmt.Errorf("error resetting pending block = %w", err)
+		}
+		return result, nil
+	}
+	if _, err := e.Blockchain.CommitBlock(); err != nil {
+		return result, fmt.Errorf("error committing block = %w", err)
+	}
+	return result, nil
+}
+
+// ExecuteScript runs script against the latest block, it makes the Emulator a ScriptExecutor
+func (e *Emulator) ExecuteScript(_ context.Context, script []byte, arguments []cadence.Value) (cadence.Value, error) {
+	encoded := make([][]byte, len(arguments))
+	for i, argument := range arguments {
+		var err error
+		if encoded[i], err = jsoncdc.Encode(argument); err != nil {
+			return nil, fmt.Errorf("error encoding script argument = %w", err)
+		}
+	}
+
+	result, err := e.Blockchain.ExecuteScript(script, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error executing script = %w", err)
+	}
+	if result.Reverted() {
+		return nil, fmt.Errorf("%w: %v", ErrScriptReverted, result.Error)
+	}
+	return result.Value, nil
+}
+
+// DeployKibble deploys FungibleToken to an account without keys and Kibble to a new account, which also gets
+// a vault. The Kibble account holds the administrator.
+func (e *Emulator) DeployKibble() (Account, error) {
+	if err := e.deployFungibleToken(); err != nil {
+		return Account{}, err
+	}
+
+	kibble, err := e.CreateAccount(sdktemplates.Contract{
+		Name:   "Kibble",
+		Source: string(e.Contracts.Code(KibbleContract)),
+	})
+	if err != nil {
+		return Account{}, fmt.Errorf("error deploying Kibble = %w", err)
+	}
+	e.Contracts.Kibble = kibble.Address
+
+	if err := e.SetupKibbleAccount(kibble); err != nil {
+		return Account{}, err
+	}
+	return kibble, nil
+}
+
+// DeployKittyItems deploys NonFungibleToken to an account without keys and KittyItems to a new account, which
+// also gets a collection. The KittyItems account holds the NFT minter.
+func (e *Emulator) DeployKittyItems() (Account, error) {
+	if err := e.deployNonFungibleToken(); err != nil {
+		return Account{}, err
+	}
+
+	kittyItems, err := e.CreateAccount(sdktemplates.Contract{
+		Name:   "KittyItems",
+		Source: string(e.Contracts.Code(KittyItemsContract)),
+	})
+	if err != nil {
+		return Account{}, fmt.Errorf("error deploying KittyItems = %w", err)
+	}
+	e.Contracts.KittyItems = kittyItems.Address
+
+	if err := e.SetupKittyItemsAccount(kittyItems); err != nil {
+		return Account{}, err
+	}
+	return kittyItems, nil
+}
+
+// DeployMarket deploys KittyItemsMarket to a new account, which also gets a market collection.
+// Kibble and KittyItems must be deployed first.
+func (e *Emulator) DeployMarket() (Account, error) {
+	if e.Contracts.Kibble == flow.EmptyAddress || e.Contracts.KittyItems == flow.EmptyAddress {
+		return Account{}, errors.New("Kibble and KittyItems must be deployed before KittyItemsMarket")
+	}
+
+	market, err := e.CreateAccount(sdktemplates.Contract{
+		Name:   "KittyItemsMarket",
+		Source: string(e.Contracts.Code(KittyItemsMarketContract)),
+	})
+	if err != nil {
+		return Account{}, fmt.Errorf("error deploying KittyItemsMarket = %w", err)
+	}
+	e.Contracts.KittyItemsMarket = market.Address
+
+	if err := e.SetupMarketAccount(market); err != nil {
+		return Account{}, err
+	}
+	return market, nil
+}
+
+// Deployment holds the accounts the contracts were deployed to by DeployContracts
+type Deployment struct {
+	Kibble     Account
+	KittyItems Account
+	Market     Account
+}
+
+// DeployContracts deploys every contract, see DeployKibble, DeployKittyItems and DeployMarket
+func (e *Emulator) DeployContracts() (Deployment, error) {
+	var (
+		d   Deployment
+		err error
+	)
+	if d.Kibble, err = e.DeployKibble(); err != nil {
+		return d, err
+	}
+	if d.KittyItems, err = e.DeployKittyItems(); err != nil {
+		return d, err
+	}
+	if d.Market, err = e.DeployMarket(); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// DeployContractsToAccount deploys FungibleToken and NonFungibleToken to accounts without keys, then Kibble,
+// KittyItems and KittyItemsMarket to a single new account, which gets a vault and both collections.
+// This is the layout the backend expects by default, with the contracts in the minter account.
+func (e *Emulator) DeployContractsToAccount() (Account, error) {
+	if err := e.deployFungibleToken(); err != nil {
+		return Account{}, err
+	}
+	if err := e.deployNonFungibleToken(); err != nil {
+		return Account{}, err
+	}
+
+	account, err := e.CreateAccount()
+	if err != nil {
+		return Account{}, err
+	}
+
+	// KittyItemsMarket imports the two others, so each contract is added once the previous ones are deployed
+	for _, contract := range []struct {
+		name    string
+		path    string
+		address *flow.Address
+	}{
+		{"Kibble", KibbleContract, &e.Contracts.Kibble},
+		{"KittyItems", KittyItemsContract, &e.Contracts.KittyItems},
+		{"KittyItemsMarket", KittyItemsMarketContract, &e.Contracts.KittyItemsMarket},
+	} {
+		tx := sdktemplates.AddAccountContract(account.Address, sdktemplates.Contract{
+			Name:   contract.name,
+			Source: string(e.Contracts.Code(contract.path)),
+		})
+		if err := e.run("deploying "+contract.name, tx, account); err != nil {
+			return Account{}, err
+		}
+		*contract.address = account.Address
+	}
+
+	if err := e.SetupKibbleAccount(account); err != nil {
+		return Account{}, err
+	}
+	if err := e.SetupKittyItemsAccount(account); err != nil {
+		return Account{}, err
+	}
+	if err := e.SetupMarketAccount(account); err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+func (e *Emulator) deployFungibleToken() error {
+	address, err := e.Blockchain.CreateAccount(nil, []sdktemplates.Contract{{
+		Name:   "FungibleToken",
+		Source: string(ft_contracts.FungibleToken()),
+	}})
+	if err != nil {
+		return fmt.Errorf("error deploying FungibleToken = %w", err)
+	}
+	e.Contracts.FungibleToken = address
+	return nil
+}
+
+func (e *Emulator) deployNonFungibleToken() error {
+	address, err := e.Blockchain.CreateAccount(nil, []sdktemplates.Contract{{
+		Name:   "NonFungibleToken",
+		Source: string(nft_contracts.NonFungibleToken()),
+	}})
+	if err != nil {
+		return fmt.Errorf("error deploying NonFungibleToken = %w", err)
+	}
+	e.Contracts.NonFungibleToken = address
+	return nil
+}
+
+// SetupKibbleAccount creates an empty Kibble vault in account
+func (e *Emulator) SetupKibbleAccount(account Account) error {
+	tx, err := e.Contracts.KibbleSetupAccount(account.Address)
+	if err != nil {
+		return err
+	}
+	return e.run("setting up Kibble account", tx, account)
+}
+
+// SetupKittyItemsAccount creates an empty KittyItems collection in account
+func (e *Emulator) SetupKittyItemsAccount(account Account) error {
+	tx, err := e.Contracts.KittyItemsSetupAccount(account.Address)
+	if err != nil {
+		return err
+	}
+	return e.run("setting up KittyItems account", tx, account)
+}
+
+// SetupMarketAccount creates an empty market collection in account
+func (e *Emulator) SetupMarketAccount(account Account) error {
+	tx, err := e.Contracts.MarketSetupAccount(account.Address)
+	if err != nil {
+		return err
+	}
+	return e.run("setting up market account", tx, account)
+}
+
+// CreateUser creates an account with a Kibble vault and a KittyItems collection, and a market collection once
+// KittyItemsMarket is deployed
+func (e *Emulator) CreateUser() (Account, error) {
+	user, err := e.CreateAccount()
+	if err != nil {
+		return Account{}, err
+	}
+	if err := e.SetupKibbleAccount(user); err != nil {
+		return Account{}, err
+	}
+	if err := e.SetupKittyItemsAccount(user); err != nil {
+		return Account{}, err
+	}
+	if e.Contracts.KittyItemsMarket != flow.EmptyAddress {
+		if err := e.SetupMarketAccount(user); err != nil {
+			return Account{}, err
+		}
+	}
+	return user, nil
+}
+
+// FundAccount transfers amount FLOW from the service account to address. New accounts hold no FLOW.
+// It relies on the FlowToken address of the default emulator chain.
+func (e *Emulator) FundAccount(address flow.Address, amount cadence.UFix64) error {
+	chain := flowgo.Emulator.Chain()
+	script := strings.NewReplacer(
+		"0xFUNGIBLETOKEN", "0x"+fvm.FungibleTokenAddress(chain).Hex(),
+		"0xFLOWTOKEN", "0x"+fvm.FlowTokenAddress(chain).Hex(),
+	).Replace(fundAccountTransaction)
+
+	serviceAddress := e.Blockchain.ServiceKey().Address
+	tx := flow.NewTransaction().
+		SetScript([]byte(script)).
+		SetGasLimit(DefaultGasLimit).
+		AddAuthorizer(serviceAddress)
+	if err := tx.AddArgument(cadence.NewAddress(address)); err != nil {
+		return err
+	}
+	if err := tx.AddArgument(amount); err != nil {
+		return err
+	}
+
+	return e.run("funding account", tx, Account{Address: serviceAddress})
+}
+
+const fundAccountTransaction = `
+import FungibleToken from 0xFUNGIBLETOKEN
+import FlowToken from 0xFLOWTOKEN
+
+transaction(recipient: Address, amount: UFix64) {
+    let sentVault: @FungibleToken.Vault
+
+    prepare(signer: AuthAccount) {
+        let vault = signer.borrow<&FlowToken.Vault>(from: /storage/flowTokenVault)
+            ?? panic("Could not borrow the service account vault")
+        self.sentVault <- vault.withdraw(amount: amount)
+    }
+
+    execute {
+        let receiver = getAccount(recipient)
+            .getCapability(/public/flowTokenReceiver)!
+            .borrow<&{FungibleToken.Receiver}>()
+            ?? panic("Could not borrow the FLOW receiver of the recipient")
+        receiver.deposit(from: <-self.sentVault)
+    }
+}
+`
+
+// MintKibble mints amount to recipient with the Kibble administrator stored in admin
+func (e *Emulator) MintKibble(admin Account, recipient flow.Address, amount cadence.UFix64) error {
+	tx, err := e.Contracts.KibbleMintTokens(admin.Address, recipient, amount)
+	if err != nil {
+		return err
+	}
+	return e.run("minting kibble", tx, admin)
+}
+
+// TransferKibble moves amount from the vault of sender to the one of recipient
+func (e *Emulator) TransferKibble(sender Account, recipient flow.Address, amount cadence.UFix64) error {
+	tx, err := e.Contracts.KibbleTransferTokens(sender.Address, amount, recipient)
+	if err != nil {
+		return err
+	}
+	return e.run("transferring kibble", tx, sender)
+}
+
+// MintKittyItem mints an item of typeID to recipient with the NFT minter stored in minter
+func (e *Emulator) MintKittyItem(minter Account, recipient flow.Address, typeID uint64) error {
+	tx, err := e.Contracts.KittyItemsMintKittyItem(minter.Address, recipient, typeID)
+	if err != nil {
+		return err
+	}
+	return e.run("minting kitty item", tx, minter)
+}
+
+// TransferKittyItem moves item itemID from the collection of owner to the one of recipient
+func (e *Emulator) TransferKittyItem(owner Account, recipient flow.Address, itemID uint64) error {
+	tx, err := e.Contracts.KittyItemsTransferKittyItem(owner.Address, recipient, itemID)
+	if err != nil {
+		return err
+	}
+	return e.run("transferring kitty item", tx, owner)
+}
+
+// ListItem puts item itemID of seller up for sale at price
+func (e *Emulator) ListItem(seller Account, itemID uint64, price cadence.UFix64) error {
+	tx, err := e.Contracts.MarketSellMarketItem(seller.Address, itemID, price)
+	if err != nil {
+		return err
+	}
+	return e.run("listing item", tx, seller)
+}
+
+// BuyItem pays for item itemID listed in the market collection of marketCollection and deposits it to buyer
+func (e *Emulator) BuyItem(buyer Account, marketCollection flow.Address, itemID uint64) error {
+	tx, err := e.Contracts.MarketBuyMarketItem(buyer.Address, itemID, marketCollection)
+	if err != nil {
+		return err
+	}
+	return e.run("buying item", tx, buyer)
+}
+
+// RemoveItem withdraws the sale offer for item itemID from the market collection of seller
+func (e *Emulator) RemoveItem(seller Account, itemID uint64) error {
+	tx, err := e.Contracts.MarketRemoveMarketItem(seller.Address, itemID)
+	if err != nil {
+		return err
+	}
+	return e.run("removing item", tx, seller)
+}
+
+// run submits tx and describes the failure with action
+func (e *Emulator) run(action string, tx *flow.Transaction, authorizer Account) error {
+	if _, err := e.Submit(tx, authorizer); err != nil {
+		return fmt.Errorf("error %s = %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// Code generated by TestComputationBudgets in ../test with -gas.update. DO NOT EDIT.
+
+package kittyitems
+
+// gasLimits holds the gas limits of the transactions, see GasLimit
+var gasLimits = map[string]uint64{
+	"kibble/transactions/burn_tokens.cdc":                  30,
+	"kibble/transactions/create_minter.cdc":                30,
+	"kibble/transactions/mint_tokens.cdc":                  60,
+	"kibble/transactions/mint_tokens_with_minter.cdc":      40,
+	"kibble/transactions/setup_account.cdc":                20,
+	"kibble/transactions/top_up_minter.cdc":                30,
+	"kibble/transactions/transfer_tokens.cdc":              50,
+	"kittyItems/transactions/mint_kitty_item.cdc":          40,
+	"kittyItems/transactions/setup_account.cdc":            20,
+	"kittyItems/transactions/transfer_kitty_item.cdc":      40,
+	"kittyItemsMarket/transactions/buy_market_item.cdc":    100,
+	"kittyItemsMarket/transactions/remove_market_item.cdc": 20,
+	"kittyItemsMarket/transactions/sell_market_item.cdc":   60,
+	"kittyItemsMarket/transactions/setup_account.cdc":      20,
+}
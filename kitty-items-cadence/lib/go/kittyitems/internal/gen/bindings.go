@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// goType is how a Cadence type is passed to and read from the bindings
+type goType struct {
+	Go string
+	// Encode formats a Go value into a cadence.Value
+	Encode string
+	// Decode is the function of decode.go reading the Go value out of a cadence.Value
+	Decode string
+}
+
+var goTypes = map[string]goType{
+	"Address": {"flow.Address", "cadence.NewAddress(%s)", "decodeAddress"},
+	"Bool":    {"bool", "cadence.NewBool(%s)", "decodeBool"},
+	"Int":     {"int", "cadence.NewInt(%s)", "decodeInt"},
+	"String":  {"string", "cadence.NewString(%s)", "decodeString"},
+	"UFix64":  {"cadence.UFix64", "%s", "decodeUFix64"},
+	"UInt64":  {"uint64", "cadence.NewUInt64(%s)", "decodeUInt64"},
+}
+
+func writeBindings(buf *bytes.Buffer, files []*cadenceFile) error {
+	buf.WriteString("import (\n\"context\"\n\n\"github.com/onflow/cadence\"\n\"github.com/onflow/flow-go-sdk\"\n)\n\n")
+
+	buf.WriteString("// Paths of the Cadence sources, relative to the cadence directory\nconst (\n")
+	consts := make(map[string]bool)
+	for _, file := range files {
+		name := pathConst(file)
+		if consts[name] {
+			return fmt.Errorf("%s and another source are both named %s", file.Path, name)
+		}
+		consts[name] = true
+		fmt.Fprintf(buf, "%s = %q\n", name, file.Path)
+	}
+	buf.WriteString(")\n")
+
+	for _, file := range files {
+		var err error
+		switch file.Kind {
+		case kindTransaction:
+			err = writeTransaction(buf, file)
+		case kindScript:
+			err = writeScript(buf, file)
+		}
+		if err != nil {
+			return fmt.Errorf("error generating binding of %s = %w", file.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func pathConst(file *cadenceFile) string {
+	switch file.Kind {
+	case kindContract:
+		return file.Name + "Contract"
+	case kindTransaction:
+		return file.Name + "Transaction"
+	}
+	return file.Name + "Script"
+}
+
+func writeTransaction(buf *bytes.Buffer, file *cadenceFile) error {
+	var params, arguments []string
+	for _, authorizer := range file.Authorizers {
+		params = append(params, authorizer+" flow.Address")
+	}
+	for _, p := range file.Params {
+		t, ok := goTypes[p.Type.Name]
+		if !ok || p.Type.Array || p.Type.Optional {
+			return fmt.Errorf("unsupported type of parameter %s", p.Name)
+		}
+		params = append(params, p.Name+" "+t.Go)
+		arguments = append(arguments, fmt.Sprintf(t.Encode, p.Name))
+	}
+
+	fmt.Fprintf(buf, "\n// %s builds %s, authorized by %s\n", file.Name, file.Path, describe(file.Authorizers))
+	fmt.Fprintf(buf, "func (c Contracts) %s(%s) (*flow.Transaction, error) {\n", file.Name, strings.Join(params, ", "))
+	fmt.Fprintf(buf, "return c.Transaction(%s, []flow.Address{%s}", pathConst(file), strings.Join(file.Authorizers, ", "))
+	for _, argument := range arguments {
+		buf.WriteString(", " + argument)
+	}
+	buf.WriteString(")\n}\n")
+
+	return nil
+}
+
+func writeScript(buf *bytes.Buffer, file *cadenceFile) error {
+	structs := make(map[string]goType)
+	for _, decl := range file.Structs {
+		name := prefixOf(file) + decl.Name
+		structs[decl.Name] = goType{Go: name, Decode: "decode" + name}
+		if err := writeStruct(buf, file, decl, name); err != nil {
+			return err
+		}
+	}
+
+	ret, ok := goTypes[file.Return.Name]
+	if !ok {
+		if ret, ok = structs[file.Return.Name]; !ok {
+			return fmt.Errorf("unsupported return type %s", file.Return.Name)
+		}
+	}
+	if file.Return.Array && file.Return.Optional {
+		return fmt.Errorf("unsupported optional array return type")
+	}
+
+	var params, arguments []string
+	params = append(params, "ctx context.Context", "executor ScriptExecutor")
+	for _, p := range file.Params {
+		t, ok := goTypes[p.Type.Name]
+		if !ok || p.Type.Array || p.Type.Optional {
+			return fmt.Errorf("unsupported type of parameter %s", p.Name)
+		}
+		params = append(params, p.Name+" "+t.Go)
+		arguments = append(arguments, fmt.Sprintf(t.Encode, p.Name))
+	}
+
+	result := ret.Go
+	switch {
+	case file.Return.Array:
+		result = "[]" + result
+	case file.Return.Optional:
+		result = "*" + result
+	}
+
+	fmt.Fprintf(buf, "\n// %s runs %s", file.Name, file.Path)
+	if file.Return.Optional {
+		buf.WriteString(", the result is nil when the script returns nil")
+	}
+	fmt.Fprintf(buf, "\nfunc (c Contracts) %s(%s) (result %s, err error) {\n", file.Name, strings.Join(params, ", "), result)
+	values := "nil"
+	if len(arguments) > 0 {
+		values = "[]cadence.Value{" + strings.Join(arguments, ", ") + "}"
+	}
+	fmt.Fprintf(buf, "value, err := executor.ExecuteScript(ctx, c.Code(%s), %s)\n", pathConst(file), values)
+	buf.WriteString("if err != nil {\nreturn result, err\n}\n")
+
+	switch {
+	case file.Return.Array:
+		fmt.Fprintf(buf, "values, err := decodeArray(value)\nif err != nil {\nreturn nil, err\n}\n")
+		fmt.Fprintf(buf, "result = make(%s, len(values))\n", result)
+		fmt.Fprintf(buf, "for i, element := range values {\nif result[i], err = %s(element); err != nil {\nreturn nil, err\n}\n}\n", ret.Decode)
+		buf.WriteString("return result, nil\n")
+	case file.Return.Optional:
+		buf.WriteString("if value = optionalValue(value); value == nil {\nreturn nil, nil\n}\n")
+		fmt.Fprintf(buf, "decoded, err := %s(value)\nif err != nil {\nreturn nil, err\n}\nreturn &decoded, nil\n", ret.Decode)
+	default:
+		fmt.Fprintf(buf, "return %s(value)\n", ret.Decode)
+	}
+	buf.WriteString("}\n")
+
+	return nil
+}
+
+func writeStruct(buf *bytes.Buffer, file *cadenceFile, decl structDecl, name string) error {
+	fmt.Fprintf(buf, "\n// %s is the %s struct returned by %s\n", name, decl.Name, file.Path)
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+	for _, field := range decl.Fields {
+		t, ok := goTypes[field.Type.Name]
+		if !ok || field.Type.Array || field.Type.Optional {
+			return fmt.Errorf("unsupported type of field %s.%s", decl.Name, field.Name)
+		}
+		fmt.Fprintf(buf, "%s %s\n", exported(field.Name), t.Go)
+	}
+	buf.WriteString("}\n")
+
+	fmt.Fprintf(buf, "\nfunc decode%s(value cadence.Value) (decoded %s, err error) {\n", name, name)
+	fmt.Fprintf(buf, "fields, err := decodeStruct(value, %d)\nif err != nil {\nreturn decoded, err\n}\n", len(decl.Fields))
+	for i, field := range decl.Fields {
+		fmt.Fprintf(buf, "if decoded.%s, err = %s(fields[%d]); err != nil {\nreturn decoded, err\n}\n", exported(field.Name), goTypes[field.Type.Name].Decode, i)
+	}
+	buf.WriteString("return decoded, nil\n}\n")
+
+	return nil
+}
+
+func prefixOf(file *cadenceFile) string {
+	dir := strings.SplitN(file.Path, "/", 2)[0]
+	return prefix(dir)
+}
+
+func describe(names []string) string {
+	switch len(names) {
+	case 0:
+		return "nobody"
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
@@ -0,0 +1,112 @@
+// gen compiles the Cadence contracts, transactions and scripts into the kittyitems package, so that it works
+// from any working directory and in binaries shipped without the sources, and generates a typed Go binding
+// for every transaction and script from its parameter list and return type.
+//
+//	go run ./internal/gen -src ../../../cadence -sources cadence.go -bindings bindings.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	src := flag.String("src", "../../../cadence", "directory holding the Cadence sources")
+	sourcesOut := flag.String("sources", "cadence.go", "file to write the sources to")
+	bindingsOut := flag.String("bindings", "bindings.go", "file to write the bindings to")
+	pkg := flag.String("package", "kittyitems", "package of the generated files")
+	flag.Parse()
+
+	sources := make(map[string]string)
+	err := filepath.Walk(*src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".cdc" {
+			return nil
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(*src, path)
+		if err != nil {
+			return err
+		}
+		sources[filepath.ToSlash(rel)] = string(contents)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("error reading cadence sources = %v", err)
+	}
+
+	paths := make([]string, 0, len(sources))
+	for path := range sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	header := fmt.Sprintf("// Code generated by internal/gen from %s. DO NOT EDIT.\n\npackage %s\n\n", filepath.ToSlash(*src), *pkg)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.WriteString("var cadenceSources = map[string]string{\n")
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "%s: %s,\n", strconv.Quote(path), quoteLines(sources[path]))
+	}
+	buf.WriteString("}\n")
+	write(*sourcesOut, buf.Bytes())
+
+	var files []*cadenceFile
+	for _, path := range paths {
+		file, err := parseFile(path, sources[path])
+		if err != nil {
+			log.Fatalf("error parsing %s = %v", path, err)
+		}
+		files = append(files, file)
+	}
+
+	buf.Reset()
+	buf.WriteString(header)
+	if err := writeBindings(&buf, files); err != nil {
+		log.Fatalf("error generating bindings = %v", err)
+	}
+	write(*bindingsOut, buf.Bytes())
+}
+
+func write(path string, source []byte) {
+	formatted, err := format.Source(source)
+	if err != nil {
+		log.Fatalf("error formatting %s = %v", path, err)
+	}
+
+	if err := ioutil.WriteFile(path, formatted, 0644); err != nil {
+		log.Fatalf("error writing %s = %v", path, err)
+	}
+}
+
+// quoteLines quotes source one line per string literal, so that diffs of the generated file stay readable
+func quoteLines(source string) string {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return `""`
+	}
+
+	quoted := make([]string, len(lines))
+	for i, line := range lines {
+		quoted[i] = strconv.Quote(line)
+	}
+	return strings.Join(quoted, " +\n")
+}
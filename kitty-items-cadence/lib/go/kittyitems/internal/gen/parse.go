@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Kinds of Cadence sources, named after the directory holding them
+const (
+	kindContract    = "contracts"
+	kindTransaction = "transactions"
+	kindScript      = "scripts"
+)
+
+// prefixes shortens the directory names in the binding names, other directories are used as they are
+var prefixes = map[string]string{
+	"kittyItemsMarket": "Market",
+}
+
+type cadenceFile struct {
+	Path string
+	Kind string
+	// Name is the Go name of the binding, e.g. KibbleGetBalance
+	Name string
+
+	// Authorizers are the parameters of the prepare block of a transaction
+	Authorizers []string
+	Params      []param
+	// Return is the return type of a script
+	Return  cadenceType
+	Structs []structDecl
+}
+
+type param struct {
+	Name string
+	Type cadenceType
+}
+
+// cadenceType is a Cadence type as written in a parameter list, e.g. `UFix64` or `SaleOfferDetails?`
+type cadenceType struct {
+	Name     string
+	Array    bool
+	Optional bool
+}
+
+type structDecl struct {
+	Name   string
+	Fields []param
+}
+
+var (
+	transactionPattern = regexp.MustCompile(`(?s)\btransaction\s*(?:\(([^)]*)\))?\s*\{`)
+	preparePattern     = regexp.MustCompile(`(?s)\bprepare\s*\(([^)]*)\)`)
+	mainPattern        = regexp.MustCompile(`(?s)\bpub\s+fun\s+main\s*\(([^)]*)\)\s*(?::\s*([^{]+?))?\s*\{`)
+	structPattern      = regexp.MustCompile(`\bpub\s+struct\s+(\w+)\s*\{`)
+	fieldPattern       = regexp.MustCompile(`\bpub\s+(?:let|var)\s+(\w+)\s*:\s*([^\n/]+)`)
+	commentPattern     = regexp.MustCompile(`(?m)//.*$`)
+	identifierPattern  = regexp.MustCompile(`^\w+$`)
+)
+
+func parseFile(filePath, source string) (*cadenceFile, error) {
+	dir, kind := path.Split(path.Dir(filePath))
+	dir = strings.TrimSuffix(dir, "/")
+	base := strings.TrimSuffix(path.Base(filePath), ".cdc")
+
+	file := &cadenceFile{Path: filePath, Kind: kind}
+	code := commentPattern.ReplaceAllString(source, "")
+
+	switch kind {
+	case kindContract:
+		file.Name = base
+		return file, nil
+
+	case kindTransaction:
+		file.Name = prefix(dir) + camel(base)
+
+		match := transactionPattern.FindStringSubmatch(code)
+		if match == nil {
+			return nil, errors.New("no transaction declaration")
+		}
+		params, err := parseParams(match[1])
+		if err != nil {
+			return nil, err
+		}
+		file.Params = params
+
+		if match := preparePattern.FindStringSubmatch(code); match != nil {
+			authorizers, err := parseParams(match[1])
+			if err != nil {
+				return nil, err
+			}
+			for _, authorizer := range authorizers {
+				file.Authorizers = append(file.Authorizers, authorizer.Name)
+			}
+		}
+
+		return file, checkNames(file)
+
+	case kindScript:
+		file.Name = prefix(dir) + camel(base)
+
+		match := mainPattern.FindStringSubmatch(code)
+		if match == nil {
+			return nil, errors.New("no main function")
+		}
+		params, err := parseParams(match[1])
+		if err != nil {
+			return nil, err
+		}
+		file.Params = params
+
+		if strings.TrimSpace(match[2]) == "" {
+			return nil, errors.New("main function returns nothing")
+		}
+		if file.Return, err = parseType(match[2]); err != nil {
+			return nil, err
+		}
+
+		if file.Structs, err = parseStructs(code); err != nil {
+			return nil, err
+		}
+
+		return file, checkNames(file)
+	}
+
+	return nil, fmt.Errorf("unknown kind of source %s", kind)
+}
+
+func parseParams(list string) ([]param, error) {
+	var params []param
+	for _, declaration := range strings.Split(list, ",") {
+		declaration = strings.TrimSpace(declaration)
+		if declaration == "" {
+			continue
+		}
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid parameter %q", declaration)
+		}
+		t, err := parseType(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param{Name: strings.TrimSpace(parts[0]), Type: t})
+	}
+	return params, nil
+}
+
+func parseType(s string) (cadenceType, error) {
+	s = strings.TrimSpace(s)
+
+	var t cadenceType
+	if strings.HasSuffix(s, "?") {
+		t.Optional = true
+		s = strings.TrimSuffix(s, "?")
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		t.Array = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	if !identifierPattern.MatchString(s) {
+		return t, fmt.Errorf("unsupported type %q", s)
+	}
+	t.Name = s
+
+	return t, nil
+}
+
+func parseStructs(code string) ([]structDecl, error) {
+	var structs []structDecl
+	for _, match := range structPattern.FindAllStringSubmatchIndex(code, -1) {
+		body, err := block(code, match[1])
+		if err != nil {
+			return nil, err
+		}
+		decl := structDecl{Name: code[match[2]:match[3]]}
+		for _, field := range fieldPattern.FindAllStringSubmatch(body, -1) {
+			t, err := parseType(field[2])
+			if err != nil {
+				return nil, err
+			}
+			decl.Fields = append(decl.Fields, param{Name: field[1], Type: t})
+		}
+		structs = append(structs, decl)
+	}
+	return structs, nil
+}
+
+// block returns the code up to the brace closing the one opened just before start
+func block(code string, start int) (string, error) {
+	depth := 1
+	for i := start; i < len(code); i++ {
+		switch code[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return code[start:i], nil
+			}
+		}
+	}
+	return "", errors.New("unbalanced braces")
+}
+
+// checkNames makes sure the parameter names can be used as they are in the bindings
+func checkNames(file *cadenceFile) error {
+	seen := map[string]bool{"c": true, "ctx": true, "executor": true, "value": true, "result": true, "err": true}
+	names := append([]string(nil), file.Authorizers...)
+	for _, p := range file.Params {
+		names = append(names, p.Name)
+	}
+	for _, name := range names {
+		if seen[name] || token.Lookup(name).IsKeyword() {
+			return fmt.Errorf("parameter name %s cannot be used in the bindings", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func prefix(dir string) string {
+	if p, ok := prefixes[dir]; ok {
+		return p
+	}
+	return exported(dir)
+}
+
+// camel turns snake_case into CamelCase, spelling out the initialisms like Go does
+func camel(s string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(s, "_") {
+		switch word {
+		case "id":
+			b.WriteString("ID")
+		case "ids":
+			b.WriteString("IDs")
+		default:
+			b.WriteString(exported(word))
+		}
+	}
+	return b.String()
+}
+
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
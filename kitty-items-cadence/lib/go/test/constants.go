@@ -1,9 +0,0 @@
-package test
-
-const (
-	ftAddressPlaceholder         = "0xFUNGIBLETOKENADDRESS"
-	kibbleAddressPlaceHolder     = "0xKIBBLE"
-	nftAddressPlaceholder        = "0xNONFUNGIBLETOKEN"
-	kittyItemsAddressPlaceHolder = "0xKITTYITEMS"
-	kittyItemsMarketPlaceholder  = "0xKITTYMARKET"
-)
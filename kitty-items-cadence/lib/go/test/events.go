@@ -0,0 +1,147 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+// event describes an expected event by its type without the contract address, e.g. `KittyItems.Minted`,
+// and the fields it must have. Fields left out are not checked.
+type event struct {
+	Type   string
+	Fields map[string]cadence.Value
+}
+
+// lastEvents returns the events emitted by the last transaction submitted, the emulator commits each in a block
+// of its own
+func lastEvents(t *testing.T, e *kittyitems.Emulator) []flow.Event {
+	block, err := e.Blockchain.GetLatestBlock()
+	require.NoError(t, err)
+	events, err := e.Blockchain.GetEventsByHeight(block.Header.Height, "")
+	require.NoError(t, err)
+	return events
+}
+
+// eventFields returns the fields of an event by name, with optionals unwrapped
+func eventFields(e flow.Event) map[string]cadence.Value {
+	fields := make(map[string]cadence.Value, len(e.Value.Fields))
+	for i, value := range e.Value.Fields {
+		name := fmt.Sprintf("%d", i)
+		if e.Value.EventType != nil && i < len(e.Value.EventType.Fields) {
+			name = e.Value.EventType.Fields[i].Identifier
+		}
+		fields[name] = unwrapOptional(value)
+	}
+	return fields
+}
+
+func unwrapOptional(value cadence.Value) cadence.Value {
+	if optional, ok := value.(cadence.Optional); ok {
+		return unwrapOptional(optional.Value)
+	}
+	return value
+}
+
+// matches reports whether actual has the type and fields of expected
+func (expected event) matches(actual flow.Event) bool {
+	if actual.Type != expected.Type && !strings.HasSuffix(actual.Type, "."+expected.Type) {
+		return false
+	}
+	fields := eventFields(actual)
+	for name, value := range expected.Fields {
+		field, ok := fields[name]
+		if !ok || !assert.ObjectsAreEqual(unwrapOptional(value), field) {
+			return false
+		}
+	}
+	return true
+}
+
+// assertEmitted asserts that one of events matches expected and returns the first that does
+func assertEmitted(t *testing.T, events []flow.Event, expected event) (flow.Event, bool) {
+	for _, actual := range events {
+		if expected.matches(actual) {
+			return actual, true
+		}
+	}
+	assert.Fail(t, "event not emitted", "expected %s\nin %s", formatExpectedEvent(expected), formatEvents(events))
+	return flow.Event{}, false
+}
+
+// assertNotEmitted asserts that none of events has the type of expected
+func assertNotEmitted(t *testing.T, events []flow.Event, eventType string) bool {
+	for _, actual := range events {
+		if (event{Type: eventType}).matches(actual) {
+			return assert.Fail(t, "unexpected event", "%s\nin %s", eventType, formatEvents(events))
+		}
+	}
+	return true
+}
+
+// assertEventSequence asserts that events contain the expected ones in this order, other events may come
+// in between
+func assertEventSequence(t *testing.T, events []flow.Event, expected ...event) bool {
+	next := 0
+	for _, actual := range events {
+		if next < len(expected) && expected[next].matches(actual) {
+			next++
+		}
+	}
+	if next == len(expected) {
+		return true
+	}
+
+	var sequence []string
+	for _, e := range expected {
+		sequence = append(sequence, formatExpectedEvent(e))
+	}
+	return assert.Fail(t, "event sequence not emitted",
+		"expected %s\nafter %d matched events of the sequence\n%s\nin %s",
+		formatExpectedEvent(expected[next]), next, strings.Join(sequence, "\n"), formatEvents(events))
+}
+
+func formatExpectedEvent(e event) string {
+	return e.Type + formatFields(e.Fields)
+}
+
+func formatEvents(events []flow.Event) string {
+	if len(events) == 0 {
+		return "no events"
+	}
+	var lines []string
+	for _, e := range events {
+		lines = append(lines, "\n  "+e.Type+formatFields(eventFields(e)))
+	}
+	return strings.Join(lines, "")
+}
+
+func formatFields(fields map[string]cadence.Value) string {
+	var formatted []string
+	for name, value := range fields {
+		formatted = append(formatted, name+": "+formatValue(unwrapOptional(value)))
+	}
+	// Map order is random, keep the messages stable
+	sort.Strings(formatted)
+	return "(" + strings.Join(formatted, ", ") + ")"
+}
+
+func formatValue(value cadence.Value) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case cadence.UFix64:
+		return fmt.Sprintf("%d.%08d", uint64(v)/1e8, uint64(v)%1e8)
+	case cadence.Address:
+		return "0x" + v.Hex()
+	}
+	return fmt.Sprint(value.ToGoValue())
+}
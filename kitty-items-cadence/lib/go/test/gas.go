@@ -0,0 +1,134 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+// computationProfile records the computation used by the transaction templates, by the size of the collections
+// they were measured with
+type computationProfile struct {
+	sizes   map[int]bool
+	samples map[string]map[int][]uint64
+}
+
+func newComputationProfile() *computationProfile {
+	return &computationProfile{sizes: make(map[int]bool), samples: make(map[string]map[int][]uint64)}
+}
+
+// measure records the computation tx, built from the template at path, uses with collections of size items,
+// then submits it like signAndSubmit
+func (p *computationProfile) measure(t *testing.T, e *kittyitems.Emulator, path string, size int, tx *flow.Transaction, signer kittyitems.Account) {
+	computation, err := e.MeasureComputation(tx, signer)
+	require.NoError(t, err, "measuring %s", path)
+
+	if p.samples[path] == nil {
+		p.samples[path] = make(map[int][]uint64)
+	}
+	p.samples[path][size] = append(p.samples[path][size], computation)
+	p.sizes[size] = true
+
+	signAndSubmit(t, e, tx, signer, nil)
+}
+
+// templates returns the paths of the templates measured, sorted
+func (p *computationProfile) templates() []string {
+	var templates []string
+	for path := range p.samples {
+		templates = append(templates, path)
+	}
+	sort.Strings(templates)
+	return templates
+}
+
+// max returns the most computation the template at path was measured to use
+func (p *computationProfile) max(path string) uint64 {
+	var all []uint64
+	for _, samples := range p.samples[path] {
+		all = append(all, samples...)
+	}
+	_, _, max := stats(all)
+	return max
+}
+
+// report formats the minimum, median and maximum computation of every template, then the median with each
+// collection size, along with the gas limit of the template
+func (p *computationProfile) report() string {
+	var sizes []int
+	for size := range p.sizes {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "template\tmin\tmedian\tmax\t")
+	for _, size := range sizes {
+		fmt.Fprintf(w, "size %d\t", size)
+	}
+	fmt.Fprintln(w, "gas limit\t")
+
+	for _, path := range p.templates() {
+		var all []uint64
+		for _, samples := range p.samples[path] {
+			all = append(all, samples...)
+		}
+		min, median, max := stats(all)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t", path, min, median, max)
+		for _, size := range sizes {
+			if samples := p.samples[path][size]; len(samples) > 0 {
+				_, median, _ := stats(samples)
+				fmt.Fprintf(w, "%d\t", median)
+			} else {
+				fmt.Fprint(w, "-\t")
+			}
+		}
+		fmt.Fprintf(w, "%d\t\n", kittyitems.GasLimit(path))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// stats returns the minimum, median and maximum of samples
+func stats(samples []uint64) (min, median, max uint64) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	sorted := append([]uint64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[0], sorted[len(sorted)/2], sorted[len(sorted)-1]
+}
+
+// gasLimit derives the gas limit of a template from the most computation it was measured to use: half as much
+// again, rounded up to a multiple of ten, so that the limits don't change with every contract edit
+func gasLimit(computation uint64) uint64 {
+	limit := computation + computation/2
+	return (limit + 9) / 10 * 10
+}
+
+// gasLimitsSource returns the source of the gas limits of kittyitems, derived from the profile
+func (p *computationProfile) gasLimitsSource() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by TestComputationBudgets in ../test with -gas.update. DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package kittyitems")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "// gasLimits holds the gas limits of the transactions, see GasLimit")
+	fmt.Fprintln(&b, "var gasLimits = map[string]uint64{")
+	for _, path := range p.templates() {
+		fmt.Fprintf(&b, "%q: %d,\n", path, gasLimit(p.max(path)))
+	}
+	fmt.Fprintln(&b, "}")
+
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+var (
+	gasUpdate = flag.Bool("gas.update", false, "write the gas limits of kittyitems from the computation measured")
+	gasReport = flag.String("gas.report", "", "write the computation report to `file`")
+)
+
+// gasLimitsFile is the source of the gas limits of kittyitems
+const gasLimitsFile = "../kittyitems/gas_limits.go"
+
+// TestComputationBudgets measures the computation every transaction template uses, with collections of a few
+// sizes, and checks it stays within the gas limit kittyitems sends it with
+func TestComputationBudgets(t *testing.T) {
+	sizes := []int{0, 5, 20}
+	if testing.Short() {
+		sizes = []int{0, 5}
+	}
+
+	profile := newComputationProfile()
+	for _, size := range sizes {
+		measureTemplates(t, profile, size)
+	}
+	if t.Failed() {
+		return
+	}
+
+	templates, err := filepath.Glob("../../../cadence/*/transactions/*.cdc")
+	require.NoError(t, err)
+	for _, template := range templates {
+		path, err := filepath.Rel("../../../cadence", template)
+		require.NoError(t, err)
+		assert.Contains(t, profile.samples, filepath.ToSlash(path), "transaction template not measured")
+	}
+
+	report := profile.report()
+	t.Logf("computation used by the transaction templates:\n%s", report)
+	if *gasReport != "" {
+		require.NoError(t, ioutil.WriteFile(*gasReport, []byte(report), 0644))
+	}
+
+	if *gasUpdate {
+		source, err := profile.gasLimitsSource()
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(gasLimitsFile, source, 0644))
+		return
+	}
+	for _, path := range profile.templates() {
+		max, limit := profile.max(path), kittyitems.GasLimit(path)
+		assert.LessOrEqual(t, max, limit,
+			"%s uses up to %d computation, above its gas limit of %d, rerun with -gas.update", path, max, limit)
+	}
+}
+
+// measureTemplates measures every transaction template on contracts deployed for it alone, once the seller and
+// the buyer own size items each and the seller has listed all of theirs
+func measureTemplates(t *testing.T, profile *computationProfile, size int) {
+	e := newEmulator(t)
+	c := &e.Contracts
+	minter, err := e.DeployContractsToAccount()
+	require.NoError(t, err)
+
+	measure := func(path string, signer kittyitems.Account) func(*flow.Transaction, error) {
+		return func(tx *flow.Transaction, err error) {
+			require.NoError(t, err)
+			profile.measure(t, e, path, size, tx, signer)
+		}
+	}
+
+	user := createAccount(t, e)
+	measure(kittyitems.KibbleSetupAccountTransaction, user)(c.KibbleSetupAccount(user.Address))
+	measure(kittyitems.KittyItemsSetupAccountTransaction, user)(c.KittyItemsSetupAccount(user.Address))
+	measure(kittyitems.MarketSetupAccountTransaction, user)(c.MarketSetupAccount(user.Address))
+
+	seller, err := e.CreateUser()
+	require.NoError(t, err)
+	buyer, err := e.CreateUser()
+	require.NoError(t, err)
+	for id := uint64(0); id < uint64(size); id++ {
+		checkSubmitted(t, e.MintKittyItem(minter, seller.Address, typeID1337), nil)
+		checkSubmitted(t, e.ListItem(seller, id, CadenceUFix64("1.0")), nil)
+	}
+	for i := 0; i < size; i++ {
+		checkSubmitted(t, e.MintKittyItem(minter, buyer.Address, typeID1337), nil)
+	}
+
+	amount := CadenceUFix64("10.0")
+	measure(kittyitems.KibbleMintTokensTransaction, minter)(c.KibbleMintTokens(minter.Address, buyer.Address, CadenceUFix64("100.0")))
+	measure(kittyitems.KibbleTransferTokensTransaction, buyer)(c.KibbleTransferTokens(buyer.Address, amount, seller.Address))
+	measure(kittyitems.KibbleBurnTokensTransaction, seller)(c.KibbleBurnTokens(seller.Address, amount))
+	measure(kittyitems.KibbleCreateMinterTransaction, minter)(c.KibbleCreateMinter(minter.Address, amount))
+	measure(kittyitems.KibbleMintTokensWithMinterTransaction, minter)(c.KibbleMintTokensWithMinter(minter.Address, buyer.Address, amount))
+	measure(kittyitems.KibbleTopUpMinterTransaction, minter)(c.KibbleTopUpMinter(minter.Address, amount))
+
+	id := uint64(2 * size)
+	price := CadenceUFix64("5.0")
+	measure(kittyitems.KittyItemsMintKittyItemTransaction, minter)(c.KittyItemsMintKittyItem(minter.Address, seller.Address, typeID1337))
+	measure(kittyitems.MarketSellMarketItemTransaction, seller)(c.MarketSellMarketItem(seller.Address, id, price))
+	measure(kittyitems.MarketRemoveMarketItemTransaction, seller)(c.MarketRemoveMarketItem(seller.Address, id))
+	measure(kittyitems.MarketSellMarketItemTransaction, seller)(c.MarketSellMarketItem(seller.Address, id, price))
+	measure(kittyitems.MarketBuyMarketItemTransaction, buyer)(c.MarketBuyMarketItem(buyer.Address, id, seller.Address))
+	measure(kittyitems.KittyItemsTransferKittyItemTransaction, buyer)(c.KittyItemsTransferKittyItem(buyer.Address, seller.Address, id))
+}
@@ -4,11 +4,9 @@ go 1.14
 
 require (
 	github.com/onflow/cadence v0.10.2
-	github.com/onflow/flow-emulator v0.12.3
-	github.com/onflow/flow-ft/lib/go/contracts v0.2.1-0.20201002112420-010719813062
-	github.com/onflow/flow-go v0.12.3 // indirect
 	github.com/onflow/flow-go-sdk v0.12.2
-	github.com/onflow/flow-nft/lib/go/contracts v0.0.0-20201125231514-e1170127bdb6
-	github.com/onflow/flow-nft/lib/go/templates v0.0.0-20201125231514-e1170127bdb6
+	github.com/onflow/kitty-items/lib/go/kittyitems v0.0.0-00010101000000-000000000000
 	github.com/stretchr/testify v1.6.1
 )
+
+replace github.com/onflow/kitty-items/lib/go/kittyitems => ../kittyitems
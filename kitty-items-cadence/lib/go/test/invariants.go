@@ -0,0 +1,363 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+// opKind is the kind of an operation played by the invariant tests
+type opKind int
+
+const (
+	opMintKibble opKind = iota
+	opTransferKibble
+	opBurnKibble
+	opMintItem
+	opTransferItem
+	opList
+	opBuy
+	opRemove
+	opKinds
+)
+
+// op is an operation played by the invariant tests. Accounts are indexes in the accounts of a run, the first one
+// being the minter holding the contracts. The operation is signed by from, to is the recipient or, for a purchase,
+// the seller.
+type op struct {
+	kind   opKind
+	from   int
+	to     int
+	itemID uint64
+	typeID uint64
+	amount string
+}
+
+func (o op) String() string {
+	from, to := accountName(o.from), accountName(o.to)
+	switch o.kind {
+	case opMintKibble:
+		return fmt.Sprintf("%s mints %s kibble to %s", from, o.amount, to)
+	case opTransferKibble:
+		return fmt.Sprintf("%s sends %s kibble to %s", from, o.amount, to)
+	case opBurnKibble:
+		return fmt.Sprintf("%s burns %s kibble", from, o.amount)
+	case opMintItem:
+		return fmt.Sprintf("%s mints an item of type %d to %s", from, o.typeID, to)
+	case opTransferItem:
+		return fmt.Sprintf("%s sends item %d to %s", from, o.itemID, to)
+	case opList:
+		return fmt.Sprintf("%s lists item %d for %s", from, o.itemID, o.amount)
+	case opBuy:
+		return fmt.Sprintf("%s buys item %d from %s", from, o.itemID, to)
+	case opRemove:
+		return fmt.Sprintf("%s unlists item %d", from, o.itemID)
+	}
+	return fmt.Sprintf("unknown operation %d", o.kind)
+}
+
+func accountName(account int) string {
+	if account == 0 {
+		return minter
+	}
+	return fmt.Sprintf("account%d", account)
+}
+
+func formatOps(ops []op) string {
+	lines := make([]string, len(ops))
+	for i, o := range ops {
+		lines[i] = fmt.Sprintf("%3d. %s", i+1, o)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateOps draws n operations among accounts with r, at least two. It follows the items it mints and the
+// offers it lists, assuming every operation succeeds, so that most operations it draws are valid and some are
+// not: an owner an item was sold by, an offer bought already, an amount above a balance.
+func generateOps(r *rand.Rand, accounts int, n int) []op {
+	var (
+		amounts  = []string{"0.5", "1.0", "2.5", "10.0"}
+		owners   []int
+		listings []op
+		closed   []op
+	)
+	account := func() int { return r.Intn(accounts) }
+	likely := func() bool { return r.Intn(5) > 0 }
+
+	ops := make([]op, n)
+	for i := range ops {
+		o := op{kind: opKind(r.Intn(int(opKinds))), from: account(), to: account(), amount: amounts[r.Intn(len(amounts))]}
+		// Operations on items and offers that don't exist yet would all revert the same way
+		if (o.kind == opBuy || o.kind == opRemove) && len(listings) == 0 && (len(closed) == 0 || likely()) {
+			o.kind = opList
+		}
+		if (o.kind == opTransferItem || o.kind == opList) && len(owners) == 0 {
+			o.kind = opMintItem
+		}
+		if len(owners) > 0 {
+			o.itemID = uint64(r.Intn(len(owners)))
+		}
+
+		switch o.kind {
+		case opMintKibble:
+			o.from = 0
+		case opMintItem:
+			o.from = 0
+			o.typeID = uint64(1 + r.Intn(3))
+			owners = append(owners, o.to)
+		case opTransferItem, opList:
+			if likely() {
+				o.from = owners[o.itemID]
+			}
+			if o.kind == opList {
+				// Listing an item again replaces its offer
+				for j, listing := range listings {
+					if listing.from == o.from && listing.itemID == o.itemID {
+						listings = append(listings[:j], listings[j+1:]...)
+						break
+					}
+				}
+				listings = append(listings, o)
+			} else {
+				owners[o.itemID] = o.to
+			}
+		case opBuy, opRemove:
+			if len(listings) > 0 && likely() {
+				j := r.Intn(len(listings))
+				listing := listings[j]
+				o.itemID, o.to = listing.itemID, listing.from
+				listings = append(listings[:j], listings[j+1:]...)
+				closed = append(closed, listing)
+			} else if len(closed) > 0 {
+				// Bought or unlisted already
+				listing := closed[r.Intn(len(closed))]
+				o.itemID, o.to = listing.itemID, listing.from
+			}
+			if o.kind == opRemove {
+				o.from = o.to
+				break
+			}
+			for o.from == o.to {
+				o.from = account()
+			}
+			if uint64(len(owners)) > o.itemID {
+				owners[o.itemID] = o.from
+			}
+		}
+		ops[i] = o
+	}
+	return ops
+}
+
+// violation is an invariant broken by the operation at step
+type violation struct {
+	step    int
+	op      op
+	message string
+}
+
+func (v *violation) Error() string {
+	return fmt.Sprintf("after step %d, %s: %s", v.step+1, v.op, v.message)
+}
+
+// offer identifies a sale offer by its seller and item
+type offer struct {
+	seller int
+	itemID uint64
+}
+
+// invariantRun plays operations on contracts deployed for it alone
+type invariantRun struct {
+	e        *kittyitems.Emulator
+	accounts []kittyitems.Account
+	// sold holds the offers bought since they were listed
+	sold map[offer]bool
+}
+
+// initialBalance is the kibble every account of an invariantRun starts with
+const initialBalance = "100.0"
+
+func newInvariantRun(accounts int) (*invariantRun, error) {
+	e, err := kittyitems.NewEmulator()
+	if err != nil {
+		return nil, err
+	}
+	minter, err := e.DeployContractsToAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &invariantRun{e: e, accounts: []kittyitems.Account{minter}, sold: make(map[offer]bool)}
+	for len(r.accounts) < accounts {
+		user, err := e.CreateUser()
+		if err != nil {
+			return nil, err
+		}
+		r.accounts = append(r.accounts, user)
+	}
+	// Enough for purchases to go through from the first operations
+	for _, account := range r.accounts {
+		if err := e.MintKibble(minter, account.Address, CadenceUFix64(initialBalance)); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// playOps plays ops on a new run with accounts accounts and returns the first *violation, or the error that
+// prevented playing them
+func playOps(accounts int, ops []op) error {
+	r, err := newInvariantRun(accounts)
+	if err != nil {
+		return err
+	}
+	for i, o := range ops {
+		message, err := r.play(o)
+		if err == nil && message == "" {
+			message, err = r.check()
+		}
+		if err != nil {
+			return fmt.Errorf("error playing step %d, %s = %w", i+1, o, err)
+		}
+		if message != "" {
+			return &violation{step: i, op: o, message: message}
+		}
+	}
+	return nil
+}
+
+// play sends the transaction of o and describes how it broke the market invariants, if it did
+func (r *invariantRun) play(o op) (string, error) {
+	tx, err := r.transaction(o)
+	if err != nil {
+		return "", err
+	}
+	_, err = r.e.Submit(tx, r.accounts[o.from])
+	reverted := errors.Is(err, kittyitems.ErrTransactionReverted)
+	if err != nil && !reverted {
+		return "", err
+	}
+
+	switch o.kind {
+	case opList, opRemove:
+		if !reverted {
+			delete(r.sold, offer{o.from, o.itemID})
+		}
+	case opBuy:
+		key := offer{o.to, o.itemID}
+		if r.sold[key] && !reverted {
+			return fmt.Sprintf("the offer of %s for item %d was bought twice", accountName(o.to), o.itemID), nil
+		}
+		if !reverted {
+			r.sold[key] = true
+		}
+	}
+	return "", nil
+}
+
+func (r *invariantRun) transaction(o op) (*flow.Transaction, error) {
+	c := r.e.Contracts
+	from, to := r.accounts[o.from].Address, r.accounts[o.to].Address
+	amount := CadenceUFix64(o.amount)
+
+	switch o.kind {
+	case opMintKibble:
+		return c.KibbleMintTokens(from, to, amount)
+	case opTransferKibble:
+		return c.KibbleTransferTokens(from, amount, to)
+	case opBurnKibble:
+		return c.KibbleBurnTokens(from, amount)
+	case opMintItem:
+		return c.KittyItemsMintKittyItem(from, to, o.typeID)
+	case opTransferItem:
+		return c.KittyItemsTransferKittyItem(from, to, o.itemID)
+	case opList:
+		return c.MarketSellMarketItem(from, o.itemID, amount)
+	case opBuy:
+		return c.MarketBuyMarketItem(from, o.itemID, to)
+	case opRemove:
+		return c.MarketRemoveMarketItem(from, o.itemID)
+	}
+	return nil, fmt.Errorf("unknown operation %d", o.kind)
+}
+
+// check describes the invariant broken by the state of the accounts, if any: the Kibble supply is the sum of
+// the balances, and every item minted is in exactly one collection
+func (r *invariantRun) check() (string, error) {
+	ctx := context.Background()
+	c := r.e.Contracts
+
+	supply, err := c.KibbleGetSupply(ctx, r.e)
+	if err != nil {
+		return "", err
+	}
+	var balances uint64
+	for _, account := range r.accounts {
+		balance, err := c.KibbleGetBalance(ctx, r.e, account.Address)
+		if err != nil {
+			return "", err
+		}
+		balances += uint64(balance)
+	}
+	if uint64(supply) != balances {
+		return fmt.Sprintf("Kibble.totalSupply is %s but the vaults hold %s",
+			formatValue(supply), formatValue(cadence.UFix64(balances))), nil
+	}
+
+	items, err := c.KittyItemsReadKittyItemsSupply(ctx, r.e)
+	if err != nil {
+		return "", err
+	}
+	owners := make(map[uint64][]string)
+	for i, account := range r.accounts {
+		ids, err := c.KittyItemsReadCollectionIDs(ctx, r.e, account.Address)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range ids {
+			owners[id] = append(owners[id], accountName(i))
+		}
+	}
+	var problems []string
+	for id := uint64(0); id < items; id++ {
+		if len(owners[id]) != 1 {
+			problems = append(problems, fmt.Sprintf("item %d is in %d collections %v", id, len(owners[id]), owners[id]))
+		}
+	}
+	for id, collections := range owners {
+		if id >= items {
+			problems = append(problems, fmt.Sprintf("item %d is in %v but only %d were minted", id, collections, items))
+		}
+	}
+	sort.Strings(problems)
+	return strings.Join(problems, ", "), nil
+}
+
+// shrink returns a subsequence of ops for which fails still holds. It removes chunks of operations while fails
+// holds without them, from halves of the sequence down to single operations.
+func shrink(ops []op, fails func([]op) bool) []op {
+	size := len(ops) / 2
+	for size > 0 {
+		removed := false
+		for start := 0; start+size <= len(ops); {
+			candidate := append(append([]op(nil), ops[:start]...), ops[start+size:]...)
+			if fails(candidate) {
+				ops, removed = candidate, true
+				continue
+			}
+			start += size
+		}
+		if !removed || size > len(ops)/2 {
+			size /= 2
+		}
+	}
+	return ops
+}
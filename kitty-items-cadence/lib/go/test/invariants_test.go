@@ -0,0 +1,74 @@
+package test
+
+import (
+	"errors"
+	"flag"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	invariantsSeed  = flag.Int64("invariants.seed", 0, "seed of the first sequence of operations played by TestInvariants, drawn when 0")
+	invariantsRuns  = flag.Int("invariants.runs", 2, "number of sequences of operations played by TestInvariants")
+	invariantsSteps = flag.Int("invariants.steps", 40, "number of operations in each sequence played by TestInvariants")
+)
+
+// invariantsAccounts is the number of accounts operations are played among, the minter included
+const invariantsAccounts = 4
+
+func TestInvariants(t *testing.T) {
+	seed := *invariantsSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	runs := *invariantsRuns
+	if testing.Short() {
+		runs = 1
+	}
+
+	for run := 0; run < runs; run++ {
+		runSeed := seed + int64(run)
+		t.Logf("playing %d operations with -invariants.seed=%d", *invariantsSteps, runSeed)
+		ops := generateOps(rand.New(rand.NewSource(runSeed)), invariantsAccounts, *invariantsSteps)
+
+		err := playOps(invariantsAccounts, ops)
+		var v *violation
+		if !errors.As(err, &v) {
+			require.NoError(t, err)
+			continue
+		}
+
+		shrunk := shrink(ops, func(ops []op) bool {
+			return errors.As(playOps(invariantsAccounts, ops), new(*violation))
+		})
+		require.True(t, errors.As(playOps(invariantsAccounts, shrunk), &v))
+		t.Fatalf("invariant broken with -invariants.seed=%d\n%s\nminimal sequence:\n%s", runSeed, v, formatOps(shrunk))
+	}
+}
+
+func TestShrink(t *testing.T) {
+	ops := generateOps(rand.New(rand.NewSource(1)), invariantsAccounts, 50)
+	ops[17] = op{kind: opBurnKibble, amount: "7.0"}
+	ops[31] = op{kind: opBurnKibble, amount: "8.0"}
+
+	// Fails when a burn of 7.0 is followed by a burn of 8.0, whatever comes around them, the generated operations
+	// use other amounts
+	fails := func(ops []op) bool {
+		seen := false
+		for _, o := range ops {
+			if o.kind == opBurnKibble && o.amount == "7.0" {
+				seen = true
+			}
+			if seen && o.kind == opBurnKibble && o.amount == "8.0" {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.Equal(t, []op{ops[17], ops[31]}, shrink(ops, fails))
+}
@@ -1,316 +1,224 @@
 package test
 
 import (
-	"strings"
+	"context"
 	"testing"
 
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-
 	"github.com/onflow/cadence"
-	jsoncdc "github.com/onflow/cadence/encoding/json"
-	emulator "github.com/onflow/flow-emulator"
 	"github.com/onflow/flow-go-sdk"
-	sdk "github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/crypto"
-	"github.com/onflow/flow-go-sdk/templates"
-	"github.com/onflow/flow-go-sdk/test"
-
-	ft_contracts "github.com/onflow/flow-ft/lib/go/contracts"
-)
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
-const (
-	kibbleRootPath           = "../../../cadence/kibble"
-	kibbleKibblePath         = kibbleRootPath + "/contracts/Kibble.cdc"
-	kibbleSetupAccountPath   = kibbleRootPath + "/transactions/setup_account.cdc"
-	kibbleTransferTokensPath = kibbleRootPath + "/transactions/transfer_tokens.cdc"
-	kibbleMintTokensPath     = kibbleRootPath + "/transactions/mint_tokens.cdc"
-	kibbleBurnTokensPath     = kibbleRootPath + "/transactions/burn_tokens.cdc"
-	kibbleGetBalancePath     = kibbleRootPath + "/scripts/get_balance.cdc"
-	kibbleGetSupplyPath      = kibbleRootPath + "/scripts/get_supply.cdc"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
 )
 
-func KibbleDeployContracts(b *emulator.Blockchain, t *testing.T) (flow.Address, flow.Address, crypto.Signer) {
-	accountKeys := test.AccountKeyGenerator()
-
-	// Should be able to deploy a contract as a new account with no keys.
-	fungibleTokenCode := loadFungibleToken()
-	fungibleAddr, err := b.CreateAccount(
-		[]*flow.AccountKey{},
-		[]templates.Contract{templates.Contract{
-			Name:   "FungibleToken",
-			Source: string(fungibleTokenCode),
-		}},
-	)
-	assert.NoError(t, err)
-
-	_, err = b.CommitBlock()
-	assert.NoError(t, err)
-
-	kibbleAccountKey, kibbleSigner := accountKeys.NewWithSigner()
-	kibbleCode := loadKibble(fungibleAddr)
-
-	kibbleAddr, err := b.CreateAccount(
-		[]*flow.AccountKey{kibbleAccountKey},
-		[]templates.Contract{templates.Contract{
-			Name:   "Kibble",
-			Source: string(kibbleCode),
-		}},
-	)
-	assert.NoError(t, err)
-
-	_, err = b.CommitBlock()
-	assert.NoError(t, err)
-
-	// Simplify testing by having the contract address also be our initial Vault.
-	KibbleSetupAccount(t, b, kibbleAddr, kibbleSigner, fungibleAddr, kibbleAddr)
-
-	return fungibleAddr, kibbleAddr, kibbleSigner
+func KibbleDeployContracts(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	kibble, err := e.DeployKibble()
+	require.NoError(t, err)
+	return kibble
 }
 
-func KibbleSetupAccount(t *testing.T, b *emulator.Blockchain, userAddress sdk.Address, userSigner crypto.Signer, fungibleAddr sdk.Address, kibbleAddr sdk.Address) {
-	tx := flow.NewTransaction().
-		SetScript(kibbleGenerateSetupKibbleAccountTransaction(fungibleAddr, kibbleAddr)).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(userAddress)
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, userAddress},
-		[]crypto.Signer{b.ServiceKey().Signer(), userSigner},
-		false,
-	)
+func KibbleCreateAccount(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	user := createAccount(t, e)
+	require.NoError(t, e.SetupKibbleAccount(user))
+	return user
 }
 
-func KibbleCreateAccount(t *testing.T, b *emulator.Blockchain, fungibleAddr sdk.Address, kibbleAddr sdk.Address) (sdk.Address, crypto.Signer) {
-	userAddress, userSigner, _ := createAccount(t, b)
-	KibbleSetupAccount(t, b, userAddress, userSigner, fungibleAddr, kibbleAddr)
-	return userAddress, userSigner
+func kibbleGetBalance(t *testing.T, e *kittyitems.Emulator, account kittyitems.Account) cadence.UFix64 {
+	balance, err := e.Contracts.KibbleGetBalance(context.Background(), e, account.Address)
+	require.NoError(t, err)
+	return balance
 }
 
-func KibbleMint(t *testing.T, b *emulator.Blockchain, fungibleAddr sdk.Address, kibbleAddr sdk.Address, kibbleSigner crypto.Signer, recipientAddress flow.Address, amount string, shouldRevert bool) {
-	tx := flow.NewTransaction().
-		SetScript(kibbleGenerateMintKibbleTransaction(fungibleAddr, kibbleAddr)).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(kibbleAddr)
-
-	_ = tx.AddArgument(cadence.NewAddress(recipientAddress))
-	_ = tx.AddArgument(CadenceUFix64(amount))
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, kibbleAddr},
-		[]crypto.Signer{b.ServiceKey().Signer(), kibbleSigner},
-		shouldRevert,
-	)
-
+func kibbleGetSupply(t *testing.T, e *kittyitems.Emulator) cadence.UFix64 {
+	supply, err := e.Contracts.KibbleGetSupply(context.Background(), e)
+	require.NoError(t, err)
+	return supply
 }
 
 func TestKibbleDeployment(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	fungibleAddr, kibbleAddr, _ := KibbleDeployContracts(b, t)
+	KibbleDeployContracts(t, e)
 
 	t.Run("Should have initialized Supply field correctly", func(t *testing.T) {
-		supply := executeScriptAndCheck(t, b, kibbleGenerateGetSupplyScript(fungibleAddr, kibbleAddr), nil)
+		supply := kibbleGetSupply(t, e)
 		expectedSupply, expectedSupplyErr := cadence.NewUFix64("0.0")
 		assert.NoError(t, expectedSupplyErr)
-		assert.Equal(t, expectedSupply, supply.(cadence.UFix64))
+		assert.Equal(t, expectedSupply, supply)
 	})
 }
 
 func TestKibbleSetupAccount(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
 	t.Run("Should be able to create empty Vault that doesn't affect supply", func(t *testing.T) {
+		KibbleDeployContracts(t, e)
 
-		fungibleAddr, kibbleAddr, _ := KibbleDeployContracts(b, t)
-
-		userAddress, _ := KibbleCreateAccount(t, b, fungibleAddr, kibbleAddr)
+		user := KibbleCreateAccount(t, e)
 
-		balance := executeScriptAndCheck(t, b, kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr), [][]byte{jsoncdc.MustEncode(cadence.Address(userAddress))})
-		assert.Equal(t, CadenceUFix64("0.0"), balance)
+		assert.Equal(t, CadenceUFix64("0.0"), kibbleGetBalance(t, e, user))
 
-		supply := executeScriptAndCheck(t, b, kibbleGenerateGetSupplyScript(fungibleAddr, kibbleAddr), nil)
-		assert.Equal(t, CadenceUFix64("0.0"), supply.(cadence.UFix64))
+		supply := kibbleGetSupply(t, e)
+		assert.Equal(t, CadenceUFix64("0.0"), supply)
 	})
 }
 
 func TestKibbleMinting(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	fungibleAddr, kibbleAddr, kibbleSigner := KibbleDeployContracts(b, t)
+	kibble := KibbleDeployContracts(t, e)
 
-	userAddress, _ := KibbleCreateAccount(t, b, fungibleAddr, kibbleAddr)
+	user := KibbleCreateAccount(t, e)
 
 	t.Run("Shouldn't be able to mint zero tokens", func(t *testing.T) {
-		KibbleMint(t, b, fungibleAddr, kibbleAddr, kibbleSigner, userAddress, "0.0", true)
+		checkSubmitted(t, e.MintKibble(kibble, user.Address, CadenceUFix64("0.0")), errorContaining("Amount minted must be greater than zero"))
 	})
 
 	t.Run("Should mint tokens, deposit, and update balance and total supply", func(t *testing.T) {
-		KibbleMint(t, b, fungibleAddr, kibbleAddr, kibbleSigner, userAddress, "50.0", false)
+		checkSubmitted(t, e.MintKibble(kibble, user.Address, CadenceUFix64("50.0")), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"Kibble.TokensMinted", map[string]cadence.Value{"amount": CadenceUFix64("50.0")}},
+			event{"Kibble.TokensDeposited", map[string]cadence.Value{"amount": CadenceUFix64("50.0"), "to": cadence.NewAddress(user.Address)}},
+		)
 
 		// Assert that the vault's balance is correct
-		result, err := b.ExecuteScript(kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr), [][]byte{jsoncdc.MustEncode(cadence.Address(userAddress))})
-		require.NoError(t, err)
-		if !assert.True(t, result.Succeeded()) {
-			t.Log(result.Error.Error())
-		}
-		balance := result.Value
-		assert.Equal(t, CadenceUFix64("50.0"), balance.(cadence.UFix64))
+		assert.Equal(t, CadenceUFix64("50.0"), kibbleGetBalance(t, e, user))
 
 		// Make sure that the total supply is correct
-		supply := executeScriptAndCheck(t, b, kibbleGenerateGetSupplyScript(fungibleAddr, kibbleAddr), nil)
-		assert.Equal(t, CadenceUFix64("50.0"), supply.(cadence.UFix64))
+		supply := kibbleGetSupply(t, e)
+		assert.Equal(t, CadenceUFix64("50.0"), supply)
+	})
+}
+
+func TestKibbleMinterAllowance(t *testing.T) {
+	e := newEmulator(t)
+
+	kibble := KibbleDeployContracts(t, e)
+
+	user := KibbleCreateAccount(t, e)
+
+	getAllowance := func() cadence.UFix64 {
+		allowance, err := e.Contracts.KibbleGetMinterAllowance(context.Background(), e, kibble.Address)
+		require.NoError(t, err)
+		return allowance
+	}
+	createMinter := func(allowedAmount string, expected *expectedError) []flow.Event {
+		tx, err := e.Contracts.KibbleCreateMinter(kibble.Address, CadenceUFix64(allowedAmount))
+		require.NoError(t, err)
+		return signAndSubmit(t, e, tx, kibble, expected)
+	}
+	mintWithMinter := func(amount string, expected *expectedError) []flow.Event {
+		tx, err := e.Contracts.KibbleMintTokensWithMinter(kibble.Address, user.Address, CadenceUFix64(amount))
+		require.NoError(t, err)
+		return signAndSubmit(t, e, tx, kibble, expected)
+	}
+	topUpMinter := func(amount string) {
+		tx, err := e.Contracts.KibbleTopUpMinter(kibble.Address, CadenceUFix64(amount))
+		require.NoError(t, err)
+		signAndSubmit(t, e, tx, kibble, nil)
+	}
+
+	t.Run("Should be able to provision a minter with an allowance", func(t *testing.T) {
+		events := createMinter("100.0", nil)
+		assertEmitted(t, events, event{"Kibble.MinterCreated", map[string]cadence.Value{"allowedAmount": CadenceUFix64("100.0")}})
+		assert.Equal(t, CadenceUFix64("100.0"), getAllowance())
+	})
+
+	t.Run("Shouldn't be able to provision a second minter", func(t *testing.T) {
+		createMinter("100.0", errorContaining("A minter already exists"))
+	})
+
+	t.Run("Should mint within the allowance and deduct from it", func(t *testing.T) {
+		events := mintWithMinter("60.0", nil)
+		assertEventSequence(t, events,
+			event{"Kibble.TokensMinted", map[string]cadence.Value{"amount": CadenceUFix64("60.0")}},
+			event{"Kibble.TokensDeposited", map[string]cadence.Value{"amount": CadenceUFix64("60.0"), "to": cadence.NewAddress(user.Address)}},
+		)
+		assert.Equal(t, CadenceUFix64("40.0"), getAllowance())
+
+		assert.Equal(t, CadenceUFix64("60.0"), kibbleGetBalance(t, e, user))
+	})
+
+	t.Run("Shouldn't be able to mint more than the allowance", func(t *testing.T) {
+		events := mintWithMinter("50.0", errorContaining("Amount minted must be less than the allowed amount"))
+		assertNotEmitted(t, events, "Kibble.TokensMinted")
+		assert.Equal(t, CadenceUFix64("40.0"), getAllowance())
+	})
+
+	t.Run("Should be able to top up the allowance", func(t *testing.T) {
+		topUpMinter("25.0")
+		assert.Equal(t, CadenceUFix64("65.0"), getAllowance())
+
+		mintWithMinter("50.0", nil)
+		assert.Equal(t, CadenceUFix64("15.0"), getAllowance())
 	})
 }
 
 func TestKibbleTransfers(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	fungibleAddr, kibbleAddr, kibbleSigner := KibbleDeployContracts(b, t)
+	kibble := KibbleDeployContracts(t, e)
 
-	userAddress, _ := KibbleCreateAccount(t, b, fungibleAddr, kibbleAddr)
+	user := KibbleCreateAccount(t, e)
 
-	KibbleMint(t, b, fungibleAddr, kibbleAddr, kibbleSigner, kibbleAddr, "1000.0", false)
+	checkSubmitted(t, e.MintKibble(kibble, kibble.Address, CadenceUFix64("1000.0")), nil)
 
 	t.Run("Shouldn't be able to withdraw more than the balance of the Vault", func(t *testing.T) {
-		tx := flow.NewTransaction().
-			SetScript(kibbleGenerateTransferVaultScript(fungibleAddr, kibbleAddr)).
-			SetGasLimit(100).
-			SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-			SetPayer(b.ServiceKey().Address).
-			AddAuthorizer(kibbleAddr)
-
-		_ = tx.AddArgument(CadenceUFix64("30000.0"))
-		_ = tx.AddArgument(cadence.NewAddress(userAddress))
-
-		signAndSubmit(
-			t, b, tx,
-			[]flow.Address{b.ServiceKey().Address, kibbleAddr},
-			[]crypto.Signer{b.ServiceKey().Signer(), kibbleSigner},
-			true,
-		)
+		checkSubmitted(t, e.TransferKibble(kibble, user.Address, CadenceUFix64("30000.0")),
+			errorContaining("Amount withdrawn must be less than or equal than the balance of the Vault"))
 
 		// Assert that the vaults' balances are correct
-		result, err := b.ExecuteScript(kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr), [][]byte{jsoncdc.MustEncode(cadence.Address(kibbleAddr))})
-		require.NoError(t, err)
-		if !assert.True(t, result.Succeeded()) {
-			t.Log(result.Error.Error())
-		}
-		balance := result.Value
-		assert.Equal(t, balance.(cadence.UFix64), CadenceUFix64("1000.0"))
-
-		result, err = b.ExecuteScript(kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr), [][]byte{jsoncdc.MustEncode(cadence.Address(userAddress))})
-		require.NoError(t, err)
-		if !assert.True(t, result.Succeeded()) {
-			t.Log(result.Error.Error())
-		}
-		balance = result.Value
-		assert.Equal(t, balance.(cadence.UFix64), CadenceUFix64("0.0"))
+		assert.Equal(t, CadenceUFix64("1000.0"), kibbleGetBalance(t, e, kibble))
+		assert.Equal(t, CadenceUFix64("0.0"), kibbleGetBalance(t, e, user))
 	})
 
 	t.Run("Should be able to withdraw and deposit tokens from a vault", func(t *testing.T) {
-		tx := flow.NewTransaction().
-			SetScript(kibbleGenerateTransferVaultScript(fungibleAddr, kibbleAddr)).
-			SetGasLimit(100).
-			SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-			SetPayer(b.ServiceKey().Address).
-			AddAuthorizer(kibbleAddr)
-
-		_ = tx.AddArgument(CadenceUFix64("300.0"))
-		_ = tx.AddArgument(cadence.NewAddress(userAddress))
-
-		signAndSubmit(
-			t, b, tx,
-			[]flow.Address{b.ServiceKey().Address, kibbleAddr},
-			[]crypto.Signer{b.ServiceKey().Signer(), kibbleSigner},
-			false,
+		checkSubmitted(t, e.TransferKibble(kibble, user.Address, CadenceUFix64("300.0")), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"Kibble.TokensWithdrawn", map[string]cadence.Value{"amount": CadenceUFix64("300.0"), "from": cadence.NewAddress(kibble.Address)}},
+			event{"Kibble.TokensDeposited", map[string]cadence.Value{"amount": CadenceUFix64("300.0"), "to": cadence.NewAddress(user.Address)}},
 		)
 
 		// Assert that the vaults' balances are correct
-		result, err := b.ExecuteScript(kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr), [][]byte{jsoncdc.MustEncode(cadence.Address(kibbleAddr))})
-		require.NoError(t, err)
-		if !assert.True(t, result.Succeeded()) {
-			t.Log(result.Error.Error())
-		}
-		balance := result.Value
-		assert.Equal(t, balance.(cadence.UFix64), CadenceUFix64("700.0"))
+		assert.Equal(t, CadenceUFix64("700.0"), kibbleGetBalance(t, e, kibble))
+		assert.Equal(t, CadenceUFix64("300.0"), kibbleGetBalance(t, e, user))
 
-		result, err = b.ExecuteScript(kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr), [][]byte{jsoncdc.MustEncode(cadence.Address(userAddress))})
-		require.NoError(t, err)
-		if !assert.True(t, result.Succeeded()) {
-			t.Log(result.Error.Error())
-		}
-		balance = result.Value
-		assert.Equal(t, balance.(cadence.UFix64), CadenceUFix64("300.0"))
-
-		supply := executeScriptAndCheck(t, b, kibbleGenerateGetSupplyScript(fungibleAddr, kibbleAddr), nil)
-		assert.Equal(t, supply.(cadence.UFix64), CadenceUFix64("1000.0"))
+		supply := kibbleGetSupply(t, e)
+		assert.Equal(t, supply, CadenceUFix64("1000.0"))
 	})
 }
 
-func kibbleReplaceAddressPlaceholders(code string, fungibleAddress, kibbleAddress string) []byte {
-	return []byte(replaceStrings(
-		code,
-		map[string]string{
-			ftAddressPlaceholder:     "0x" + fungibleAddress,
-			kibbleAddressPlaceHolder: "0x" + kibbleAddress,
-		},
-	))
-}
+func TestKibbleBurning(t *testing.T) {
+	e := newEmulator(t)
 
-func loadFungibleToken() []byte {
-	return ft_contracts.FungibleToken()
-}
+	kibble := KibbleDeployContracts(t, e)
 
-func loadKibble(fungibleAddr flow.Address) []byte {
-	return []byte(strings.ReplaceAll(
-		string(readFile(kibbleKibblePath)),
-		ftAddressPlaceholder,
-		"0x"+fungibleAddr.String(),
-	))
-}
+	user := KibbleCreateAccount(t, e)
 
-func kibbleGenerateGetSupplyScript(fungibleAddr, kibbleAddr flow.Address) []byte {
-	return kibbleReplaceAddressPlaceholders(
-		string(readFile(kibbleGetSupplyPath)),
-		fungibleAddr.String(),
-		kibbleAddr.String(),
-	)
-}
+	checkSubmitted(t, e.MintKibble(kibble, user.Address, CadenceUFix64("100.0")), nil)
 
-func kibbleGenerateGetBalanceScript(fungibleAddr, kibbleAddr flow.Address) []byte {
-	return kibbleReplaceAddressPlaceholders(
-		string(readFile(kibbleGetBalancePath)),
-		fungibleAddr.String(),
-		kibbleAddr.String(),
-	)
-}
-func kibbleGenerateTransferVaultScript(fungibleAddr, kibbleAddr flow.Address) []byte {
-	return kibbleReplaceAddressPlaceholders(
-		string(readFile(kibbleTransferTokensPath)),
-		fungibleAddr.String(),
-		kibbleAddr.String(),
-	)
-}
+	burn := func(amount string, expected *expectedError) []flow.Event {
+		tx, err := e.Contracts.KibbleBurnTokens(user.Address, CadenceUFix64(amount))
+		require.NoError(t, err)
+		return signAndSubmit(t, e, tx, user, expected)
+	}
+
+	t.Run("Should burn tokens and deduct them from the total supply", func(t *testing.T) {
+		events := burn("40.0", nil)
+		assertEventSequence(t, events,
+			event{"Kibble.TokensWithdrawn", map[string]cadence.Value{"amount": CadenceUFix64("40.0"), "from": cadence.NewAddress(user.Address)}},
+			event{"Kibble.TokensBurned", map[string]cadence.Value{"amount": CadenceUFix64("40.0")}},
+		)
 
-func kibbleGenerateSetupKibbleAccountTransaction(fungibleAddr, kibbleAddr flow.Address) []byte {
-	return kibbleReplaceAddressPlaceholders(
-		string(readFile(kibbleSetupAccountPath)),
-		fungibleAddr.String(),
-		kibbleAddr.String(),
-	)
-}
+		assert.Equal(t, CadenceUFix64("60.0"), kibbleGetBalance(t, e, user))
+		assert.Equal(t, CadenceUFix64("60.0"), kibbleGetSupply(t, e))
+	})
+
+	t.Run("Shouldn't be able to burn more than the balance of the Vault", func(t *testing.T) {
+		burn("60.5", errorContaining("Amount withdrawn must be less than or equal than the balance of the Vault"))
 
-func kibbleGenerateMintKibbleTransaction(fungibleAddr, kibbleAddr flow.Address) []byte {
-	return kibbleReplaceAddressPlaceholders(
-		string(readFile(kibbleMintTokensPath)),
-		fungibleAddr.String(),
-		kibbleAddr.String(),
-	)
+		assert.Equal(t, CadenceUFix64("60.0"), kibbleGetBalance(t, e, user))
+		assert.Equal(t, CadenceUFix64("60.0"), kibbleGetSupply(t, e))
+	})
 }
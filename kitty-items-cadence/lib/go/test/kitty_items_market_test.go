@@ -1,404 +1,216 @@
 package test
 
 import (
-	"strings"
+	"context"
 	"testing"
 
 	"github.com/onflow/cadence"
-	emulator "github.com/onflow/flow-emulator"
-	"github.com/onflow/flow-go-sdk"
-	sdk "github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/crypto"
-	sdktemplates "github.com/onflow/flow-go-sdk/templates"
-	"github.com/onflow/flow-go-sdk/test"
 	"github.com/stretchr/testify/assert"
-)
+	"github.com/stretchr/testify/require"
 
-const (
-	kittyItemsMarketRootPath             = "../../../cadence/KittyItemsMarket"
-	kittyItemsMarketKittyItemsMarketPath = kittyItemsMarketRootPath + "/contracts/KittyItemsMarket.cdc"
-	kittyItemsMarketSetupAccountPath     = kittyItemsMarketRootPath + "/transactions/setup_account.cdc"
-	kittyItemsMarketSellItemPath         = kittyItemsMarketRootPath + "/transactions/sell_market_item.cdc"
-	kittyItemsMarketBuyItemPath          = kittyItemsMarketRootPath + "/transactions/buy_market_item.cdc"
-	kittyItemsMarketRemoveItemPath       = kittyItemsMarketRootPath + "/transactions/remove_market_item.cdc"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
 )
 
 const (
 	typeID1337 = 1337
 )
 
-type TestContractsInfo struct {
-	FTAddr                 flow.Address
-	KibbleAddr             flow.Address
-	KibbleSigner           crypto.Signer
-	NFTAddr                flow.Address
-	KittyItemsAddr         flow.Address
-	KittyItemsSigner       crypto.Signer
-	KittyItemsMarketAddr   flow.Address
-	KittyItemsMarketSigner crypto.Signer
-}
-
-func KittyItemsMarketDeployContracts(b *emulator.Blockchain, t *testing.T) TestContractsInfo {
-	accountKeys := test.AccountKeyGenerator()
-
-	ftAddr, kibbleAddr, kibbleSigner := KibbleDeployContracts(b, t)
-	nftAddr, kittyItemsAddr, kittyItemsSigner := KittyItemsDeployContracts(b, t)
-
-	// Should be able to deploy a contract as a new account with one key.
-	kittyItemsMarketAccountKey, kittyItemsMarketSigner := accountKeys.NewWithSigner()
-	kittyItemsMarketCode := loadKittyItemsMarket(
-		ftAddr.String(),
-		nftAddr.String(),
-		kibbleAddr.String(),
-		kittyItemsAddr.String(),
-	)
-	kittyItemsMarketAddr, err := b.CreateAccount(
-		[]*flow.AccountKey{kittyItemsMarketAccountKey},
-		[]sdktemplates.Contract{
-			{
-				Name:   "KittyItemsMarket",
-				Source: string(kittyItemsMarketCode),
-			},
-		})
-	if !assert.NoError(t, err) {
-		t.Log(err.Error())
-	}
-	_, err = b.CommitBlock()
-	assert.NoError(t, err)
-
-	// Simplify the workflow by having contract addresses also be our initial test collections.
-	KittyItemsSetupAccount(t, b, kittyItemsAddr, kittyItemsSigner, nftAddr, kittyItemsAddr)
-	KittyItemsMarketSetupAccount(b, t, kittyItemsMarketAddr, kittyItemsMarketSigner, kittyItemsMarketAddr)
-
-	return TestContractsInfo{
-		ftAddr,
-		kibbleAddr,
-		kibbleSigner,
-		nftAddr,
-		kittyItemsAddr,
-		kittyItemsSigner,
-		kittyItemsMarketAddr,
-		kittyItemsMarketSigner,
-	}
-}
-
-func KittyItemsMarketSetupAccount(b *emulator.Blockchain, t *testing.T, userAddress sdk.Address, userSigner crypto.Signer, kittyItemsMarketAddr sdk.Address) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsMarketGenerateSetupAccountScript(kittyItemsMarketAddr.String())).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(userAddress)
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, userAddress},
-		[]crypto.Signer{b.ServiceKey().Signer(), userSigner},
-		false,
-	)
+func KittyItemsMarketDeployContracts(t *testing.T, e *kittyitems.Emulator) kittyitems.Deployment {
+	deployment, err := e.DeployContracts()
+	require.NoError(t, err)
+	return deployment
 }
 
 // Create a new account with the Kibble and KittyItems resources set up BUT no KittyItemsMarket resource.
-func KittyItemsMarketCreatePurchaserAccount(b *emulator.Blockchain, t *testing.T, contracts TestContractsInfo) (sdk.Address, crypto.Signer) {
-	userAddress, userSigner, _ := createAccount(t, b)
-	KibbleSetupAccount(t, b, userAddress, userSigner, contracts.FTAddr, contracts.KibbleAddr)
-	KittyItemsSetupAccount(t, b, userAddress, userSigner, contracts.NFTAddr, contracts.KittyItemsAddr)
-	return userAddress, userSigner
+func KittyItemsMarketCreatePurchaserAccount(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	user := createAccount(t, e)
+	require.NoError(t, e.SetupKibbleAccount(user))
+	require.NoError(t, e.SetupKittyItemsAccount(user))
+	return user
 }
 
 // Create a new account with the Kibble, KittyItems, and KittyItemsMarket resources set up.
-func KittyItemsMarketCreateAccount(b *emulator.Blockchain, t *testing.T, contracts TestContractsInfo) (sdk.Address, crypto.Signer) {
-	userAddress, userSigner := KittyItemsMarketCreatePurchaserAccount(b, t, contracts)
-	KittyItemsMarketSetupAccount(b, t, userAddress, userSigner, contracts.KittyItemsMarketAddr)
-	return userAddress, userSigner
+func KittyItemsMarketCreateAccount(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	user, err := e.CreateUser()
+	require.NoError(t, err)
+	return user
 }
 
-func KittyItemsMarketListItem(b *emulator.Blockchain, t *testing.T, contracts TestContractsInfo, userAddress sdk.Address, userSigner crypto.Signer, tokenID uint64, price string, shouldFail bool) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsMarketGenerateSellItemScript(contracts)).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(userAddress)
-	tx.AddArgument(cadence.NewUInt64(tokenID))
-	tx.AddArgument(CadenceUFix64(price))
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, userAddress},
-		[]crypto.Signer{b.ServiceKey().Signer(), userSigner},
-		shouldFail,
-	)
-}
-
-func KittyItemsMarketPurchaseItem(
-	b *emulator.Blockchain,
-	t *testing.T,
-	contracts TestContractsInfo,
-	userAddress sdk.Address,
-	userSigner crypto.Signer,
-	marketCollectionAddress sdk.Address,
-	tokenID uint64,
-	shouldFail bool,
-) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsMarketGenerateBuyItemScript(contracts)).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(userAddress)
-	tx.AddArgument(cadence.NewUInt64(tokenID))
-	tx.AddArgument(cadence.NewAddress(marketCollectionAddress))
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, userAddress},
-		[]crypto.Signer{b.ServiceKey().Signer(), userSigner},
-		shouldFail,
-	)
-}
-
-func KittyItemsMarketRemoveItem(
-	b *emulator.Blockchain,
-	t *testing.T,
-	contracts TestContractsInfo,
-	userAddress sdk.Address,
-	userSigner crypto.Signer,
-	marketCollectionAddress sdk.Address,
-	tokenID uint64,
-	shouldFail bool,
-) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsMarketGenerateRemoveItemScript(contracts)).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(userAddress)
-	tx.AddArgument(cadence.NewUInt64(tokenID))
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, userAddress},
-		[]crypto.Signer{b.ServiceKey().Signer(), userSigner},
-		shouldFail,
-	)
+// KittyItemsMarketCreateSeller creates an account with the market set up and gives it a freshly minted item
+func KittyItemsMarketCreateSeller(t *testing.T, e *kittyitems.Emulator, contracts kittyitems.Deployment, tokenID uint64) kittyitems.Account {
+	user := KittyItemsMarketCreateAccount(t, e)
+	// Contract mints item
+	checkSubmitted(t, e.MintKittyItem(contracts.KittyItems, contracts.KittyItems.Address, typeID1337), nil)
+	// Contract transfers item to another seller account (we don't need to do this)
+	checkSubmitted(t, e.TransferKittyItem(contracts.KittyItems, user.Address, tokenID), nil)
+	return user
 }
 
 func TestKittyItemsMarketDeployContracts(t *testing.T) {
-	b := newEmulator()
-	KittyItemsMarketDeployContracts(b, t)
+	e := newEmulator(t)
+	KittyItemsMarketDeployContracts(t, e)
 }
 
 func TestKittyItemsMarketSetupAccount(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	contracts := KittyItemsMarketDeployContracts(b, t)
+	KittyItemsMarketDeployContracts(t, e)
 
 	t.Run("Should be able to create an empty Collection", func(t *testing.T) {
-		userAddress, userSigner, _ := createAccount(t, b)
-		KittyItemsMarketSetupAccount(b, t, userAddress, userSigner, contracts.KittyItemsMarketAddr)
+		user := createAccount(t, e)
+		checkSubmitted(t, e.SetupMarketAccount(user), nil)
 	})
 }
 
 func TestKittyItemsMarketCreateSaleOffer(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	contracts := KittyItemsMarketDeployContracts(b, t)
+	contracts := KittyItemsMarketDeployContracts(t, e)
 
 	t.Run("Should be able to create a sale offer and list it", func(t *testing.T) {
 		tokenToList := uint64(0)
-		tokenPrice := "1.11"
-		userAddress, userSigner := KittyItemsMarketCreateAccount(b, t, contracts)
-		// Contract mints item
-		KittyItemsMintItem(
-			b,
-			t,
-			contracts.NFTAddr,
-			contracts.KittyItemsAddr,
-			contracts.KittyItemsSigner,
-			typeID1337,
-		)
-		// Contract transfers item to another seller account (we don't need to do this)
-		KittyItemsTransferItem(
-			b,
-			t,
-			contracts.NFTAddr,
-			contracts.KittyItemsAddr,
-			contracts.KittyItemsSigner,
-			tokenToList,
-			userAddress,
-			false,
-		)
+		tokenPrice := CadenceUFix64("1.11")
+		user := KittyItemsMarketCreateSeller(t, e, contracts, tokenToList)
 		// Other seller account lists the item
-		KittyItemsMarketListItem(
-			b,
-			t,
-			contracts,
-			userAddress,
-			userSigner,
-			tokenToList,
-			tokenPrice,
-			false,
+		checkSubmitted(t, e.ListItem(user, tokenToList, tokenPrice), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"KittyItemsMarket.SaleOfferCreated", map[string]cadence.Value{"itemID": cadence.NewUInt64(tokenToList), "price": tokenPrice}},
+			event{"KittyItemsMarket.CollectionInsertedSaleOffer", map[string]cadence.Value{
+				"saleItemID":         cadence.NewUInt64(tokenToList),
+				"saleItemCollection": cadence.NewAddress(user.Address),
+			}},
 		)
+
+		ids, err := e.Contracts.MarketReadCollectionIDs(context.Background(), e, user.Address)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{tokenToList}, ids)
+
+		offer, err := e.Contracts.MarketReadSaleOfferDetails(context.Background(), e, user.Address, tokenToList)
+		require.NoError(t, err)
+		assert.Equal(t, &kittyitems.MarketSaleOfferDetails{SaleItemID: tokenToList, SalePrice: tokenPrice}, offer)
+
+		offer, err = e.Contracts.MarketReadSaleOfferDetails(context.Background(), e, user.Address, tokenToList+1)
+		require.NoError(t, err)
+		assert.Nil(t, offer)
 	})
 
 	t.Run("Should be able to accept a sale offer", func(t *testing.T) {
 		tokenToList := uint64(1)
-		tokenPrice := "1.11"
-		userAddress, userSigner := KittyItemsMarketCreateAccount(b, t, contracts)
-		// Contract mints item
-		KittyItemsMintItem(
-			b,
-			t,
-			contracts.NFTAddr,
-			contracts.KittyItemsAddr,
-			contracts.KittyItemsSigner,
-			typeID1337,
-		)
-		// Contract transfers item to another seller account (we don't need to do this)
-		KittyItemsTransferItem(
-			b,
-			t,
-			contracts.NFTAddr,
-			contracts.KittyItemsAddr,
-			contracts.KittyItemsSigner,
-			tokenToList,
-			userAddress,
-			false,
-		)
+		tokenPrice := CadenceUFix64("1.11")
+		user := KittyItemsMarketCreateSeller(t, e, contracts, tokenToList)
 		// Other seller account lists the item
-		KittyItemsMarketListItem(
-			b,
-			t,
-			contracts,
-			userAddress,
-			userSigner,
-			tokenToList,
-			tokenPrice,
-			false,
-		)
-		buyerAddress, buyerSigner := KittyItemsMarketCreatePurchaserAccount(b, t, contracts)
+		checkSubmitted(t, e.ListItem(user, tokenToList, tokenPrice), nil)
+		buyer := KittyItemsMarketCreatePurchaserAccount(t, e)
 		// Fund the purchase
-		KibbleMint(
-			t,
-			b,
-			contracts.FTAddr,
-			contracts.KibbleAddr,
-			contracts.KibbleSigner,
-			buyerAddress,
-			"100.0",
-			false,
-		)
+		checkSubmitted(t, e.MintKibble(contracts.Kibble, buyer.Address, CadenceUFix64("100.0")), nil)
 		// Make the purchase
-		KittyItemsMarketPurchaseItem(
-			b,
-			t,
-			contracts,
-			buyerAddress,
-			buyerSigner,
-			userAddress,
-			tokenToList,
-			false,
+		checkSubmitted(t, e.BuyItem(buyer, user.Address, tokenToList), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"Kibble.TokensWithdrawn", map[string]cadence.Value{"amount": tokenPrice, "from": cadence.NewAddress(buyer.Address)}},
+			event{"KittyItemsMarket.CollectionRemovedSaleOffer", map[string]cadence.Value{
+				"saleItemID":         cadence.NewUInt64(tokenToList),
+				"saleItemCollection": cadence.NewAddress(user.Address),
+			}},
+			event{"Kibble.TokensDeposited", map[string]cadence.Value{"amount": tokenPrice, "to": cadence.NewAddress(user.Address)}},
+			event{"KittyItems.Withdraw", map[string]cadence.Value{"id": cadence.NewUInt64(tokenToList), "from": cadence.NewAddress(user.Address)}},
+			event{"KittyItems.Deposit", map[string]cadence.Value{"id": cadence.NewUInt64(tokenToList), "to": cadence.NewAddress(buyer.Address)}},
+			event{"KittyItemsMarket.SaleOfferAccepted", map[string]cadence.Value{"itemID": cadence.NewUInt64(tokenToList)}},
+			event{"KittyItemsMarket.SaleOfferFinished", map[string]cadence.Value{"itemID": cadence.NewUInt64(tokenToList)}},
 		)
 	})
 
 	t.Run("Should be able to remove a sale offer", func(t *testing.T) {
 		tokenToList := uint64(2)
-		tokenPrice := "1.11"
-		userAddress, userSigner := KittyItemsMarketCreateAccount(b, t, contracts)
-		// Contract mints item
-		KittyItemsMintItem(
-			b,
-			t,
-			contracts.NFTAddr,
-			contracts.KittyItemsAddr,
-			contracts.KittyItemsSigner,
-			typeID1337,
-		)
-		// Contract transfers item to another seller account (we don't need to do this)
-		KittyItemsTransferItem(
-			b,
-			t,
-			contracts.NFTAddr,
-			contracts.KittyItemsAddr,
-			contracts.KittyItemsSigner,
-			tokenToList,
-			userAddress,
-			false,
-		)
+		tokenPrice := CadenceUFix64("1.11")
+		user := KittyItemsMarketCreateSeller(t, e, contracts, tokenToList)
 		// Other seller account lists the item
-		KittyItemsMarketListItem(
-			b,
-			t,
-			contracts,
-			userAddress,
-			userSigner,
-			tokenToList,
-			tokenPrice,
-			false,
-		)
-		// Make the purchase
-		KittyItemsMarketRemoveItem(
-			b,
-			t,
-			contracts,
-			userAddress,
-			userSigner,
-			userAddress,
-			tokenToList,
-			false,
+		checkSubmitted(t, e.ListItem(user, tokenToList, tokenPrice), nil)
+		// Remove the listing
+		checkSubmitted(t, e.RemoveItem(user, tokenToList), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"KittyItemsMarket.CollectionRemovedSaleOffer", map[string]cadence.Value{
+				"saleItemID":         cadence.NewUInt64(tokenToList),
+				"saleItemCollection": cadence.NewAddress(user.Address),
+			}},
+			event{"KittyItemsMarket.SaleOfferFinished", map[string]cadence.Value{"itemID": cadence.NewUInt64(tokenToList)}},
 		)
 	})
 }
 
-func replaceKittyItemsMarketAddressPlaceholders(codeBytes []byte, contracts TestContractsInfo) []byte {
-	code := string(codeBytes)
-
-	code = strings.ReplaceAll(code, ftAddressPlaceholder, "0x"+contracts.FTAddr.String())
-	code = strings.ReplaceAll(code, kibbleAddressPlaceHolder, "0x"+contracts.KibbleAddr.String())
-	code = strings.ReplaceAll(code, nftAddressPlaceholder, "0x"+contracts.NFTAddr.String())
-	code = strings.ReplaceAll(code, kittyItemsAddressPlaceHolder, "0x"+contracts.KittyItemsAddr.String())
-	code = strings.ReplaceAll(code, kittyItemsMarketPlaceholder, "0x"+contracts.KittyItemsMarketAddr.String())
-
-	return []byte(code)
-}
-
-func loadKittyItemsMarket(ftAddr, nftAddr, kibbleAddr, kittyItemsAddr string) []byte {
-	code := string(readFile(kittyItemsMarketKittyItemsMarketPath))
-
-	code = strings.ReplaceAll(code, ftAddressPlaceholder, "0x"+ftAddr)
-	code = strings.ReplaceAll(code, kibbleAddressPlaceHolder, "0x"+kibbleAddr)
-	code = strings.ReplaceAll(code, nftAddressPlaceholder, "0x"+nftAddr)
-	code = strings.ReplaceAll(code, kittyItemsAddressPlaceHolder, "0x"+kittyItemsAddr)
-
-	return []byte(code)
-}
-
-func kittyItemsMarketGenerateSetupAccountScript(kittyItemsMarketAddr string) []byte {
-	code := string(readFile(kittyItemsMarketSetupAccountPath))
-
-	code = strings.ReplaceAll(code, kittyItemsMarketPlaceholder, "0x"+kittyItemsMarketAddr)
-
-	return []byte(code)
-}
-
-func kittyItemsMarketGenerateSellItemScript(contracts TestContractsInfo) []byte {
-	return replaceKittyItemsMarketAddressPlaceholders(
-		readFile(kittyItemsMarketSellItemPath),
-		contracts,
-	)
-}
-
-func kittyItemsMarketGenerateBuyItemScript(contracts TestContractsInfo) []byte {
-	return replaceKittyItemsMarketAddressPlaceholders(
-		readFile(kittyItemsMarketBuyItemPath),
-		contracts,
-	)
-}
+func TestKittyItemsMarketScenarios(t *testing.T) {
+	scenarios := []scenario{
+		{
+			name:   "Bob buys the item Alice listed",
+			actors: []string{"alice", "bob"},
+			steps: []step{
+				{do: mintItem("alice", typeID1337), expect: []expectation{owns("alice", 0)}},
+				{do: mintKibble("bob", "100.0"), expect: []expectation{balance("bob", "100.0")}},
+				{do: list("alice", 0, "10.0"), expect: []expectation{listed("alice", 0, "10.0"), listing("alice", 0)}},
+				{do: buy("bob", "alice", 0), expect: []expectation{
+					balance("alice", "10.0"),
+					balance("bob", "90.0"),
+					owns("alice"),
+					owns("bob", 0),
+					listing("alice"),
+				}},
+			},
+		},
+		{
+			name:   "Bob can't buy an item without enough kibble",
+			actors: []string{"alice", "bob"},
+			steps: []step{
+				{do: mintItem("alice", typeID1337)},
+				{do: mintKibble("bob", "5.0")},
+				{do: list("alice", 0, "10.0")},
+				{
+					do:     buy("bob", "alice", 0),
+					revert: errorContaining("Amount withdrawn must be less than or equal than the balance of the Vault"),
+					expect: []expectation{balance("bob", "5.0"), owns("alice", 0), owns("bob"), listed("alice", 0, "10.0")},
+				},
+				{do: mintKibble("alice", "5.0")},
+				{do: sendKibble("alice", "bob", "5.0"), expect: []expectation{balance("alice", "0.0"), balance("bob", "10.0")}},
+				{do: buy("bob", "alice", 0), expect: []expectation{balance("alice", "10.0"), balance("bob", "0.0"), owns("bob", 0)}},
+			},
+		},
+		{
+			name:   "Nobody can buy an item once it is unlisted",
+			actors: []string{"alice", "bob"},
+			steps: []step{
+				{do: mintItem("alice", typeID1337)},
+				{do: mintKibble("bob", "100.0")},
+				{do: list("alice", 0, "10.0")},
+				{do: unlist("alice", 0), expect: []expectation{listing("alice"), owns("alice", 0)}},
+				{do: buy("bob", "alice", 0), revert: errorContaining("No item with that ID"), expect: []expectation{balance("bob", "100.0")}},
+			},
+		},
+		{
+			name:   "An item is sold once and resold by its new owner",
+			actors: []string{"alice", "bob", "carol"},
+			steps: []step{
+				{do: mintItem("alice", typeID1337)},
+				{do: mintItem("alice", typeID1337), expect: []expectation{owns("alice", 0, 1)}},
+				{do: mintKibble("bob", "20.0")},
+				{do: mintKibble("carol", "20.0")},
+				{do: list("alice", 0, "10.0")},
+				{do: list("alice", 1, "15.0"), expect: []expectation{listing("alice", 0, 1)}},
+				{do: buy("bob", "alice", 0), expect: []expectation{listing("alice", 1)}},
+				{do: buy("carol", "alice", 0), revert: errorContaining("No item with that ID"), expect: []expectation{balance("carol", "20.0")}},
+				{do: list("bob", 0, "12.5")},
+				{do: buy("carol", "bob", 0), expect: []expectation{
+					balance("alice", "10.0"),
+					balance("bob", "22.5"),
+					balance("carol", "7.5"),
+					owns("alice", 1),
+					owns("bob"),
+					owns("carol", 0),
+					listing("bob"),
+				}},
+				{do: sendItem("carol", "alice", 0), expect: []expectation{owns("alice", 0, 1), owns("carol")}},
+			},
+		},
+	}
 
-func kittyItemsMarketGenerateRemoveItemScript(contracts TestContractsInfo) []byte {
-	return replaceKittyItemsMarketAddressPlaceholders(
-		readFile(kittyItemsMarketRemoveItemPath),
-		contracts,
-	)
+	for _, sc := range scenarios {
+		sc.run(t)
+	}
 }
@@ -1,170 +1,66 @@
 package test
 
 import (
-	"strings"
+	"context"
 	"testing"
 
 	"github.com/onflow/cadence"
-	jsoncdc "github.com/onflow/cadence/encoding/json"
-	emulator "github.com/onflow/flow-emulator"
-	sdk "github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/crypto"
-	sdktemplates "github.com/onflow/flow-go-sdk/templates"
-	"github.com/onflow/flow-go-sdk/test"
-
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
-	"github.com/onflow/flow-go-sdk"
-
-	nft_contracts "github.com/onflow/flow-nft/lib/go/contracts"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
 )
 
 const (
-	kittyItemsRootPath                   = "../../../cadence/kittyItems"
-	kittyItemsKittyItemsPath             = kittyItemsRootPath + "/contracts/KittyItems.cdc"
-	kittyItemsSetupAccountPath           = kittyItemsRootPath + "/transactions/setup_account.cdc"
-	kittyItemsMintKittyItemPath          = kittyItemsRootPath + "/transactions/mint_kitty_item.cdc"
-	kittyItemsTransferKittyItemPath      = kittyItemsRootPath + "/transactions/transfer_kitty_item.cdc"
-	kittyItemsInspectKittyItemSupplyPath = kittyItemsRootPath + "/scripts/read_kitty_items_supply.cdc"
-	kittyItemsInspectCollectionLenPath   = kittyItemsRootPath + "/scripts/read_collection_length.cdc"
-	kittyItemsInspectCollectionIdsPath   = kittyItemsRootPath + "/scripts/read_collection_ids.cdc"
-
 	typeID1 = 1000
 	typeID2 = 2000
 )
 
-func KittyItemsDeployContracts(b *emulator.Blockchain, t *testing.T) (flow.Address, flow.Address, crypto.Signer) {
-	accountKeys := test.AccountKeyGenerator()
-
-	// Should be able to deploy a contract as a new account with no keys.
-	nftCode := loadNonFungibleToken()
-	nftAddr, err := b.CreateAccount(
-		nil,
-		[]sdktemplates.Contract{
-			{
-				Name:   "NonFungibleToken",
-				Source: string(nftCode),
-			},
-		})
-	if !assert.NoError(t, err) {
-		t.Log(err.Error())
-	}
-	_, err = b.CommitBlock()
-	assert.NoError(t, err)
-
-	// Should be able to deploy a contract as a new account with one key.
-	kittyItemsAccountKey, kittyItemsSigner := accountKeys.NewWithSigner()
-	kittyItemsCode := loadKittyItems(nftAddr.String())
-	kittyItemsAddr, err := b.CreateAccount(
-		[]*flow.AccountKey{kittyItemsAccountKey},
-		[]sdktemplates.Contract{
-			{
-				Name:   "KittyItems",
-				Source: string(kittyItemsCode),
-			},
-		})
-	if !assert.NoError(t, err) {
-		t.Log(err.Error())
-	}
-	_, err = b.CommitBlock()
-	assert.NoError(t, err)
-
-	// Simplify the workflow by having the contract address also be our initial test collection.
-	KittyItemsSetupAccount(t, b, kittyItemsAddr, kittyItemsSigner, nftAddr, kittyItemsAddr)
-
-	return nftAddr, kittyItemsAddr, kittyItemsSigner
-}
-
-func KittyItemsSetupAccount(t *testing.T, b *emulator.Blockchain, userAddress sdk.Address, userSigner crypto.Signer, nftAddr sdk.Address, kittyItemsAddr sdk.Address) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsGenerateSetupAccountScript(nftAddr.String(), kittyItemsAddr.String())).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(userAddress)
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, userAddress},
-		[]crypto.Signer{b.ServiceKey().Signer(), userSigner},
-		false,
-	)
-}
-
-func KittyItemsCreateAccount(t *testing.T, b *emulator.Blockchain, nftAddr sdk.Address, kittyItemsAddr sdk.Address) (sdk.Address, crypto.Signer) {
-	userAddress, userSigner, _ := createAccount(t, b)
-	KittyItemsSetupAccount(t, b, userAddress, userSigner, nftAddr, kittyItemsAddr)
-	return userAddress, userSigner
+func KittyItemsDeployContracts(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	kittyItems, err := e.DeployKittyItems()
+	require.NoError(t, err)
+	return kittyItems
 }
 
-func KittyItemsMintItem(b *emulator.Blockchain, t *testing.T, nftAddr, kittyItemsAddr flow.Address, kittyItemsSigner crypto.Signer, typeID uint64) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsGenerateMintKittyItemScript(nftAddr.String(), kittyItemsAddr.String())).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(kittyItemsAddr)
-	tx.AddArgument(cadence.NewAddress(kittyItemsAddr))
-	tx.AddArgument(cadence.NewUInt64(typeID))
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, kittyItemsAddr},
-		[]crypto.Signer{b.ServiceKey().Signer(), kittyItemsSigner},
-		false,
-	)
+func KittyItemsCreateAccount(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	user := createAccount(t, e)
+	require.NoError(t, e.SetupKittyItemsAccount(user))
+	return user
 }
 
-func KittyItemsTransferItem(b *emulator.Blockchain, t *testing.T, nftAddr, kittyItemsAddr flow.Address, kittyItemsSigner crypto.Signer, typeID uint64, recipientAddr flow.Address, shouldFail bool) {
-	tx := flow.NewTransaction().
-		SetScript(kittyItemsGenerateTransferKittyItemScript(nftAddr.String(), kittyItemsAddr.String())).
-		SetGasLimit(100).
-		SetProposalKey(b.ServiceKey().Address, b.ServiceKey().Index, b.ServiceKey().SequenceNumber).
-		SetPayer(b.ServiceKey().Address).
-		AddAuthorizer(kittyItemsAddr)
-	tx.AddArgument(cadence.NewAddress(recipientAddr))
-	tx.AddArgument(cadence.NewUInt64(typeID))
-
-	signAndSubmit(
-		t, b, tx,
-		[]flow.Address{b.ServiceKey().Address, kittyItemsAddr},
-		[]crypto.Signer{b.ServiceKey().Signer(), kittyItemsSigner},
-		shouldFail,
-	)
+func kittyItemsCollectionLen(t *testing.T, e *kittyitems.Emulator, account kittyitems.Account) int {
+	len, err := e.Contracts.KittyItemsReadCollectionLength(context.Background(), e, account.Address)
+	require.NoError(t, err)
+	return len
 }
 
 func TestKittyItemsDeployContracts(t *testing.T) {
-	b := newEmulator()
-	KittyItemsDeployContracts(b, t)
+	e := newEmulator(t)
+	KittyItemsDeployContracts(t, e)
 }
 
 func TestCreateKittyItem(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	nftAddr, kittyItemsAddr, kittyItemsSigner := KittyItemsDeployContracts(b, t)
+	kittyItems := KittyItemsDeployContracts(t, e)
 
-	supply := executeScriptAndCheck(t, b, kittyItemsGenerateInspectKittyItemSupplyScript(nftAddr.String(), kittyItemsAddr.String()), nil)
-	assert.Equal(t, cadence.NewUInt64(0), supply.(cadence.UInt64))
+	supply, err := e.Contracts.KittyItemsReadKittyItemsSupply(context.Background(), e)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), supply)
 
-	len := executeScriptAndCheck(
-		t,
-		b,
-		kittyItemsGenerateInspectCollectionLenScript(nftAddr.String(), kittyItemsAddr.String()),
-		[][]byte{jsoncdc.MustEncode(cadence.NewAddress(kittyItemsAddr))},
-	)
-	assert.Equal(t, cadence.NewInt(0), len.(cadence.Int))
+	len := kittyItemsCollectionLen(t, e, kittyItems)
+	assert.Equal(t, 0, len)
 
 	t.Run("Should be able to mint a kittyItems", func(t *testing.T) {
-		KittyItemsMintItem(b, t, nftAddr, kittyItemsAddr, kittyItemsSigner, typeID1)
+		checkSubmitted(t, e.MintKittyItem(kittyItems, kittyItems.Address, typeID1), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"KittyItems.Minted", map[string]cadence.Value{"id": cadence.NewUInt64(0), "typeID": cadence.NewUInt64(typeID1)}},
+			event{"KittyItems.Deposit", map[string]cadence.Value{"id": cadence.NewUInt64(0), "to": cadence.NewAddress(kittyItems.Address)}},
+		)
 
 		// Assert that the account's collection is correct
-		len := executeScriptAndCheck(
-			t,
-			b,
-			kittyItemsGenerateInspectCollectionLenScript(nftAddr.String(), kittyItemsAddr.String()),
-			[][]byte{jsoncdc.MustEncode(cadence.NewAddress(kittyItemsAddr))},
-		)
-		assert.Equal(t, cadence.NewInt(1), len.(cadence.Int))
+		len := kittyItemsCollectionLen(t, e, kittyItems)
+		assert.Equal(t, 1, len)
 
 		// Assert that the token type is correct
 		/*typeID := executeScriptAndCheck(
@@ -186,27 +82,23 @@ func TestCreateKittyItem(t *testing.T) {
 }
 
 func TestTransferNFT(t *testing.T) {
-	b := newEmulator()
+	e := newEmulator(t)
 
-	nftAddr, kittyItemsAddr, kittyItemsSigner := KittyItemsDeployContracts(b, t)
+	kittyItems := KittyItemsDeployContracts(t, e)
 
-	userAddress, userSigner, _ := createAccount(t, b)
+	user := createAccount(t, e)
 
 	// create a new Collection
 	t.Run("Should be able to create a new empty NFT Collection", func(t *testing.T) {
-		KittyItemsSetupAccount(t, b, userAddress, userSigner, nftAddr, kittyItemsAddr)
+		require.NoError(t, e.SetupKittyItemsAccount(user))
 
-		len := executeScriptAndCheck(
-			t,
-			b, kittyItemsGenerateInspectCollectionLenScript(nftAddr.String(), kittyItemsAddr.String()),
-			[][]byte{jsoncdc.MustEncode(cadence.NewAddress(userAddress))},
-		)
-		assert.Equal(t, cadence.NewInt(0), len.(cadence.Int))
+		len := kittyItemsCollectionLen(t, e, user)
+		assert.Equal(t, 0, len)
 
 	})
 
 	t.Run("Shouldn't be able to withdraw an NFT that doesn't exist in a collection", func(t *testing.T) {
-		KittyItemsTransferItem(b, t, nftAddr, kittyItemsAddr, kittyItemsSigner, 3333333, userAddress, true)
+		checkSubmitted(t, e.TransferKittyItem(kittyItems, user.Address, 3333333), errorMatching(`panic: missing NFT\b`))
 
 		//executeScriptAndCheck(t, b, kittyItemsGenerateInspectCollectionLenScript(nftAddr, kittyItemsAddr, userAddress, "KittyItems", "KittyItemsCollection", 0))
 
@@ -216,9 +108,13 @@ func TestTransferNFT(t *testing.T) {
 
 	// transfer an NFT
 	t.Run("Should be able to withdraw an NFT and deposit to another accounts collection", func(t *testing.T) {
-		KittyItemsMintItem(b, t, nftAddr, kittyItemsAddr, kittyItemsSigner, typeID1)
+		checkSubmitted(t, e.MintKittyItem(kittyItems, kittyItems.Address, typeID1), nil)
 		// Cheat: we have minted one item, its ID will be zero
-		KittyItemsTransferItem(b, t, nftAddr, kittyItemsAddr, kittyItemsSigner, 0, userAddress, false)
+		checkSubmitted(t, e.TransferKittyItem(kittyItems, user.Address, 0), nil)
+		assertEventSequence(t, lastEvents(t, e),
+			event{"KittyItems.Withdraw", map[string]cadence.Value{"id": cadence.NewUInt64(0), "from": cadence.NewAddress(kittyItems.Address)}},
+			event{"KittyItems.Deposit", map[string]cadence.Value{"id": cadence.NewUInt64(0), "to": cadence.NewAddress(user.Address)}},
+		)
 
 		// Assert that the account's collection is correct
 		//executeScriptAndCheck(t, b, kittyItemsGenerateInspectCollectionScript(nftAddr, kittyItemsAddr, userAddress, "KittyItems", "KittyItemsCollection", 0))
@@ -254,73 +150,3 @@ func TestTransferNFT(t *testing.T) {
 
 	})*/
 }
-
-func replaceKittyItemsAddressPlaceholders(code, nftAddress, kittyItemsAddress string) []byte {
-	return []byte(replaceStrings(
-		code,
-		map[string]string{
-			nftAddressPlaceholder:        "0x" + nftAddress,
-			kittyItemsAddressPlaceHolder: "0x" + kittyItemsAddress,
-		},
-	))
-}
-
-func loadNonFungibleToken() []byte {
-	return nft_contracts.NonFungibleToken()
-}
-
-func loadKittyItems(nftAddr string) []byte {
-	return []byte(strings.ReplaceAll(
-		string(readFile(kittyItemsKittyItemsPath)),
-		nftAddressPlaceholder,
-		"0x"+nftAddr,
-	))
-}
-
-func kittyItemsGenerateSetupAccountScript(nftAddr, kittyItemsAddr string) []byte {
-	return replaceKittyItemsAddressPlaceholders(
-		string(readFile(kittyItemsSetupAccountPath)),
-		nftAddr,
-		kittyItemsAddr,
-	)
-}
-
-func kittyItemsGenerateMintKittyItemScript(nftAddr, kittyItemsAddr string) []byte {
-	return replaceKittyItemsAddressPlaceholders(
-		string(readFile(kittyItemsMintKittyItemPath)),
-		nftAddr,
-		kittyItemsAddr,
-	)
-}
-
-func kittyItemsGenerateTransferKittyItemScript(nftAddr, kittyItemsAddr string) []byte {
-	return replaceKittyItemsAddressPlaceholders(
-		string(readFile(kittyItemsTransferKittyItemPath)),
-		nftAddr,
-		kittyItemsAddr,
-	)
-}
-
-func kittyItemsGenerateInspectKittyItemSupplyScript(nftAddr, kittyItemsAddr string) []byte {
-	return replaceKittyItemsAddressPlaceholders(
-		string(readFile(kittyItemsInspectKittyItemSupplyPath)),
-		nftAddr,
-		kittyItemsAddr,
-	)
-}
-
-func kittyItemsGenerateInspectCollectionLenScript(nftAddr, kittyItemsAddr string) []byte {
-	return replaceKittyItemsAddressPlaceholders(
-		string(readFile(kittyItemsInspectCollectionLenPath)),
-		nftAddr,
-		kittyItemsAddr,
-	)
-}
-
-func kittyItemsGenerateInspectCollectionIdsScript(nftAddr, kittyItemsAddr string) []byte {
-	return replaceKittyItemsAddressPlaceholders(
-		string(readFile(kittyItemsInspectCollectionIdsPath)),
-		nftAddr,
-		kittyItemsAddr,
-	)
-}
@@ -0,0 +1,242 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+// minter is the actor holding the contracts, it mints the kibble and the items of a scenario
+const minter = "minter"
+
+// scenario is a marketplace case played by actors declared by name, on contracts deployed for it alone.
+// Each actor gets an account with a Kibble vault, a KittyItems collection and a market collection, the
+// minter holds the contracts along with its own vault and collections. Items are numbered from 0 in the
+// order they are minted.
+//
+//	scenario{
+//		name:   "Bob buys the item Alice listed",
+//		actors: []string{"alice", "bob"},
+//		steps: []step{
+//			{do: mintItem("alice", typeID1337)},
+//			{do: mintKibble("bob", "100.0")},
+//			{do: list("alice", 0, "10.0"), expect: []expectation{listed("alice", 0, "10.0")}},
+//			{do: buy("bob", "alice", 0), expect: []expectation{owns("bob", 0), balance("alice", "10.0")}},
+//		},
+//	}
+type scenario struct {
+	name   string
+	actors []string
+	steps  []step
+}
+
+// step is an action, expected to succeed unless revert is set, followed by the state expected once it is done
+type step struct {
+	do     action
+	revert *expectedError
+	expect []expectation
+}
+
+// action is a transaction signed by the first of the actors it refers to
+type action struct {
+	description string
+	actors      []string
+	build       func(s *scenarioState) (*flow.Transaction, error)
+}
+
+// expectation checks the state of the account of an actor between steps
+type expectation struct {
+	actor string
+	check func(t *testing.T, s *scenarioState)
+}
+
+// scenarioState is the emulator a scenario is played on and the accounts of its actors
+type scenarioState struct {
+	e      *kittyitems.Emulator
+	actors map[string]kittyitems.Account
+}
+
+// address returns the address of actor
+func (s *scenarioState) address(actor string) flow.Address {
+	return s.actors[actor].Address
+}
+
+// run plays the scenario as a subtest of t, with a subtest per step. It stops at the first step that fails,
+// the state of the following ones would not be meaningful.
+func (sc scenario) run(t *testing.T) {
+	t.Run(sc.name, func(t *testing.T) {
+		e := newEmulator(t)
+		account, err := e.DeployContractsToAccount()
+		require.NoError(t, err)
+
+		s := &scenarioState{e: e, actors: map[string]kittyitems.Account{minter: account}}
+		for _, actor := range sc.actors {
+			require.NotContains(t, s.actors, actor, "actor %q is declared twice", actor)
+			s.actors[actor], err = e.CreateUser()
+			require.NoError(t, err)
+		}
+
+		for i, step := range sc.steps {
+			if !t.Run(fmt.Sprintf("%d %s", i+1, step.do.description), func(t *testing.T) { step.play(t, s) }) {
+				return
+			}
+		}
+	})
+}
+
+// play sends the transaction of step and checks the state expected after it
+func (step step) play(t *testing.T, s *scenarioState) {
+	// A typo in a name would otherwise send the transaction to the empty address
+	for _, actor := range step.actors() {
+		require.Contains(t, s.actors, actor, "actor %q is not declared in the scenario", actor)
+	}
+
+	tx, err := step.do.build(s)
+	require.NoError(t, err)
+	signAndSubmit(t, s.e, tx, s.actors[step.do.actors[0]], step.revert)
+
+	for _, expectation := range step.expect {
+		expectation.check(t, s)
+	}
+}
+
+// actors returns the actors a step refers to
+func (step step) actors() []string {
+	actors := append([]string(nil), step.do.actors...)
+	for _, expectation := range step.expect {
+		actors = append(actors, expectation.actor)
+	}
+	return actors
+}
+
+// mintKibble has the minter mint amount kibble to actor
+func mintKibble(actor string, amount string) action {
+	return action{
+		description: fmt.Sprintf("%s mints %s kibble to %s", minter, amount, actor),
+		actors:      []string{minter, actor},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.KibbleMintTokens(s.address(minter), s.address(actor), CadenceUFix64(amount))
+		},
+	}
+}
+
+// mintItem has the minter mint an item of typeID to actor
+func mintItem(actor string, typeID uint64) action {
+	return action{
+		description: fmt.Sprintf("%s mints an item of type %d to %s", minter, typeID, actor),
+		actors:      []string{minter, actor},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.KittyItemsMintKittyItem(s.address(minter), s.address(actor), typeID)
+		},
+	}
+}
+
+// sendKibble has from transfer amount kibble to to
+func sendKibble(from string, to string, amount string) action {
+	return action{
+		description: fmt.Sprintf("%s sends %s kibble to %s", from, amount, to),
+		actors:      []string{from, to},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.KibbleTransferTokens(s.address(from), CadenceUFix64(amount), s.address(to))
+		},
+	}
+}
+
+// sendItem has from transfer item itemID to to
+func sendItem(from string, to string, itemID uint64) action {
+	return action{
+		description: fmt.Sprintf("%s sends item %d to %s", from, itemID, to),
+		actors:      []string{from, to},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.KittyItemsTransferKittyItem(s.address(from), s.address(to), itemID)
+		},
+	}
+}
+
+// list has seller put item itemID up for sale at price
+func list(seller string, itemID uint64, price string) action {
+	return action{
+		description: fmt.Sprintf("%s lists item %d for %s", seller, itemID, price),
+		actors:      []string{seller},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.MarketSellMarketItem(s.address(seller), itemID, CadenceUFix64(price))
+		},
+	}
+}
+
+// unlist has seller withdraw the sale offer for item itemID
+func unlist(seller string, itemID uint64) action {
+	return action{
+		description: fmt.Sprintf("%s unlists item %d", seller, itemID),
+		actors:      []string{seller},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.MarketRemoveMarketItem(s.address(seller), itemID)
+		},
+	}
+}
+
+// buy has buyer buy item itemID listed by seller, at the price of the sale offer
+func buy(buyer string, seller string, itemID uint64) action {
+	return action{
+		description: fmt.Sprintf("%s buys item %d from %s", buyer, itemID, seller),
+		actors:      []string{buyer, seller},
+		build: func(s *scenarioState) (*flow.Transaction, error) {
+			return s.e.Contracts.MarketBuyMarketItem(s.address(buyer), itemID, s.address(seller))
+		},
+	}
+}
+
+// balance expects the Kibble vault of actor to hold amount
+func balance(actor string, amount string) expectation {
+	return expectation{
+		actor: actor,
+		check: func(t *testing.T, s *scenarioState) {
+			actual, err := s.e.Contracts.KibbleGetBalance(context.Background(), s.e, s.address(actor))
+			require.NoError(t, err)
+			assert.Equal(t, CadenceUFix64(amount), actual, "kibble balance of %s", actor)
+		},
+	}
+}
+
+// owns expects the KittyItems collection of actor to hold exactly the items itemIDs
+func owns(actor string, itemIDs ...uint64) expectation {
+	return expectation{
+		actor: actor,
+		check: func(t *testing.T, s *scenarioState) {
+			actual, err := s.e.Contracts.KittyItemsReadCollectionIDs(context.Background(), s.e, s.address(actor))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, itemIDs, actual, "items owned by %s", actor)
+		},
+	}
+}
+
+// listed expects actor to have item itemID up for sale at price
+func listed(actor string, itemID uint64, price string) expectation {
+	return expectation{
+		actor: actor,
+		check: func(t *testing.T, s *scenarioState) {
+			offer, err := s.e.Contracts.MarketReadSaleOfferDetails(context.Background(), s.e, s.address(actor), itemID)
+			require.NoError(t, err)
+			expected := &kittyitems.MarketSaleOfferDetails{SaleItemID: itemID, SalePrice: CadenceUFix64(price)}
+			assert.Equal(t, expected, offer, "sale offer of %s for item %d", actor, itemID)
+		},
+	}
+}
+
+// listing expects the market collection of actor to hold sale offers for exactly the items itemIDs
+func listing(actor string, itemIDs ...uint64) expectation {
+	return expectation{
+		actor: actor,
+		check: func(t *testing.T, s *scenarioState) {
+			actual, err := s.e.Contracts.MarketReadCollectionIDs(context.Background(), s.e, s.address(actor))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, itemIDs, actual, "items listed by %s", actor)
+		},
+	}
+}
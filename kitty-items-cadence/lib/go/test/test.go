@@ -1,113 +1,137 @@
 package test
 
 import (
-	"io/ioutil"
-	"os"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/onflow/cadence"
-	emulator "github.com/onflow/flow-emulator"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/flow-go-sdk"
-	"github.com/onflow/flow-go-sdk/crypto"
-	"github.com/onflow/flow-go-sdk/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/onflow/cadence/runtime/cmd"
-	sdk "github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
 )
 
 // newEmulator returns a emulator object for testing
-func newEmulator() *emulator.Blockchain {
-	b, err := emulator.NewBlockchain()
-	if err != nil {
-		panic(err)
-	}
-	return b
+func newEmulator(t *testing.T) *kittyitems.Emulator {
+	e, err := kittyitems.NewEmulator()
+	require.NoError(t, err)
+	return e
 }
 
-// signAndSubmit signs a transaction with an array of signers and adds their signatures to the transaction
-// Then submits the transaction to the emulator. If the private keys don't match up with the addresses,
-// the transaction will not succeed.
-// shouldRevert parameter indicates whether the transaction should fail or not
-// This function asserts the correct result and commits the block if it passed
-func signAndSubmit(
-	t *testing.T,
-	b *emulator.Blockchain,
-	tx *flow.Transaction,
-	signerAddresses []flow.Address,
-	signers []crypto.Signer,
-	shouldRevert bool,
-) {
-	// sign transaction with each signer
-	for i := len(signerAddresses) - 1; i >= 0; i-- {
-		signerAddress := signerAddresses[i]
-		signer := signers[i]
-
-		if i == 0 {
-			err := tx.SignEnvelope(signerAddress, 0, signer)
-			assert.NoError(t, err)
-		} else {
-			err := tx.SignPayload(signerAddress, 0, signer)
-			assert.NoError(t, err)
-		}
+// expectedError matches the error of a transaction expected to revert
+type expectedError struct {
+	description string
+	match       func(err error) bool
+}
+
+// errorContaining expects the transaction to revert with an error whose message contains substring
+func errorContaining(substring string) *expectedError {
+	return &expectedError{
+		description: fmt.Sprintf("an error containing %q", substring),
+		match: func(err error) bool {
+			return strings.Contains(err.Error(), substring)
+		},
 	}
+}
 
-	submit(t, b, tx, shouldRevert)
+// errorMatching expects the transaction to revert with an error whose message matches the regular expression pattern
+func errorMatching(pattern string) *expectedError {
+	re := regexp.MustCompile(pattern)
+	return &expectedError{
+		description: fmt.Sprintf("an error matching %q", pattern),
+		match: func(err error) bool {
+			return re.MatchString(err.Error())
+		},
+	}
 }
 
-// submit submits a transaction and checks
-// if it fails or not
-func submit(
-	t *testing.T,
-	b *emulator.Blockchain,
-	tx *flow.Transaction,
-	shouldRevert bool,
-) {
-	// submit the signed transaction
-	err := b.AddTransaction(*tx)
-	require.NoError(t, err)
+// errorOfKind expects the transaction to revert with a Cadence error of the type target points to,
+// e.g. errorOfKind(new(*interpreter.ConditionError))
+func errorOfKind(target interface{}) *expectedError {
+	return &expectedError{
+		description: fmt.Sprintf("a %s", reflect.TypeOf(target).Elem()),
+		match: func(err error) bool {
+			return errors.As(err, target)
+		},
+	}
+}
 
-	result, err := b.ExecuteNextTransaction()
-	require.NoError(t, err)
+// checkSubmitted asserts that a transaction sent with the kittyitems package succeeded when expected is nil,
+// or reverted with the expected error
+func checkSubmitted(t *testing.T, err error, expected *expectedError) {
+	checkReverted(t, nil, err, expected, nil)
+}
 
-	if shouldRevert {
-		assert.True(t, result.Reverted())
-	} else {
-		if !assert.True(t, result.Succeeded()) {
-			t.Log(result.Error.Error())
-			cmd.PrettyPrintError(os.Stdout, result.Error, "", map[string]string{"": ""})
+// checkReverted is checkSubmitted for a transaction whose script is known, failures then show the code where
+// the transaction reverted
+func checkReverted(t *testing.T, e *kittyitems.Emulator, err error, expected *expectedError, script []byte) {
+	if expected == nil {
+		if err != nil {
+			assert.Fail(t, "transaction failed", prettyError(e, err, script))
 		}
+		return
 	}
 
-	_, err = b.CommitBlock()
-	assert.NoError(t, err)
+	if !errors.Is(err, kittyitems.ErrTransactionReverted) {
+		assert.Fail(t, "expected transaction to revert", "expected %s, got %v", expected.description, err)
+		return
+	}
+	if !expected.match(err) {
+		assert.Fail(t, "transaction reverted with another error", "expected %s, got\n%s", expected.description, prettyError(e, err, script))
+	}
 }
 
-// executeScriptAndCheck executes a script and checks to make sure that it succeeded.
-func executeScriptAndCheck(t *testing.T, b *emulator.Blockchain, script []byte, arguments [][]byte) cadence.Value {
-	result, err := b.ExecuteScript(script, arguments)
-	require.NoError(t, err)
-	if !assert.True(t, result.Succeeded()) {
-		t.Log(result.Error.Error())
+// prettyError renders the Cadence error of a reverted transaction with an excerpt of the code it was raised in,
+// the script of the transaction or a contract deployed to the emulator
+func prettyError(e *kittyitems.Emulator, err error, script []byte) string {
+	var runtimeErr runtime.Error
+	if !errors.As(err, &runtimeErr) {
+		return err.Error()
 	}
 
-	return result.Value
+	cadenceErr := runtimeErr.Err
+	if _, ok := cadenceErr.(ast.HasPosition); !ok && reflect.TypeOf(cadenceErr).Kind() == reflect.Struct {
+		// The position of errors like stdlib.PanicError is only available through a pointer
+		pointer := reflect.New(reflect.TypeOf(cadenceErr))
+		pointer.Elem().Set(reflect.ValueOf(cadenceErr))
+		cadenceErr = pointer.Interface().(error)
+	}
+
+	filename, code := "transaction", string(script)
+	if located, ok := cadenceErr.(ast.HasImportLocation); ok {
+		if location, ok := located.ImportLocation().(ast.AddressLocation); ok {
+			filename, code = location.Name, ""
+			if e != nil {
+				if account, err := e.Blockchain.GetAccount(flow.Address(location.Address)); err == nil {
+					code = string(account.Contracts[location.Name])
+				}
+			}
+		}
+	}
+
+	return runtime.PrettyPrintError(cadenceErr, filename, code, false)
 }
 
-// readFile reads a file from the file system
-// and returns its contents
-func readFile(path string) []byte {
-	contents, err := ioutil.ReadFile(path)
-	if err != nil {
-		panic(err)
+// signAndSubmit submits a transaction built with the kittyitems bindings, authorized by signer,
+// checks it succeeded or reverted with the expected error and returns the events it emitted
+func signAndSubmit(t *testing.T, e *kittyitems.Emulator, tx *flow.Transaction, signer kittyitems.Account, expected *expectedError) []flow.Event {
+	result, err := e.Submit(tx, signer)
+	checkReverted(t, e, err, expected, tx.Script)
+	if result == nil {
+		return nil
 	}
-	return contents
+	return result.Events
 }
 
 // CadenceUFix64 returns a UFix64 value
-func CadenceUFix64(value string) cadence.Value {
+func CadenceUFix64(value string) cadence.UFix64 {
 	newValue, err := cadence.NewUFix64(value)
 
 	if err != nil {
@@ -117,25 +141,9 @@ func CadenceUFix64(value string) cadence.Value {
 	return newValue
 }
 
-func replaceStrings(
-	source string,
-	substitutions map[string]string,
-) string {
-	for find, replace := range substitutions {
-		source = strings.ReplaceAll(
-			source,
-			find,
-			replace,
-		)
-	}
-	return source
-}
-
-// Simple error-handling wrapper for Flow account creation.
-func createAccount(t *testing.T, b *emulator.Blockchain) (sdk.Address, crypto.Signer, *sdk.AccountKey) {
-	accountKeys := test.AccountKeyGenerator()
-	accountKey, signer := accountKeys.NewWithSigner()
-	address, err := b.CreateAccount([]*sdk.AccountKey{accountKey}, nil)
+// createAccount creates an account with no resources set up
+func createAccount(t *testing.T, e *kittyitems.Emulator) kittyitems.Account {
+	account, err := e.CreateAccount()
 	require.NoError(t, err)
-	return address, signer, accountKey
+	return account
 }
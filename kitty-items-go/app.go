@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/controllers"
+	"github.com/dapperlabs/kitty-items-go/middlewares"
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/client"
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// minterBalanceInterval is how often the minter balance metric is refreshed
+const minterBalanceInterval = 30 * time.Second
+
+// app holds the services of the server and the router serving them
+type app struct {
+	conf        Config
+	accessNodes *services.AccessNodePool
+	flowService *services.FlowService
+	indexer     *services.IndexerService
+	stream      *services.StreamService
+	webhooks    *services.WebhookService
+	apiKeys     *services.APIKeysService
+	router      *mux.Router
+
+	// background counts the goroutines of start, wait waits for them
+	background sync.WaitGroup
+}
+
+// newApp connects to the access nodes, checks the minter account key and builds the services and the router.
+// The background work of the services only begins with start.
+func newApp(ctx context.Context, conf Config) (a *app, err error) {
+	// Every RPC to the access node gets a client span, child of the span of the request that caused it
+	accessNodes, err := dialAccessNodes(conf, grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to access nodes = %w", err)
+	}
+	defer func() {
+		if err != nil {
+			accessNodes.Close()
+		}
+	}()
+
+	accessNodes.Check(ctx)
+	for _, status := range accessNodes.Status() {
+		log.Info().Str("access_node", status.Address).Bool("healthy", status.Healthy).Uint64("sealed_height", status.Height).Msg("checked access node")
+	}
+
+	// Retrieve the Flow Account with our configured minter address so we can create a transaction signer for it
+	var minterAccount *flow.Account
+	err = accessNodes.Read(ctx, func(c *client.Client) (err error) {
+		minterAccount, err = c.GetAccount(ctx, conf.MinterFlowAddress)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving minter account = %w", err)
+	}
+
+	log.Info().Str("minter_address", minterAccount.Address.Hex()).Msg("retrieved minter account")
+
+	proposalKeys, err := conf.MinterProposalKeys(minterAccount)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting minter account keys = %w", err)
+	}
+	signer := crypto.NewInMemorySigner(conf.MinterPrivateKey, conf.MinterHashAlgo)
+
+	// Instantiate our internal services
+	flowService := services.NewFlow(accessNodes, signer, conf.MinterFlowAddress, proposalKeys)
+	limitsService := services.NewLimits(conf.Limits())
+	scriptCache := services.NewScriptCache(flowService, conf.ScriptCache())
+	kibblesService := services.NewKibbles(flowService, limitsService, scriptCache, conf.FungibleTokenFlowAddress, conf.KibbleFlowAddress)
+	kittyItemsService := services.NewKittyItems(flowService, limitsService, scriptCache, conf.NonFungibleTokenFlowAddress, conf.KittyItemsFlowAddress)
+	marketService := services.NewMarket(scriptCache, conf.KittyItemsMarketFlowAddress)
+
+	// Cached reads of an account are dropped as soon as an indexed event names it
+	indexer, err := services.NewIndexer(flowService, conf.Indexer())
+	if err != nil {
+		return nil, fmt.Errorf("error loading indexer cursor = %w", err)
+	}
+	indexer.Subscribe(scriptCache.HandleEvent)
+
+	streamService := services.NewStream(conf.StreamHistorySize)
+	indexer.Subscribe(streamService.PublishEvent)
+	flowService.SubscribeTransactions(streamService.PublishTransaction)
+
+	webhooksService, err := services.NewWebhooks(conf.WebhooksFile, conf.Webhooks())
+	if err != nil {
+		return nil, fmt.Errorf("error loading webhooks = %w", err)
+	}
+	indexer.Subscribe(webhooksService.HandleEvent)
+
+	apiKeys, err := services.NewAPIKeys(conf.APIKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading api keys = %w", err)
+	}
+
+	r := mux.NewRouter()
+	r.Use(middlewares.RequestID, middlewares.Tracing, middlewares.Metrics)
+
+	r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+
+	// Health endpoints are unauthenticated so the orchestrator can probe them
+	checks := services.FlowHealthChecks(flowService, conf.Health())
+	if conf.IndexerEnabled {
+		checks = append(checks, services.IndexerHealthCheck(indexer, conf.IndexerMaxLag, 10*conf.IndexerPollInterval))
+	}
+	healthC := controllers.NewHealth(services.NewHealth(conf.HealthCheckTimeout, checks...))
+	r.HandleFunc("/healthz", healthC.HandleHealthz).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", healthC.HandleReadyz).Methods(http.MethodGet)
+
+	kibblesC := controllers.NewKibbles(kibblesService)
+	r.Handle("/admin/minter", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(kibblesC.HandleGetMinterAllowance))).Methods(http.MethodGet)
+	r.Handle("/admin/minter", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(kibblesC.HandleProvisionMinter))).Methods(http.MethodPost)
+	r.Handle("/kibbles/balance/{address}", middlewares.RequireScope(apiKeys, services.ScopeRead)(http.HandlerFunc(kibblesC.HandleGetBalance))).Methods(http.MethodGet)
+	r.Handle("/admin/minter/top-up", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(kibblesC.HandleTopUpMinter))).Methods(http.MethodPost)
+
+	if conf.FaucetMode {
+		log.Info().Uint("difficulty", conf.FaucetDifficulty).Dur("cooldown", conf.FaucetCooldown).Msg("faucet mode enabled")
+		faucetC := controllers.NewFaucet(services.NewFaucet(conf.Faucet()), kibblesService, conf.FaucetAmount)
+		r.HandleFunc("/kibbles/challenge", faucetC.HandleChallenge).Methods(http.MethodPost)
+		r.HandleFunc("/kibbles/new", faucetC.HandleMintKibbles).Methods(http.MethodPost)
+	} else {
+		r.Handle("/kibbles/new", middlewares.RequireScope(apiKeys, services.ScopeMintKibble)(http.HandlerFunc(kibblesC.HandleMintKibbles))).Methods(http.MethodPost)
+	}
+
+	kittyItemsC := controllers.NewKittyItems(kittyItemsService)
+	r.Handle("/kitty-items/mint", middlewares.RequireScope(apiKeys, services.ScopeMintItem)(http.HandlerFunc(kittyItemsC.HandleMintKittyItem))).Methods(http.MethodPost)
+	r.Handle("/kitty-items/collection/{address}", middlewares.RequireScope(apiKeys, services.ScopeRead)(http.HandlerFunc(kittyItemsC.HandleGetCollectionIDs))).Methods(http.MethodGet)
+
+	marketC := controllers.NewMarket(marketService)
+	r.Handle("/market/collection/{address}", middlewares.RequireScope(apiKeys, services.ScopeRead)(http.HandlerFunc(marketC.HandleGetSaleOfferIDs))).Methods(http.MethodGet)
+	r.Handle("/market/collection/{address}/offers/{itemID}", middlewares.RequireScope(apiKeys, services.ScopeRead)(http.HandlerFunc(marketC.HandleGetSaleOffer))).Methods(http.MethodGet)
+
+	webhooksC := controllers.NewWebhooks(webhooksService, indexer)
+	r.Handle("/admin/webhooks", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(webhooksC.HandleListWebhooks))).Methods(http.MethodGet)
+	r.Handle("/admin/webhooks", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(webhooksC.HandleRegisterWebhook))).Methods(http.MethodPost)
+	r.Handle("/admin/webhooks/{id}", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(webhooksC.HandleDeleteWebhook))).Methods(http.MethodDelete)
+	r.Handle("/admin/webhooks/{id}/dead-letters", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(webhooksC.HandleListDeadLetters))).Methods(http.MethodGet)
+	r.Handle("/admin/webhooks/{id}/dead-letters/{deliveryID}/replay", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(webhooksC.HandleReplayDeadLetter))).Methods(http.MethodPost)
+	r.Handle("/admin/webhooks/{id}/replay", middlewares.RequireScope(apiKeys, services.ScopeAdmin)(http.HandlerFunc(webhooksC.HandleReplayWebhook))).Methods(http.MethodPost)
+
+	streamC := controllers.NewStream(streamService)
+	r.Handle("/events/stream", middlewares.RequireScope(apiKeys, services.ScopeRead)(http.HandlerFunc(streamC.HandleStream))).Methods(http.MethodGet)
+
+	return &app{
+		conf:        conf,
+		accessNodes: accessNodes,
+		flowService: flowService,
+		indexer:     indexer,
+		stream:      streamService,
+		webhooks:    webhooksService,
+		apiKeys:     apiKeys,
+		router:      r,
+	}, nil
+}
+
+// start runs the background work of the services until ctx is done: the minter balance metric, the access node
+// checks, the indexer and the webhook deliveries
+func (a *app) start(ctx context.Context) {
+	// Keep the minter balance metric current even when nothing is minted
+	a.run(func() { a.flowService.MonitorMinterBalance(ctx, minterBalanceInterval) })
+	a.run(func() { a.accessNodes.Monitor(ctx, a.conf.AccessNodeCheckInterval) })
+	if a.conf.IndexerEnabled {
+		a.run(func() { a.indexer.Run(ctx) })
+	}
+	a.run(func() { a.webhooks.Run(ctx) })
+}
+
+func (a *app) run(fn func()) {
+	a.background.Add(1)
+	go func() {
+		defer a.background.Done()
+		fn()
+	}()
+}
+
+// wait waits until the background work returned, once the context given to start is done.
+// The indexer saves its cursor as it returns.
+func (a *app) wait() {
+	a.background.Wait()
+}
+
+// close disconnects from the access nodes
+func (a *app) close() {
+	a.accessNodes.Close()
+}
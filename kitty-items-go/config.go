@@ -1,31 +1,702 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/onflow/cadence"
 	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/client"
 	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
 )
 
+const (
+	defaultWebhooksFile      = "webhooks.json"
+	defaultIndexerCursorFile = "indexer_cursor.json"
+)
+
+// Config is loaded from defaults, then an optional YAML file, then `KITTY_ITEMS_*` environment variables.
+// Sections are embedded so environment variable names stay flat, e.g. `KITTY_ITEMS_FLOWNODE`.
 type Config struct {
-	FlowNode              string `default:"localhost:3569"`
-	MinterFlowAddressHex  string `required:"true"`
-	MinterPrivateKeyHex   string `required:"true"`
-	MinterSigAlgoName     string `default:"ECDSA_P256"`
-	MinterHashAlgoName    string `default:"SHA3_256"`
-	MinterAccountKeyIndex int    `default:"0"`
+	NetworkConfig  `yaml:"network"`
+	AccountsConfig `yaml:"accounts"`
+	KeysConfig     `yaml:"keys"`
+	HTTPConfig     `yaml:"http"`
+	LimitsConfig   `yaml:"limits"`
+	FaucetConfig   `yaml:"faucet"`
+	HealthConfig   `yaml:"health"`
+	LoggingConfig  `yaml:"logging"`
+	TracingConfig  `yaml:"tracing"`
+	IndexerConfig  `yaml:"indexer"`
+	CacheConfig    `yaml:"cache"`
+	StreamConfig   `yaml:"stream"`
+	WebhooksConfig `yaml:"webhooks"`
+
+	// These are computed variables based on the configuration above
+	MinterFlowAddress           flow.Address              `ignored:"true" yaml:"-"`
+	MinterPrivateKey            crypto.PrivateKey         `ignored:"true" yaml:"-"`
+	MinterSigAlgo               crypto.SignatureAlgorithm `ignored:"true" yaml:"-"`
+	MinterHashAlgo              crypto.HashAlgorithm      `ignored:"true" yaml:"-"`
+	FungibleTokenFlowAddress    flow.Address              `ignored:"true" yaml:"-"`
+	KibbleFlowAddress           flow.Address              `ignored:"true" yaml:"-"`
+	NonFungibleTokenFlowAddress flow.Address              `ignored:"true" yaml:"-"`
+	KittyItemsFlowAddress       flow.Address              `ignored:"true" yaml:"-"`
+	KittyItemsMarketFlowAddress flow.Address              `ignored:"true" yaml:"-"`
+}
+
+type NetworkConfig struct {
+	// FlowNode is the access node used when AccessNodes is empty
+	FlowNode string `yaml:"flow_node"`
+	// AccessNodes lists several access nodes to balance reads over and fail over between
+	AccessNodes []AccessNodeConfig `yaml:"access_nodes" ignored:"true"`
+	// AccessNodeMaxLag is how many blocks a node may trail the most advanced one and still serve reads
+	AccessNodeMaxLag           uint64        `yaml:"access_node_max_lag"`
+	AccessNodeCheckInterval    time.Duration `yaml:"access_node_check_interval"`
+	FungibleTokenAddressHex    string        `yaml:"fungible_token_address"`
+	NonFungibleTokenAddressHex string        `yaml:"non_fungible_token_address"`
+	// KibbleAddressHex defaults to the minter address, which must hold the Kibble Administrator
+	KibbleAddressHex string `yaml:"kibble_address"`
+	// KittyItemsAddressHex defaults to the minter address, which is where the contract is deployed
+	KittyItemsAddressHex string `yaml:"kitty_items_address"`
+	// KittyItemsMarketAddressHex defaults to the minter address, which is where the contract is deployed
+	KittyItemsMarketAddressHex string `yaml:"kitty_items_market_address"`
+}
+
+type AccessNodeConfig struct {
+	Address string `yaml:"address"`
+	TLS     bool   `yaml:"tls"`
+	// TLSServerName overrides the name the certificate is checked against, defaults to the address host
+	TLSServerName string `yaml:"tls_server_name"`
+	// TLSCAFile is a PEM bundle to verify the node certificate with instead of the system roots
+	TLSCAFile string `yaml:"tls_ca_file"`
+}
+
+type AccountsConfig struct {
+	MinterFlowAddressHex string `yaml:"minter_address"`
+}
+
+type KeysConfig struct {
+	MinterPrivateKeyHex   string `yaml:"minter_private_key"`
+	MinterSigAlgoName     string `yaml:"minter_sig_algo"`
+	MinterHashAlgoName    string `yaml:"minter_hash_algo"`
+	MinterAccountKeyIndex int    `yaml:"minter_key_index"`
+	// MinterProposalKeyIndexes are other keys of the minter account, holding the same public key, that
+	// propose transactions next to the minter key so that several can be sent at once
+	MinterProposalKeyIndexes []int `yaml:"minter_proposal_key_indexes"`
+}
+
+type HTTPConfig struct {
+	ListenAddress string `yaml:"listen_address"`
+	APIKeysFile   string `yaml:"api_keys_file"`
+	// ShutdownTimeout bounds how long in-flight requests and submitted transactions are waited for on SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// LimitsConfig holds the mint limits, a value of 0 disables the limit. They are counted in memory by each
+// instance: a restart resets them and replicas don't share them. MaxKittyItemsPerRecipient has no window, it
+// counts the items minted since the instance started.
+type LimitsConfig struct {
+	RateLimitRequestsPerMinute  uint64 `yaml:"requests_per_minute"`
+	MaxKibblePerRecipientPerDay uint64 `yaml:"max_kibble_per_recipient_per_day"`
+	MaxKittyItemsPerRecipient   uint64 `yaml:"max_kitty_items_per_recipient"`
+	MaxKibbleMintedPerDay       uint64 `yaml:"max_kibble_minted_per_day"`
+}
+
+// FaucetConfig enables faucet mode, which makes /kibbles/new public,
+// protected by a proof-of-work challenge and a cooldown per address
+type FaucetConfig struct {
+	FaucetMode         bool          `yaml:"enabled"`
+	FaucetAmount       uint          `yaml:"amount"`
+	FaucetDifficulty   uint          `yaml:"difficulty"`
+	FaucetChallengeTTL time.Duration `yaml:"challenge_ttl"`
+	FaucetCooldown     time.Duration `yaml:"cooldown"`
+}
+
+// HealthConfig sets the thresholds of the readiness checks served on /readyz
+type HealthConfig struct {
+	HealthMaxSealedLag     uint64        `yaml:"max_sealed_lag"`
+	HealthMinMinterBalance string        `yaml:"min_minter_balance"`
+	HealthCheckTimeout     time.Duration `yaml:"check_timeout"`
+}
+
+// LoggingConfig sets the minimum level and the format of the logs, `json` or `console` for development
+type LoggingConfig struct {
+	LogLevel  string `yaml:"level"`
+	LogFormat string `yaml:"format"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported: `none`, `stdout` for local use,
+// or `otlp` to send them over gRPC to TracingEndpoint
+type TracingConfig struct {
+	TracingExporter    string  `yaml:"exporter"`
+	TracingEndpoint    string  `yaml:"endpoint"`
+	TracingInsecure    bool    `yaml:"insecure"`
+	TracingSampleRatio float64 `yaml:"sample_ratio"`
+	TracingServiceName string  `yaml:"service_name"`
+}
+
+// IndexerConfig sets how the events of our contracts are followed. The indexed events invalidate cached reads.
+type IndexerConfig struct {
+	IndexerEnabled bool `yaml:"enabled"`
+	// IndexerStartHeight is the first height to index, 0 starts at the latest sealed block
+	IndexerStartHeight      uint64        `yaml:"start_height"`
+	IndexerPollInterval     time.Duration `yaml:"poll_interval"`
+	IndexerMaxBlocksPerPoll uint64        `yaml:"max_blocks_per_poll"`
+	// IndexerMaxLag is how many blocks the indexer may trail the latest sealed block before readiness fails
+	IndexerMaxLag uint64 `yaml:"max_lag"`
+	// IndexerCursorFile is where the indexed height is saved to resume after a restart, empty does not save it
+	IndexerCursorFile string `yaml:"cursor_file"`
+}
+
+// CacheConfig sets how long script results at the latest sealed block are served and how many results are kept
+type CacheConfig struct {
+	CacheLatestTTL  time.Duration `yaml:"latest_ttl"`
+	CacheMaxEntries int           `yaml:"max_entries"`
+}
+
+// StreamConfig sets how many recent events /events/stream keeps for clients resuming with Last-Event-ID
+type StreamConfig struct {
+	StreamHistorySize int `yaml:"history_size"`
+}
+
+// WebhooksConfig sets where webhooks are stored and how deliveries are retried. Deliveries back off
+// exponentially from WebhookInitialBackoff and move to the dead letters after WebhookMaxAttempts.
+type WebhooksConfig struct {
+	WebhooksFile          string        `yaml:"file"`
+	WebhookMaxAttempts    int           `yaml:"max_attempts"`
+	WebhookInitialBackoff time.Duration `yaml:"initial_backoff"`
+	WebhookMaxBackoff     time.Duration `yaml:"max_backoff"`
+	WebhookTimeout        time.Duration `yaml:"timeout"`
+	WebhookWorkers        int           `yaml:"workers"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		NetworkConfig: NetworkConfig{
+			FlowNode:                   "localhost:3569",
+			AccessNodeMaxLag:           2,
+			AccessNodeCheckInterval:    5 * time.Second,
+			FungibleTokenAddressHex:    "9a0766d93b6608b7",
+			NonFungibleTokenAddressHex: "631e88ae7f1d7c20",
+		},
+		KeysConfig: KeysConfig{
+			MinterSigAlgoName:  "ECDSA_P256",
+			MinterHashAlgoName: "SHA3_256",
+		},
+		HTTPConfig: HTTPConfig{
+			ListenAddress:   ":8080",
+			APIKeysFile:     "api_keys.json",
+			ShutdownTimeout: 30 * time.Second,
+		},
+		FaucetConfig: FaucetConfig{
+			FaucetAmount:       10,
+			FaucetDifficulty:   20,
+			FaucetChallengeTTL: 2 * time.Minute,
+			FaucetCooldown:     time.Hour,
+		},
+		HealthConfig: HealthConfig{
+			HealthMaxSealedLag:     100,
+			HealthMinMinterBalance: "0.001",
+			HealthCheckTimeout:     5 * time.Second,
+		},
+		LoggingConfig: LoggingConfig{
+			LogLevel:  "info",
+			LogFormat: "json",
+		},
+		TracingConfig: TracingConfig{
+			TracingExporter:    tracingExporterNone,
+			TracingEndpoint:    "localhost:4317",
+			TracingSampleRatio: 1,
+			TracingServiceName: "kitty-items-go",
+		},
+		IndexerConfig: IndexerConfig{
+			IndexerEnabled:          true,
+			IndexerPollInterval:     2 * time.Second,
+			IndexerMaxBlocksPerPoll: 250,
+			IndexerMaxLag:           50,
+			IndexerCursorFile:       defaultIndexerCursorFile,
+		},
+		CacheConfig: CacheConfig{
+			CacheLatestTTL:  2 * time.Second,
+			CacheMaxEntries: 10000,
+		},
+		StreamConfig: StreamConfig{
+			StreamHistorySize: 1000,
+		},
+		WebhooksConfig: WebhooksConfig{
+			WebhooksFile:          defaultWebhooksFile,
+			WebhookMaxAttempts:    8,
+			WebhookInitialBackoff: 5 * time.Second,
+			WebhookMaxBackoff:     time.Hour,
+			WebhookTimeout:        10 * time.Second,
+			WebhookWorkers:        4,
+		},
+	}
+}
+
+// LoadConfig reads the YAML file at path, if any, on top of the defaults, applies environment overrides
+// and validates the result. Unknown keys in the file are rejected.
+func LoadConfig(path string) (Config, error) {
+	conf, err := readConfig(path)
+	if err != nil {
+		return conf, err
+	}
+
+	if err := conf.Validate(); err != nil {
+		return conf, err
+	}
+
+	return conf, conf.Compute()
+}
+
+// readConfig reads the YAML file at path, if any, on top of the defaults and applies environment overrides
+func readConfig(path string) (Config, error) {
+	conf := defaultConfig()
+
+	if path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return conf, fmt.Errorf("error reading configuration file = %w", err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(contents))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&conf); err != nil && err != io.EOF {
+			return conf, fmt.Errorf("error parsing configuration file %s = %w", path, err)
+		}
+	}
+
+	// Environment variables with `KITTY_ITEMS` prefix override the file
+	if err := envconfig.Process("KITTY_ITEMS", &conf); err != nil {
+		return conf, fmt.Errorf("error parsing environment = %w", err)
+	}
+
+	return conf, nil
+}
+
+// Validate checks every setting and reports all problems at once
+func (c *Config) Validate() error {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.FlowNode == "" && len(c.AccessNodes) == 0 {
+		addProblem("network.flow_node or network.access_nodes is required")
+	}
+	for i, node := range c.AccessNodes {
+		if node.Address == "" {
+			addProblem("network.access_nodes[%d].address is required", i)
+		}
+		if !node.TLS && (node.TLSServerName != "" || node.TLSCAFile != "") {
+			addProblem("network.access_nodes[%d] sets TLS options but tls is false", i)
+		}
+		if node.TLSCAFile != "" {
+			if _, err := os.Stat(node.TLSCAFile); err != nil {
+				addProblem("network.access_nodes[%d].tls_ca_file cannot be read: %s", i, err)
+			}
+		}
+	}
+	if c.AccessNodeCheckInterval <= 0 {
+		addProblem("network.access_node_check_interval must be positive")
+	}
+	for _, address := range []struct {
+		name     string
+		value    string
+		required bool
+	}{
+		{"network.fungible_token_address", c.FungibleTokenAddressHex, true},
+		{"network.non_fungible_token_address", c.NonFungibleTokenAddressHex, true},
+		{"network.kibble_address", c.KibbleAddressHex, false},
+		{"network.kitty_items_address", c.KittyItemsAddressHex, false},
+		{"network.kitty_items_market_address", c.KittyItemsMarketAddressHex, false},
+		{"accounts.minter_address", c.MinterFlowAddressHex, true},
+	} {
+		if err := validateAddressHex(address.value, address.required); err != nil {
+			addProblem("%s %s", address.name, err)
+		}
+	}
+
+	sigAlgo := crypto.StringToSignatureAlgorithm(c.MinterSigAlgoName)
+	if sigAlgo == crypto.UnknownSignatureAlgorithm {
+		addProblem("keys.minter_sig_algo %q is not a known signature algorithm", c.MinterSigAlgoName)
+	}
+	hashAlgo := crypto.StringToHashAlgorithm(c.MinterHashAlgoName)
+	if hashAlgo == crypto.UnknownHashAlgorithm {
+		addProblem("keys.minter_hash_algo %q is not a known hash algorithm", c.MinterHashAlgoName)
+	}
+	if sigAlgo != crypto.UnknownSignatureAlgorithm && hashAlgo != crypto.UnknownHashAlgorithm && !crypto.CompatibleAlgorithms(sigAlgo, hashAlgo) {
+		addProblem("keys.minter_hash_algo %s cannot be used with %s", c.MinterHashAlgoName, c.MinterSigAlgoName)
+	}
+	if c.MinterPrivateKeyHex == "" {
+		addProblem("keys.minter_private_key is required")
+	} else if sigAlgo != crypto.UnknownSignatureAlgorithm {
+		if _, err := crypto.DecodePrivateKeyHex(sigAlgo, c.MinterPrivateKeyHex); err != nil {
+			addProblem("keys.minter_private_key is not a valid %s key", c.MinterSigAlgoName)
+		}
+	}
+	if c.MinterAccountKeyIndex < 0 {
+		addProblem("keys.minter_key_index must not be negative")
+	}
+	proposalKeyIndexes := map[int]bool{c.MinterAccountKeyIndex: true}
+	for _, index := range c.MinterProposalKeyIndexes {
+		if index < 0 {
+			addProblem("keys.minter_proposal_key_indexes must not be negative")
+		} else if proposalKeyIndexes[index] {
+			addProblem(fmt.Sprintf("keys.minter_proposal_key_indexes lists key %d twice or with the minter key", index))
+		}
+		proposalKeyIndexes[index] = true
+	}
+
+	if c.ListenAddress == "" {
+		addProblem("http.listen_address is required")
+	}
+	if c.APIKeysFile == "" {
+		addProblem("http.api_keys_file is required")
+	}
+	if c.ShutdownTimeout <= 0 {
+		addProblem("http.shutdown_timeout must be positive")
+	}
+
+	if c.FaucetMode {
+		if c.FaucetAmount == 0 {
+			addProblem("faucet.amount must be greater than zero")
+		}
+		if c.FaucetDifficulty > 256 {
+			addProblem("faucet.difficulty must be at most 256 bits")
+		}
+		if c.FaucetChallengeTTL <= 0 {
+			addProblem("faucet.challenge_ttl must be positive")
+		}
+		if c.FaucetCooldown < 0 {
+			addProblem("faucet.cooldown must not be negative")
+		}
+	}
+
+	if _, err := cadence.NewUFix64(c.HealthMinMinterBalance); err != nil {
+		addProblem("health.min_minter_balance %q is not a valid FLOW amount", c.HealthMinMinterBalance)
+	}
+	if c.HealthCheckTimeout <= 0 {
+		addProblem("health.check_timeout must be positive")
+	}
+
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil || c.LogLevel == "" {
+		addProblem("logging.level %q is not a known level", c.LogLevel)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "console" {
+		addProblem("logging.format must be json or console")
+	}
+
+	switch c.TracingExporter {
+	case tracingExporterNone, tracingExporterStdout:
+	case tracingExporterOTLP:
+		if c.TracingEndpoint == "" {
+			addProblem("tracing.endpoint is required with the otlp exporter")
+		}
+	default:
+		addProblem("tracing.exporter must be none, stdout or otlp")
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		addProblem("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if c.IndexerEnabled {
+		if c.IndexerPollInterval <= 0 {
+			addProblem("indexer.poll_interval must be positive")
+		}
+		if c.IndexerMaxBlocksPerPoll == 0 {
+			addProblem("indexer.max_blocks_per_poll must be greater than zero")
+		}
+	}
+
+	if c.CacheLatestTTL < 0 {
+		addProblem("cache.latest_ttl must not be negative")
+	}
+	if c.CacheMaxEntries < 0 {
+		addProblem("cache.max_entries must not be negative")
+	}
+
+	if c.StreamHistorySize < 0 {
+		addProblem("stream.history_size must not be negative")
+	}
 
-	// These are computed variables based on the env variables above
-	MinterFlowAddress flow.Address      `ignored:"true"`
-	MinterPrivateKey  crypto.PrivateKey `ignored:"true"`
+	if c.WebhooksFile == "" {
+		addProblem("webhooks.file is required")
+	}
+	if c.WebhookMaxAttempts <= 0 {
+		addProblem("webhooks.max_attempts must be greater than zero")
+	}
+	if c.WebhookInitialBackoff <= 0 || c.WebhookMaxBackoff < c.WebhookInitialBackoff {
+		addProblem("webhooks.initial_backoff must be positive and at most webhooks.max_backoff")
+	}
+	if c.WebhookTimeout <= 0 {
+		addProblem("webhooks.timeout must be positive")
+	}
+	if c.WebhookWorkers <= 0 {
+		addProblem("webhooks.workers must be greater than zero")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	return nil
 }
 
 // Compute sanitizes and converts configurations to their proper types for flow
 func (c *Config) Compute() (err error) {
 	c.MinterFlowAddress = flow.HexToAddress(c.MinterFlowAddressHex)
-	if c.MinterPrivateKey, err = crypto.DecodePrivateKeyHex(crypto.StringToSignatureAlgorithm(c.MinterSigAlgoName), c.MinterPrivateKeyHex); err != nil {
+	c.FungibleTokenFlowAddress = flow.HexToAddress(c.FungibleTokenAddressHex)
+	c.NonFungibleTokenFlowAddress = flow.HexToAddress(c.NonFungibleTokenAddressHex)
+	c.KibbleFlowAddress = c.MinterFlowAddress
+	if c.KibbleAddressHex != "" {
+		c.KibbleFlowAddress = flow.HexToAddress(c.KibbleAddressHex)
+	}
+	c.KittyItemsFlowAddress = c.MinterFlowAddress
+	if c.KittyItemsAddressHex != "" {
+		c.KittyItemsFlowAddress = flow.HexToAddress(c.KittyItemsAddressHex)
+	}
+	c.KittyItemsMarketFlowAddress = c.MinterFlowAddress
+	if c.KittyItemsMarketAddressHex != "" {
+		c.KittyItemsMarketFlowAddress = flow.HexToAddress(c.KittyItemsMarketAddressHex)
+	}
+	c.MinterSigAlgo = crypto.StringToSignatureAlgorithm(c.MinterSigAlgoName)
+	c.MinterHashAlgo = crypto.StringToHashAlgorithm(c.MinterHashAlgoName)
+	if c.MinterPrivateKey, err = crypto.DecodePrivateKeyHex(c.MinterSigAlgo, c.MinterPrivateKeyHex); err != nil {
 		return fmt.Errorf("error decrypting private key: %w", err)
 	}
 
 	return nil
 }
+
+// MinterAccountKey returns the configured key of the minter account after checking
+// that it exists, is not revoked and matches the configured private key and algorithms
+func (c *Config) MinterAccountKey(account *flow.Account) (*flow.AccountKey, error) {
+	return c.minterKey(account, "keys.minter_key_index", c.MinterAccountKeyIndex)
+}
+
+// MinterProposalKeys returns the minter key followed by the other keys of the minter account transactions are
+// proposed with, each checked like the minter key and for having the full weight to sign alone
+func (c *Config) MinterProposalKeys(account *flow.Account) ([]*flow.AccountKey, error) {
+	minterKey, err := c.MinterAccountKey(account)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*flow.AccountKey{minterKey}
+	for _, index := range c.MinterProposalKeyIndexes {
+		key, err := c.minterKey(account, "keys.minter_proposal_key_indexes", index)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		if key.Weight < flow.AccountKeyWeightThreshold {
+			return nil, fmt.Errorf("key %d of account %s has a weight of %d, it cannot sign alone", key.Index, account.Address, key.Weight)
+		}
+	}
+
+	return keys, nil
+}
+
+// minterKey returns the key at index of the minter account, configured by setting, after checking
+// that it exists, is not revoked and matches the configured private key and algorithms
+func (c *Config) minterKey(account *flow.Account, setting string, index int) (*flow.AccountKey, error) {
+	if index >= len(account.Keys) {
+		return nil, fmt.Errorf("%s %d is out of range, account %s has %d keys", setting, index, account.Address, len(account.Keys))
+	}
+
+	key := account.Keys[index]
+	if key.Revoked {
+		return nil, fmt.Errorf("key %d of account %s is revoked", key.Index, account.Address)
+	}
+	if !bytes.Equal(key.PublicKey.Encode(), c.MinterPrivateKey.PublicKey().Encode()) {
+		return nil, fmt.Errorf("keys.minter_private_key does not match key %d of account %s", key.Index, account.Address)
+	}
+	if key.HashAlgo != c.MinterHashAlgo {
+		return nil, fmt.Errorf("keys.minter_hash_algo is %s but key %d of account %s uses %s", c.MinterHashAlgo, key.Index, account.Address, key.HashAlgo)
+	}
+
+	return key, nil
+}
+
+// Faucet returns the proof-of-work settings used in faucet mode
+func (c *Config) Faucet() services.FaucetConfig {
+	return services.FaucetConfig{
+		Difficulty:   c.FaucetDifficulty,
+		ChallengeTTL: c.FaucetChallengeTTL,
+		Cooldown:     c.FaucetCooldown,
+	}
+}
+
+// Limits returns the mint limits configured for the services
+func (c *Config) Limits() services.LimitsConfig {
+	return services.LimitsConfig{
+		RequestsPerMinute:           c.RateLimitRequestsPerMinute,
+		MaxKibblePerRecipientPerDay: c.MaxKibblePerRecipientPerDay,
+		MaxKittyItemsPerRecipient:   c.MaxKittyItemsPerRecipient,
+		MaxKibbleMintedPerDay:       c.MaxKibbleMintedPerDay,
+	}
+}
+
+// Health returns the thresholds of the readiness checks
+func (c *Config) Health() services.HealthConfig {
+	// Validate already checked the balance parses
+	minBalance, _ := cadence.NewUFix64(c.HealthMinMinterBalance)
+	return services.HealthConfig{
+		MaxSealedLag:     c.HealthMaxSealedLag,
+		MinMinterBalance: uint64(minBalance),
+		Timeout:          c.HealthCheckTimeout,
+	}
+}
+
+// Indexer returns the indexer settings, with the events of our contracts that change account state
+func (c *Config) Indexer() services.IndexerConfig {
+	var eventTypes []string
+	for _, contract := range []struct {
+		address flow.Address
+		name    string
+		events  []string
+	}{
+		{c.KibbleFlowAddress, "Kibble", []string{"TokensWithdrawn", "TokensDeposited"}},
+		{c.KittyItemsFlowAddress, "KittyItems", []string{"Withdraw", "Deposit", "Minted"}},
+		{c.KittyItemsMarketFlowAddress, "KittyItemsMarket", []string{
+			"SaleOfferCreated", "SaleOfferAccepted", "SaleOfferFinished",
+			"CollectionInsertedSaleOffer", "CollectionRemovedSaleOffer",
+		}},
+	} {
+		for _, event := range contract.events {
+			eventTypes = append(eventTypes, fmt.Sprintf("A.%s.%s.%s", contract.address.Hex(), contract.name, event))
+		}
+	}
+
+	return services.IndexerConfig{
+		EventTypes:       eventTypes,
+		StartHeight:      c.IndexerStartHeight,
+		PollInterval:     c.IndexerPollInterval,
+		MaxBlocksPerPoll: c.IndexerMaxBlocksPerPoll,
+		CursorFile:       c.IndexerCursorFile,
+	}
+}
+
+// ScriptCache returns the settings of the cache of read script results
+func (c *Config) ScriptCache() services.ScriptCacheConfig {
+	return services.ScriptCacheConfig{
+		LatestTTL:  c.CacheLatestTTL,
+		MaxEntries: c.CacheMaxEntries,
+	}
+}
+
+// Webhooks returns the delivery settings of the webhooks
+func (c *Config) Webhooks() services.WebhookConfig {
+	return services.WebhookConfig{
+		MaxAttempts:    c.WebhookMaxAttempts,
+		InitialBackoff: c.WebhookInitialBackoff,
+		MaxBackoff:     c.WebhookMaxBackoff,
+		Timeout:        c.WebhookTimeout,
+		Workers:        c.WebhookWorkers,
+	}
+}
+
+// Logger builds the global logger. Sensitive fields are redacted whatever the format.
+func (c *Config) Logger() zerolog.Logger {
+	var out io.Writer = os.Stdout
+	if c.LogFormat == "console" {
+		out = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	}
+
+	level, _ := zerolog.ParseLevel(c.LogLevel)
+	return zerolog.New(services.NewRedactingWriter(out)).Level(level).With().Timestamp().Logger()
+}
+
+// runConfigCommand implements `config check`, which validates the configuration and,
+// unless -offline is given, checks the minter account key against the access node
+func runConfigCommand(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	path := fs.String("config", envOrDefault("KITTY_ITEMS_CONFIG", ""), "path to the YAML configuration file")
+	offline := fs.Bool("offline", false, "skip the checks that need the access node")
+
+	if len(args) == 0 || args[0] != "check" {
+		return errors.New("usage: kitty-items-go config check [-config file] [-offline]")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	conf, err := LoadConfig(*path)
+	if err != nil {
+		return err
+	}
+
+	if !*offline {
+		nodes, err := dialAccessNodes(conf)
+		if err != nil {
+			return err
+		}
+		defer nodes.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// Every node must be reachable, not only the first one that answers
+		nodes.Check(ctx)
+		for _, status := range nodes.Status() {
+			if !status.Healthy {
+				return fmt.Errorf("access node %s is unreachable = %s", status.Address, status.Error)
+			}
+		}
+
+		var minterAccount *flow.Account
+		err = nodes.Read(ctx, func(c *client.Client) (err error) {
+			minterAccount, err = c.GetAccount(ctx, conf.MinterFlowAddress)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error retrieving minter account = %w", err)
+		}
+
+		if _, err := conf.MinterAccountKey(minterAccount); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+func validateAddressHex(value string, required bool) error {
+	if value == "" {
+		if required {
+			return errors.New("is required")
+		}
+		return nil
+	}
+	if strings.HasPrefix(value, "0x") {
+		return errors.New("must not start with 0x")
+	}
+	if len(value) > 2*flow.AddressLength {
+		return fmt.Errorf("%q is longer than %d hex characters", value, 2*flow.AddressLength)
+	}
+	if len(value)%2 == 1 {
+		value = "0" + value
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return fmt.Errorf("%q is not valid hex", value)
+	}
+	return nil
+}
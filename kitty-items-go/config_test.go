@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMinterAddressHex = "01cf0e2f2f715450"
+
+func testPrivateKey(t *testing.T, seed byte) crypto.PrivateKey {
+	privateKey, err := crypto.GeneratePrivateKey(crypto.ECDSA_P256, []byte(strings.Repeat(string(rune('a'+seed)), 32)))
+	require.NoError(t, err)
+	return privateKey
+}
+
+// testConfig returns the defaults with the settings that have none filled in
+func testConfig(t *testing.T) Config {
+	conf := defaultConfig()
+	conf.MinterFlowAddressHex = testMinterAddressHex
+	conf.MinterPrivateKeyHex = hex.EncodeToString(testPrivateKey(t, 0).Encode())
+	return conf
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(c *Config)
+		// problems are parts of the error expected, none when the configuration is valid
+		problems []string
+	}{
+		{"Should accept the defaults with a minter", func(c *Config) {}, nil},
+		{"Should require an access node", func(c *Config) { c.FlowNode = "" }, []string{"network.flow_node or network.access_nodes is required"}},
+		{
+			"Should check every access node",
+			func(c *Config) {
+				c.AccessNodes = []AccessNodeConfig{{Address: "a:9000", TLS: true}, {TLSServerName: "b"}}
+			},
+			[]string{"network.access_nodes[1].address is required", "network.access_nodes[1] sets TLS options but tls is false"},
+		},
+		{"Should require the minter address", func(c *Config) { c.MinterFlowAddressHex = "" }, []string{"accounts.minter_address is required"}},
+		{"Should reject an address starting with 0x", func(c *Config) { c.KibbleAddressHex = "0x" + testMinterAddressHex }, []string{"network.kibble_address must not start with 0x"}},
+		{"Should reject an address that is too long", func(c *Config) { c.KittyItemsAddressHex = testMinterAddressHex + "00" }, []string{"network.kitty_items_address"}},
+		{"Should reject an address that is not hex", func(c *Config) { c.FungibleTokenAddressHex = "xyz" }, []string{"network.fungible_token_address \"0xyz\" is not valid hex"}},
+		{"Should require the minter private key", func(c *Config) { c.MinterPrivateKeyHex = "" }, []string{"keys.minter_private_key is required"}},
+		{"Should reject an invalid private key", func(c *Config) { c.MinterPrivateKeyHex = "abcd" }, []string{"keys.minter_private_key is not a valid ECDSA_P256 key"}},
+		{"Should reject an unknown signature algorithm", func(c *Config) { c.MinterSigAlgoName = "RSA" }, []string{"keys.minter_sig_algo \"RSA\""}},
+		{"Should reject incompatible algorithms", func(c *Config) { c.MinterHashAlgoName = "SHA2_384" }, []string{"keys.minter_hash_algo SHA2_384 cannot be used with ECDSA_P256"}},
+		{"Should reject a negative minter key index", func(c *Config) { c.MinterAccountKeyIndex = -1 }, []string{"keys.minter_key_index must not be negative"}},
+		{"Should reject a negative proposal key index", func(c *Config) { c.MinterProposalKeyIndexes = []int{-1} }, []string{"keys.minter_proposal_key_indexes must not be negative"}},
+		{
+			"Should reject proposal keys listed twice",
+			func(c *Config) { c.MinterProposalKeyIndexes = []int{1, 0, 1} },
+			[]string{"lists key 0 twice or with the minter key", "lists key 1 twice or with the minter key"},
+		},
+		{"Should only check the faucet when it is enabled", func(c *Config) { c.FaucetAmount = 0 }, nil},
+		{
+			"Should check the faucet settings",
+			func(c *Config) {
+				c.FaucetMode = true
+				c.FaucetAmount = 0
+				c.FaucetDifficulty = 257
+			},
+			[]string{"faucet.amount must be greater than zero", "faucet.difficulty must be at most 256 bits"},
+		},
+		{"Should reject an invalid balance threshold", func(c *Config) { c.HealthMinMinterBalance = "-1" }, []string{"health.min_minter_balance \"-1\""}},
+		{"Should reject an unknown log level", func(c *Config) { c.LogLevel = "loud" }, []string{"logging.level \"loud\""}},
+		{"Should require an endpoint for otlp", func(c *Config) { c.TracingExporter, c.TracingEndpoint = "otlp", "" }, []string{"tracing.endpoint is required"}},
+		{"Should reject a sample ratio above 1", func(c *Config) { c.TracingSampleRatio = 2 }, []string{"tracing.sample_ratio must be between 0 and 1"}},
+		{
+			"Should check the webhook backoffs",
+			func(c *Config) { c.WebhookInitialBackoff, c.WebhookMaxBackoff = time.Minute, time.Second },
+			[]string{"webhooks.initial_backoff must be positive and at most webhooks.max_backoff"},
+		},
+		{
+			"Should report every problem at once",
+			func(c *Config) {
+				c.ListenAddress = ""
+				c.ShutdownTimeout = 0
+			},
+			[]string{"http.listen_address is required", "http.shutdown_timeout must be positive"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := testConfig(t)
+			c.change(&conf)
+
+			err := conf.Validate()
+			if len(c.problems) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, problem := range c.problems {
+				assert.Contains(t, err.Error(), problem)
+			}
+		})
+	}
+}
+
+func TestConfigMinterAccountKey(t *testing.T) {
+	privateKey := testPrivateKey(t, 0)
+	accountKey := func(index int, privateKey crypto.PrivateKey, hashAlgo crypto.HashAlgorithm, revoked bool) *flow.AccountKey {
+		return &flow.AccountKey{
+			Index:     index,
+			PublicKey: privateKey.PublicKey(),
+			SigAlgo:   crypto.ECDSA_P256,
+			HashAlgo:  hashAlgo,
+			Weight:    flow.AccountKeyWeightThreshold,
+			Revoked:   revoked,
+		}
+	}
+
+	cases := []struct {
+		name    string
+		keys    []*flow.AccountKey
+		index   int
+		problem string
+	}{
+		{
+			name:  "Should return the configured key",
+			keys:  []*flow.AccountKey{accountKey(0, testPrivateKey(t, 1), crypto.SHA3_256, false), accountKey(1, privateKey, crypto.SHA3_256, false)},
+			index: 1,
+		},
+		{
+			name:    "Should reject an index out of range",
+			keys:    []*flow.AccountKey{accountKey(0, privateKey, crypto.SHA3_256, false)},
+			index:   1,
+			problem: "keys.minter_key_index 1 is out of range, account 01cf0e2f2f715450 has 1 keys",
+		},
+		{
+			name:    "Should reject a revoked key",
+			keys:    []*flow.AccountKey{accountKey(0, privateKey, crypto.SHA3_256, true)},
+			problem: "key 0 of account 01cf0e2f2f715450 is revoked",
+		},
+		{
+			name:    "Should reject a key of another private key",
+			keys:    []*flow.AccountKey{accountKey(0, testPrivateKey(t, 1), crypto.SHA3_256, false)},
+			problem: "keys.minter_private_key does not match key 0",
+		},
+		{
+			name:    "Should reject a key with another hash algorithm",
+			keys:    []*flow.AccountKey{accountKey(0, privateKey, crypto.SHA2_256, false)},
+			problem: "keys.minter_hash_algo is SHA3_256 but key 0",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := testConfig(t)
+			conf.MinterAccountKeyIndex = c.index
+			require.NoError(t, conf.Validate())
+			require.NoError(t, conf.Compute())
+
+			key, err := conf.MinterAccountKey(&flow.Account{Address: conf.MinterFlowAddress, Keys: c.keys})
+			if c.problem != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), c.problem)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.keys[c.index], key)
+		})
+	}
+}
+
+func TestConfigMinterProposalKeys(t *testing.T) {
+	privateKey := testPrivateKey(t, 0)
+	accountKey := func(index int, weight int) *flow.AccountKey {
+		return &flow.AccountKey{
+			Index:     index,
+			PublicKey: privateKey.PublicKey(),
+			SigAlgo:   crypto.ECDSA_P256,
+			HashAlgo:  crypto.SHA3_256,
+			Weight:    weight,
+		}
+	}
+	otherKey := &flow.AccountKey{Index: 3, PublicKey: testPrivateKey(t, 1).PublicKey(), SigAlgo: crypto.ECDSA_P256, HashAlgo: crypto.SHA3_256, Weight: flow.AccountKeyWeightThreshold}
+	keys := []*flow.AccountKey{accountKey(0, flow.AccountKeyWeightThreshold), accountKey(1, flow.AccountKeyWeightThreshold), accountKey(2, 500), otherKey}
+
+	cases := []struct {
+		name     string
+		indexes  []int
+		expected []*flow.AccountKey
+		problem  string
+	}{
+		{"Should return the minter key alone by default", nil, []*flow.AccountKey{keys[0]}, ""},
+		{"Should return the minter key first, then the proposal keys", []int{1}, []*flow.AccountKey{keys[0], keys[1]}, ""},
+		{"Should reject a proposal key out of range", []int{4}, nil, "keys.minter_proposal_key_indexes 4 is out of range"},
+		{"Should reject a proposal key of another private key", []int{3}, nil, "keys.minter_private_key does not match key 3"},
+		{"Should reject a proposal key that cannot sign alone", []int{2}, nil, "key 2 of account 01cf0e2f2f715450 has a weight of 500"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := testConfig(t)
+			conf.MinterProposalKeyIndexes = c.indexes
+			require.NoError(t, conf.Validate())
+			require.NoError(t, conf.Compute())
+
+			proposalKeys, err := conf.MinterProposalKeys(&flow.Account{Address: conf.MinterFlowAddress, Keys: keys})
+			if c.problem != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), c.problem)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, proposalKeys)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	write := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+	minter := "accounts:\n  minter_address: " + testMinterAddressHex + "\nkeys:\n  minter_private_key: " +
+		hex.EncodeToString(testPrivateKey(t, 0).Encode()) + "\n"
+
+	t.Run("Should read the file on top of the defaults", func(t *testing.T) {
+		conf, err := LoadConfig(write(t, minter+"http:\n  listen_address: \":9090\"\n"))
+		require.NoError(t, err)
+		assert.Equal(t, ":9090", conf.ListenAddress)
+		assert.Equal(t, 30*time.Second, conf.ShutdownTimeout)
+		assert.Equal(t, flow.HexToAddress(testMinterAddressHex), conf.KibbleFlowAddress)
+	})
+
+	t.Run("Should let the environment override the file", func(t *testing.T) {
+		os.Setenv("KITTY_ITEMS_LISTENADDRESS", ":7070")
+		defer os.Unsetenv("KITTY_ITEMS_LISTENADDRESS")
+
+		conf, err := LoadConfig(write(t, minter+"http:\n  listen_address: \":9090\"\n"))
+		require.NoError(t, err)
+		assert.Equal(t, ":7070", conf.ListenAddress)
+	})
+
+	t.Run("Should reject unknown keys", func(t *testing.T) {
+		_, err := LoadConfig(write(t, minter+"http:\n  listen_adress: \":9090\"\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "listen_adress")
+	})
+
+	t.Run("Should validate the file", func(t *testing.T) {
+		_, err := LoadConfig(write(t, minter+"keys:\n  minter_key_index: -1\n"))
+		require.Error(t, err)
+	})
+}
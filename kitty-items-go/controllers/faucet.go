@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/flow-go-sdk"
+)
+
+type faucetController struct {
+	faucetService  *services.FaucetService
+	kibblesService *services.KibblesService
+	amount         uint
+}
+
+type FaucetChallengeRequest struct {
+	FlowAddress string `json:"flow_address"`
+}
+
+type FaucetMintRequest struct {
+	FlowAddress string `json:"flow_address"`
+	Challenge   string `json:"challenge"`
+	Nonce       string `json:"nonce"`
+}
+
+func NewFaucet(f *services.FaucetService, k *services.KibblesService, amount uint) *faucetController {
+	return &faucetController{f, k, amount}
+}
+
+// HandleChallenge issues a proof-of-work challenge for the requested address
+func (f *faucetController) HandleChallenge(w http.ResponseWriter, r *http.Request) {
+	body := &FaucetChallengeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if strings.HasPrefix(body.FlowAddress, "0x") {
+		http.Error(w, "invalid flow address: remove 0x", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := f.faucetService.IssueChallenge(flow.HexToAddress(body.FlowAddress))
+	if handleCooldownError(w, err) {
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error issuing faucet challenge")
+		http.Error(w, "error issuing challenge", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&challenge)
+}
+
+// HandleMintKibbles mints the faucet amount to an address that solved its pending challenge
+func (f *faucetController) HandleMintKibbles(w http.ResponseWriter, r *http.Request) {
+	body := &FaucetMintRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if strings.HasPrefix(body.FlowAddress, "0x") {
+		http.Error(w, "invalid flow address: remove 0x", http.StatusBadRequest)
+		return
+	}
+
+	flowDestinationAddress := flow.HexToAddress(body.FlowAddress)
+	ctx := services.WithLogFields(r.Context(), map[string]interface{}{"flow_address": flowDestinationAddress.Hex()})
+	logger := services.Logger(ctx)
+
+	logger.Info().Str("challenge", body.Challenge).Msg("faucet request")
+
+	err := f.faucetService.Redeem(flowDestinationAddress, body.Challenge, body.Nonce)
+	if handleCooldownError(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	transactionID, quota, err := f.kibblesService.Mint(ctx, flowDestinationAddress, f.amount)
+	writeQuotaHeaders(w, quota)
+	if err != nil {
+		f.faucetService.ResetCooldown(flowDestinationAddress)
+	}
+	if handleQuotaError(w, err) {
+		return
+	}
+	if handleShuttingDownError(w, err) {
+		return
+	}
+	if errors.Is(err, services.ErrAllowanceExceeded) {
+		http.Error(w, "faucet is empty", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("error minting tokens")
+		http.Error(w, "error minting tokens", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info().Str("transaction_id", transactionID).Msg("faucet minted kibbles")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&MintKibblesResponse{transactionID})
+}
+
+// handleCooldownError writes a 429 response with Retry-After if err is a cooldown error and reports whether it did
+func handleCooldownError(w http.ResponseWriter, err error) bool {
+	var cooldownErr *services.CooldownError
+	if !errors.As(err, &cooldownErr) {
+		return false
+	}
+
+	retryAfter := int64(time.Until(cooldownErr.Until).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	http.Error(w, cooldownErr.Error(), http.StatusTooManyRequests)
+	return true
+}
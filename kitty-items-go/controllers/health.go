@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+)
+
+type healthController struct {
+	healthService *services.HealthService
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks []services.CheckResult `json:"checks,omitempty"`
+}
+
+func NewHealth(h *services.HealthService) *healthController {
+	return &healthController{h}
+}
+
+// HandleHealthz reports that the process is alive, without touching any dependency
+func (h *healthController) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&HealthResponse{Status: services.CheckStatusOK})
+}
+
+// HandleReadyz runs the readiness checks and responds with 503 if any of them fails
+func (h *healthController) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ready, checks := h.healthService.Ready(r.Context())
+
+	response := &HealthResponse{Status: services.CheckStatusOK, Checks: checks}
+	status := http.StatusOK
+	if !ready {
+		response.Status = services.CheckStatusFail
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthController(t *testing.T) {
+	newController := func(fake *services.FakeFlow) *healthController {
+		conf := services.HealthConfig{MaxSealedLag: 10, MinMinterBalance: 5e8}
+		return NewHealth(services.NewHealth(time.Second, services.FlowHealthChecks(fake, conf)...))
+	}
+	newFake := func(balance uint64) *services.FakeFlow {
+		fake := services.NewFakeFlow(testMinterAddress, kittyitems.Contracts{})
+		fake.SetMinterAccount(&flow.Account{
+			Address: testMinterAddress,
+			Balance: balance,
+			Keys:    []*flow.AccountKey{{Index: 0, Weight: flow.AccountKeyWeightThreshold}},
+		})
+		return fake
+	}
+
+	cases := []struct {
+		name    string
+		balance uint64
+		status  int
+		// failed are the checks expected to fail
+		failed []string
+	}{
+		{"Should be ready when every check passes", 5e8, http.StatusOK, nil},
+		{"Should be unavailable when a check fails", 1e8, http.StatusServiceUnavailable, []string{"minter_balance"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newController(newFake(c.balance)).HandleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, c.status, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+			var response HealthResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			var failed []string
+			for _, check := range response.Checks {
+				if check.Status == services.CheckStatusFail {
+					failed = append(failed, check.Name)
+				}
+			}
+			assert.Equal(t, c.failed, failed)
+			if c.failed == nil {
+				assert.Equal(t, services.CheckStatusOK, response.Status)
+			} else {
+				assert.Equal(t, services.CheckStatusFail, response.Status)
+			}
+		})
+	}
+
+	t.Run("Should be alive without running the checks", func(t *testing.T) {
+		fake := newFake(0)
+		w := httptest.NewRecorder()
+		newController(fake).HandleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+	})
+}
@@ -1,13 +1,14 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
-	"log"
-
 	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/cadence"
 	"github.com/onflow/flow-go-sdk"
 )
 
@@ -24,6 +25,20 @@ type MintKibblesResponse struct {
 	TransactionID string `json:"transaction_id"`
 }
 
+type MinterAllowanceRequest struct {
+	Amount string `json:"amount"`
+}
+
+type MinterAllowanceResponse struct {
+	AllowedAmount string `json:"allowed_amount"`
+	Height        uint64 `json:"height"`
+}
+
+type BalanceResponse struct {
+	Balance string `json:"balance"`
+	Height  uint64 `json:"height"`
+}
+
 func NewKibbles(k *services.KibblesService) *kibblesController {
 	return &kibblesController{k}
 }
@@ -40,17 +55,108 @@ func (k *kibblesController) HandleMintKibbles(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	log.Printf("minting kibbles request = %+v", *body)
-
 	flowDestinationAddress := flow.HexToAddress(body.FlowAddress)
-	transactionID, err := k.kibblesService.Mint(r.Context(), flowDestinationAddress, body.Amount)
+	ctx := services.WithLogFields(r.Context(), map[string]interface{}{"flow_address": flowDestinationAddress.Hex()})
+	logger := services.Logger(ctx)
+
+	logger.Info().Uint("amount", body.Amount).Msg("minting kibbles")
+
+	transactionID, quota, err := k.kibblesService.Mint(ctx, flowDestinationAddress, body.Amount)
+	writeQuotaHeaders(w, quota)
+	if handleQuotaError(w, err) {
+		return
+	}
+	if handleShuttingDownError(w, err) {
+		return
+	}
+	if errors.Is(err, services.ErrAllowanceExceeded) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
-		log.Printf("error minting tokens = %s", err)
+		logger.Error().Err(err).Msg("error minting tokens")
 		http.Error(w, "error minting tokens", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("minted kibbles txId=%s", transactionID)
+	logger.Info().Str("transaction_id", transactionID).Msg("minted kibbles")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&MintKibblesResponse{transactionID})
+}
+
+// HandleGetMinterAllowance returns the remaining allowance of the on-chain Minter, at the block given by `?at=` if any
+func (k *kibblesController) HandleGetMinterAllowance(w http.ResponseWriter, r *http.Request) {
+	at, ok := readBlockRef(w, r)
+	if !ok {
+		return
+	}
+
+	allowance, height, err := k.kibblesService.MinterAllowance(r.Context(), at)
+	if err != nil {
+		handleReadError(r.Context(), w, err, "error reading minter allowance")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&MinterAllowanceResponse{services.FormatUFix64(allowance), height})
+}
+
+// HandleGetBalance returns the Kibble balance of an account, at the block given by `?at=` if any
+func (k *kibblesController) HandleGetBalance(w http.ResponseWriter, r *http.Request) {
+	ctx, address, ok := readAddress(w, r)
+	if !ok {
+		return
+	}
+	at, ok := readBlockRef(w, r)
+	if !ok {
+		return
+	}
+
+	balance, height, err := k.kibblesService.Balance(ctx, address, at)
+	if err != nil {
+		handleReadError(ctx, w, err, "error reading balance")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&BalanceResponse{services.FormatUFix64(balance), height})
+}
+
+// HandleProvisionMinter creates the long-lived Minter with the requested allowance
+func (k *kibblesController) HandleProvisionMinter(w http.ResponseWriter, r *http.Request) {
+	k.handleMinterTransaction(w, r, k.kibblesService.ProvisionMinter)
+}
+
+// HandleTopUpMinter raises the allowance of the existing Minter by the requested amount
+func (k *kibblesController) HandleTopUpMinter(w http.ResponseWriter, r *http.Request) {
+	k.handleMinterTransaction(w, r, k.kibblesService.TopUpMinter)
+}
+
+func (k *kibblesController) handleMinterTransaction(w http.ResponseWriter, r *http.Request, send func(context.Context, cadence.UFix64) (string, error)) {
+	body := &MinterAllowanceRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	amount, err := cadence.NewUFix64(body.Amount)
+	if err != nil {
+		http.Error(w, "invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := send(r.Context(), amount)
+	if handleShuttingDownError(w, err) {
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error sending minter transaction")
+		http.Error(w, "error sending minter transaction", http.StatusInternalServerError)
+		return
+	}
+
+	services.Logger(r.Context()).Info().Str("transaction_id", transactionID).Msg("sent minter transaction")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(&MintKibblesResponse{transactionID})
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFungibleTokenAddress = flow.HexToAddress("ee82856bf20e2aa6")
+	testMinterAddress        = flow.HexToAddress("01cf0e2f2f715450")
+	testRecipientAddress     = flow.HexToAddress("179b6b1cb6755e31")
+)
+
+func newTestKibblesRouter(t *testing.T) (*mux.Router, *services.FakeFlow) {
+	fake := services.NewFakeFlow(testMinterAddress, kittyitems.Contracts{FungibleToken: testFungibleTokenAddress, Kibble: testMinterAddress})
+	cache := services.NewScriptCache(fake, services.ScriptCacheConfig{LatestTTL: time.Minute, MaxEntries: 100})
+	kibbles := services.NewKibbles(fake, services.NewLimits(services.LimitsConfig{}), cache, testFungibleTokenAddress, testMinterAddress)
+
+	c := NewKibbles(kibbles)
+	r := mux.NewRouter()
+	r.HandleFunc("/kibbles/new", c.HandleMintKibbles).Methods(http.MethodPost)
+	r.HandleFunc("/kibbles/balance/{address}", c.HandleGetBalance).Methods(http.MethodGet)
+	return r, fake
+}
+
+func testUFix64(t *testing.T, s string) cadence.UFix64 {
+	value, err := cadence.NewUFix64(s)
+	require.NoError(t, err)
+	return value
+}
+
+func TestKibblesController(t *testing.T) {
+	t.Run("Should return the balance and the height it was read at", func(t *testing.T) {
+		r, fake := newTestKibblesRouter(t)
+		fake.SetHeight(7)
+		fake.SetScriptResult(kittyitems.KibbleGetBalanceScript, testUFix64(t, "3.5"))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/kibbles/balance/"+testRecipientAddress.Hex(), nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response BalanceResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, BalanceResponse{Balance: "3.50000000", Height: 7}, response)
+	})
+
+	t.Run("Should return the transaction ID of a mint", func(t *testing.T) {
+		r, fake := newTestKibblesRouter(t)
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "100.0"))
+
+		w := httptest.NewRecorder()
+		body := `{"flow_address": "` + testRecipientAddress.Hex() + `", "amount": 10}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/kibbles/new", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response MintKibblesResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		transactions := fake.Transactions()
+		require.Len(t, transactions, 1)
+		assert.Equal(t, transactions[0].Transaction.ID().String(), response.TransactionID)
+	})
+
+	t.Run("Should be unavailable when the minter allowance is exhausted", func(t *testing.T) {
+		r, fake := newTestKibblesRouter(t)
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "1.0"))
+
+		w := httptest.NewRecorder()
+		body := `{"flow_address": "` + testRecipientAddress.Hex() + `", "amount": 10}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/kibbles/new", strings.NewReader(body)))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Empty(t, fake.Transactions())
+	})
+
+	t.Run("Should ask to retry a mint rejected during shutdown", func(t *testing.T) {
+		r, fake := newTestKibblesRouter(t)
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "100.0"))
+		fake.SetSendError("mint_kibbles", fmt.Errorf("error sending = %w", services.ErrShuttingDown))
+
+		w := httptest.NewRecorder()
+		body := `{"flow_address": "` + testRecipientAddress.Hex() + `", "amount": 10}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/kibbles/new", strings.NewReader(body)))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "5", w.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/flow-go-sdk"
+)
+
+type kittyItemsController struct {
+	kittyItemsService *services.KittyItemsService
+}
+
+type MintKittyItemRequest struct {
+	FlowAddress string `json:"flow_address"`
+	TypeID      uint64 `json:"type_id"`
+}
+
+type MintKittyItemResponse struct {
+	TransactionID string `json:"transaction_id"`
+}
+
+type CollectionIDsResponse struct {
+	IDs    []uint64 `json:"ids"`
+	Height uint64   `json:"height"`
+}
+
+func NewKittyItems(k *services.KittyItemsService) *kittyItemsController {
+	return &kittyItemsController{k}
+}
+
+func (k *kittyItemsController) HandleMintKittyItem(w http.ResponseWriter, r *http.Request) {
+	body := &MintKittyItemRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if strings.HasPrefix(body.FlowAddress, "0x") {
+		http.Error(w, "invalid flow address: remove 0x", http.StatusBadRequest)
+		return
+	}
+
+	flowDestinationAddress := flow.HexToAddress(body.FlowAddress)
+	ctx := services.WithLogFields(r.Context(), map[string]interface{}{"flow_address": flowDestinationAddress.Hex()})
+	logger := services.Logger(ctx)
+
+	logger.Info().Uint64("type_id", body.TypeID).Msg("minting kitty item")
+
+	transactionID, quota, err := k.kittyItemsService.Mint(ctx, flowDestinationAddress, body.TypeID)
+	writeQuotaHeaders(w, quota)
+	if handleQuotaError(w, err) {
+		return
+	}
+	if handleShuttingDownError(w, err) {
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("error minting kitty item")
+		http.Error(w, "error minting kitty item", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info().Str("transaction_id", transactionID).Msg("minted kitty item")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&MintKittyItemResponse{transactionID})
+}
+
+// HandleGetCollectionIDs returns the IDs of the KittyItems owned by an account, at the block given by `?at=` if any
+func (k *kittyItemsController) HandleGetCollectionIDs(w http.ResponseWriter, r *http.Request) {
+	ctx, address, ok := readAddress(w, r)
+	if !ok {
+		return
+	}
+	at, ok := readBlockRef(w, r)
+	if !ok {
+		return
+	}
+
+	ids, height, err := k.kittyItemsService.CollectionIDs(ctx, address, at)
+	if err != nil {
+		handleReadError(ctx, w, err, "error reading collection")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&CollectionIDsResponse{ids, height})
+}
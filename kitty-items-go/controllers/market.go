@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+)
+
+type marketController struct {
+	marketService *services.MarketService
+}
+
+type SaleOfferIDsResponse struct {
+	IDs    []uint64 `json:"ids"`
+	Height uint64   `json:"height"`
+}
+
+type SaleOfferResponse struct {
+	SaleCompleted bool   `json:"sale_completed"`
+	ItemID        uint64 `json:"item_id"`
+	Price         string `json:"price"`
+	Height        uint64 `json:"height"`
+}
+
+func NewMarket(m *services.MarketService) *marketController {
+	return &marketController{m}
+}
+
+// HandleGetSaleOfferIDs returns the IDs of the items offered for sale in the market collection of an account,
+// at the block given by `?at=` if any
+func (m *marketController) HandleGetSaleOfferIDs(w http.ResponseWriter, r *http.Request) {
+	ctx, address, ok := readAddress(w, r)
+	if !ok {
+		return
+	}
+	at, ok := readBlockRef(w, r)
+	if !ok {
+		return
+	}
+
+	ids, height, err := m.marketService.SaleOfferIDs(ctx, address, at)
+	if err != nil {
+		handleReadError(ctx, w, err, "error reading sale offers")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&SaleOfferIDsResponse{ids, height})
+}
+
+// HandleGetSaleOffer returns an offer of the market collection of an account, at the block given by `?at=` if any,
+// or 404 if the item was not for sale
+func (m *marketController) HandleGetSaleOffer(w http.ResponseWriter, r *http.Request) {
+	ctx, address, ok := readAddress(w, r)
+	if !ok {
+		return
+	}
+	at, ok := readBlockRef(w, r)
+	if !ok {
+		return
+	}
+
+	itemID, err := strconv.ParseUint(mux.Vars(r)["itemID"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid item id", http.StatusBadRequest)
+		return
+	}
+
+	offer, height, err := m.marketService.SaleOffer(ctx, address, itemID, at)
+	if err != nil {
+		handleReadError(ctx, w, err, "error reading sale offer")
+		return
+	}
+	if offer == nil {
+		http.Error(w, "sale offer not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&SaleOfferResponse{offer.SaleCompleted, offer.SaleItemID, services.FormatUFix64(offer.SalePrice), height})
+}
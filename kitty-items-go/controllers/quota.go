@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+)
+
+// writeQuotaHeaders exposes the state of every enforced limit so clients can pace themselves
+func writeQuotaHeaders(w http.ResponseWriter, q services.Quota) {
+	h := w.Header()
+	if q.RequestsLimit > 0 || q.RecipientLimit > 0 || q.GlobalLimit > 0 {
+		// The counters belong to the instance that served the request
+		h.Set("X-Quota-Scope", services.QuotaScope)
+	}
+	if q.RequestsLimit > 0 {
+		h.Set("X-RateLimit-Limit", strconv.FormatUint(q.RequestsLimit, 10))
+		h.Set("X-RateLimit-Remaining", strconv.FormatUint(q.RequestsRemaining, 10))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(q.RequestsReset.Unix(), 10))
+	}
+	if q.RecipientLimit > 0 {
+		h.Set("X-Quota-Recipient-Limit", strconv.FormatUint(q.RecipientLimit, 10))
+		h.Set("X-Quota-Recipient-Remaining", strconv.FormatUint(q.RecipientRemaining, 10))
+	}
+	if q.GlobalLimit > 0 {
+		h.Set("X-Quota-Global-Limit", strconv.FormatUint(q.GlobalLimit, 10))
+		h.Set("X-Quota-Global-Remaining", strconv.FormatUint(q.GlobalRemaining, 10))
+	}
+	if !q.DailyReset.IsZero() && (q.RecipientLimit > 0 || q.GlobalLimit > 0) {
+		h.Set("X-Quota-Reset", strconv.FormatInt(q.DailyReset.Unix(), 10))
+	}
+}
+
+// handleQuotaError writes a 429 response if err is a quota error and reports whether it did
+func handleQuotaError(w http.ResponseWriter, err error) bool {
+	var quotaErr *services.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+	http.Error(w, quotaErr.Error(), http.StatusTooManyRequests)
+	return true
+}
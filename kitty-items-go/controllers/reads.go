@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+	"github.com/onflow/flow-go-sdk"
+)
+
+// readAddress parses the `address` route variable and adds it to the log fields of the request context.
+// It writes a 400 response and reports false when the address is invalid.
+func readAddress(w http.ResponseWriter, r *http.Request) (context.Context, flow.Address, bool) {
+	address, err := parseAddress(mux.Vars(r)["address"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, flow.EmptyAddress, false
+	}
+
+	ctx := services.WithLogFields(r.Context(), map[string]interface{}{"flow_address": address.Hex()})
+	return ctx, address, true
+}
+
+// parseAddress parses a hex address without the 0x prefix
+func parseAddress(addressHex string) (flow.Address, error) {
+	if strings.HasPrefix(addressHex, "0x") {
+		return flow.EmptyAddress, errors.New("invalid flow address: remove 0x")
+	}
+	if len(addressHex) > 2*flow.AddressLength {
+		return flow.EmptyAddress, errors.New("invalid flow address")
+	}
+
+	address := flow.HexToAddress(addressHex)
+	if address == flow.EmptyAddress {
+		return flow.EmptyAddress, errors.New("invalid flow address")
+	}
+	return address, nil
+}
+
+// readBlockRef parses the optional `at` query parameter, a block height or a hex block ID.
+// It writes a 400 response and reports false when the parameter is invalid.
+func readBlockRef(w http.ResponseWriter, r *http.Request) (services.BlockRef, bool) {
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		return services.BlockRef{}, true
+	}
+
+	if height, err := strconv.ParseUint(at, 10, 64); err == nil {
+		return services.AtHeight(height), true
+	}
+
+	if id, err := hex.DecodeString(at); err == nil && len(id) == len(flow.Identifier{}) {
+		return services.AtBlockID(flow.BytesToID(id)), true
+	}
+
+	http.Error(w, "invalid at: must be a block height or a block ID", http.StatusBadRequest)
+	return services.BlockRef{}, false
+}
+
+// handleReadError logs a failed read and writes a 500 response, a 404 when the requested block is unknown,
+// or a 504 when the access nodes could not be reached
+func handleReadError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, services.ErrBlockNotFound) {
+		http.Error(w, "block not found", http.StatusNotFound)
+		return
+	}
+
+	services.Logger(ctx).Error().Err(err).Msg(message)
+	if errors.Is(err, services.ErrNoAccessNode) || errors.Is(err, context.DeadlineExceeded) {
+		http.Error(w, message, http.StatusGatewayTimeout)
+		return
+	}
+	http.Error(w, message, http.StatusInternalServerError)
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+)
+
+// shutdownRetryAfter is how long clients are asked to wait before retrying a mint rejected during shutdown,
+// by which time the load balancer sends them to another instance
+const shutdownRetryAfter = 5 * time.Second
+
+// handleShuttingDownError writes a 503 response with Retry-After if err reports the service is shutting down
+// and reports whether it did
+func handleShuttingDownError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, services.ErrShuttingDown) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(shutdownRetryAfter.Seconds()), 10))
+	http.Error(w, "shutting down, retry later", http.StatusServiceUnavailable)
+	return true
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/flow-go-sdk"
+)
+
+// streamKeepAlive is how often a comment is sent on idle streams, so proxies don't close them
+const streamKeepAlive = 15 * time.Second
+
+type streamController struct {
+	streamService *services.StreamService
+}
+
+func NewStream(s *services.StreamService) *streamController {
+	return &streamController{s}
+}
+
+// HandleStream serves the events matching the `transactions`, `addresses` and `types` query parameters, each a
+// comma separated list, as Server-Sent Events. Clients resume with the `Last-Event-ID` header, or the
+// `last_event_id` parameter. A `reset` event is sent first when events may have been missed since.
+func (s *streamController) HandleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := readStreamFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	subscription, missed, complete := s.streamService.Subscribe(filter, lastEventID)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		if err := writeStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event services.StreamEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Name(), data)
+	return err
+}
+
+func readStreamFilter(r *http.Request) (services.StreamFilter, error) {
+	var filter services.StreamFilter
+
+	for _, id := range queryList(r, "transactions") {
+		if decoded, err := hex.DecodeString(id); err != nil || len(decoded) != len(flow.Identifier{}) {
+			return filter, fmt.Errorf("invalid transaction id %q", id)
+		}
+		filter.TransactionIDs = append(filter.TransactionIDs, strings.ToLower(id))
+	}
+
+	for _, addressHex := range queryList(r, "addresses") {
+		address, err := parseAddress(addressHex)
+		if err != nil {
+			return filter, fmt.Errorf("%s: %q", err, addressHex)
+		}
+		filter.Addresses = append(filter.Addresses, address)
+	}
+
+	filter.EventTypes = queryList(r, "types")
+
+	return filter, nil
+}
+
+// queryList returns the comma separated values of a query parameter, which may also be repeated
+func queryList(r *http.Request, name string) []string {
+	var values []string
+	for _, param := range r.URL.Query()[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
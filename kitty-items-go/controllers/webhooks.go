@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+	"github.com/onflow/flow-go-sdk"
+)
+
+// webhookMaxReplayBlocks bounds the height range of a replay, which is fetched again from the access node
+const webhookMaxReplayBlocks = 10000
+
+type webhooksController struct {
+	webhooksService *services.WebhookService
+	indexer         *services.IndexerService
+}
+
+type RegisterWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Addresses  []string `json:"addresses"`
+	// Secret is generated when empty, it is only returned on registration
+	Secret string `json:"secret"`
+}
+
+type ReplayWebhookRequest struct {
+	FromHeight uint64 `json:"from_height"`
+	ToHeight   uint64 `json:"to_height"`
+}
+
+type ReplayWebhookResponse struct {
+	Queued int `json:"queued"`
+}
+
+func NewWebhooks(w *services.WebhookService, indexer *services.IndexerService) *webhooksController {
+	return &webhooksController{w, indexer}
+}
+
+// HandleRegisterWebhook registers a webhook and returns it with its secret, which cannot be read again
+func (c *webhooksController) HandleRegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	body := &RegisterWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	var addresses []flow.Address
+	for _, addressHex := range body.Addresses {
+		address, err := parseAddress(addressHex)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s: %q", err, addressHex), http.StatusBadRequest)
+			return
+		}
+		addresses = append(addresses, address)
+	}
+
+	webhook, err := c.webhooksService.Register(body.URL, body.EventTypes, addresses, body.Secret)
+	if errors.Is(err, services.ErrInvalidWebhookURL) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error registering webhook")
+		http.Error(w, "error registering webhook", http.StatusInternalServerError)
+		return
+	}
+
+	services.Logger(r.Context()).Info().Str("webhook_id", webhook.ID).Str("url", webhook.URL).Msg("registered webhook")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// HandleListWebhooks returns every webhook, without their secrets
+func (c *webhooksController) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.webhooksService.List())
+}
+
+// HandleDeleteWebhook removes a webhook and its dead letters
+func (c *webhooksController) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.webhooksService.Delete(id)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error deleting webhook")
+		http.Error(w, "error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+
+	services.Logger(r.Context()).Info().Str("webhook_id", id).Msg("deleted webhook")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListDeadLetters returns the deliveries to a webhook that were given up on
+func (c *webhooksController) HandleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := c.webhooksService.DeadLetters(mux.Vars(r)["id"])
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error listing dead letters")
+		http.Error(w, "error listing dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// HandleReplayDeadLetter delivers a dead letter again
+func (c *webhooksController) HandleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := c.webhooksService.ReplayDeadLetter(vars["id"], vars["deliveryID"])
+	if errors.Is(err, services.ErrDeliveryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error replaying dead letter")
+		http.Error(w, "error replaying dead letter", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(&ReplayWebhookResponse{1})
+}
+
+// HandleReplayWebhook fetches the events of a height range again and delivers the ones matching the webhook
+func (c *webhooksController) HandleReplayWebhook(w http.ResponseWriter, r *http.Request) {
+	body := &ReplayWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if body.ToHeight < body.FromHeight || body.ToHeight-body.FromHeight >= webhookMaxReplayBlocks {
+		http.Error(w, fmt.Sprintf("invalid range: to_height must be at least from_height, and at most %d blocks after it", webhookMaxReplayBlocks-1), http.StatusBadRequest)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if _, err := c.webhooksService.Get(id); errors.Is(err, services.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	events, err := c.indexer.Events(r.Context(), body.FromHeight, body.ToHeight)
+	if err != nil {
+		handleReadError(r.Context(), w, err, "error fetching events")
+		return
+	}
+
+	queued, err := c.webhooksService.ReplayEvents(id, events)
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		services.Logger(r.Context()).Error().Err(err).Msg("error replaying events")
+		http.Error(w, "error replaying events", http.StatusInternalServerError)
+		return
+	}
+
+	services.Logger(r.Context()).Info().Str("webhook_id", id).Int("queued", queued).
+		Uint64("from_height", body.FromHeight).Uint64("to_height", body.ToHeight).Msg("replaying webhook events")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(&ReplayWebhookResponse{queued})
+}
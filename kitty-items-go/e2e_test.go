@@ -0,0 +1,691 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	sdktemplates "github.com/onflow/flow-go-sdk/templates"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// e2eTimeout bounds every wait for a seal, an event or a webhook delivery
+const e2eTimeout = 30 * time.Second
+
+// TestAPI boots the router against an in-process emulator with the contracts deployed and goes through every
+// endpoint, checking the state read back from the chain and the events streamed for each transaction
+func TestAPI(t *testing.T) {
+	e, err := startEmulator()
+	require.NoError(t, err)
+	defer e.stop()
+
+	conf := defaultConfig()
+	e.configure(&conf)
+	conf.APIKeysFile = filepath.Join(t.TempDir(), "api_keys.json")
+	conf.WebhooksFile = filepath.Join(t.TempDir(), "webhooks.json")
+	conf.IndexerCursorFile = filepath.Join(t.TempDir(), "indexer_cursor.json")
+	conf.IndexerPollInterval = 100 * time.Millisecond
+	conf.AccessNodeCheckInterval = time.Second
+	conf.LogLevel = "warn"
+	require.NoError(t, conf.Validate())
+	require.NoError(t, conf.Compute())
+	log.Logger = conf.Logger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := newApp(ctx, conf)
+	require.NoError(t, err)
+	defer a.close()
+	a.start(ctx)
+
+	server := httptest.NewServer(a.router)
+	defer server.Close()
+
+	key, _, err := a.apiKeys.Create([]services.APIKeyScope{
+		services.ScopeAdmin, services.ScopeRead, services.ScopeMintKibble, services.ScopeMintItem,
+	})
+	require.NoError(t, err)
+	api := &apiClient{t: t, url: server.URL, key: key}
+	// The stream is closed first, the server waits for its connections to close
+	streamCtx, closeStream := context.WithCancel(ctx)
+	defer closeStream()
+	events := api.stream(streamCtx, "")
+
+	alice, err := e.CreateUser()
+	require.NoError(t, err)
+	bob, err := e.CreateUser()
+	require.NoError(t, err)
+
+	t.Run("Should serve the health endpoints", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, api.get("/healthz", nil))
+
+		// The indexer check passes once it has caught up with the emulator
+		deadline := time.Now().Add(e2eTimeout)
+		for api.get("/readyz", nil) != http.StatusOK {
+			require.True(t, time.Now().Before(deadline), "not ready in time")
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+
+	t.Run("Should require an API key with the scope of the endpoint", func(t *testing.T) {
+		anonymous := &apiClient{t: t, url: server.URL}
+		assert.Equal(t, http.StatusUnauthorized, anonymous.get("/kibbles/balance/"+alice.Address.Hex(), nil))
+
+		readKey, _, err := a.apiKeys.Create([]services.APIKeyScope{services.ScopeRead})
+		require.NoError(t, err)
+		reader := &apiClient{t: t, url: server.URL, key: readKey}
+		assert.Equal(t, http.StatusOK, reader.get("/kibbles/balance/"+alice.Address.Hex(), nil))
+		assert.Equal(t, http.StatusForbidden, reader.post("/kibbles/new", map[string]interface{}{
+			"flow_address": alice.Address.Hex(),
+			"amount":       1,
+		}, nil))
+	})
+
+	t.Run("Should mint Kibble", func(t *testing.T) {
+		balanceBefore, heightBefore := api.balanceAt(alice.Address, "")
+		require.Equal(t, "0.00000000", balanceBefore)
+
+		transactionID := api.submit("/kibbles/new", map[string]interface{}{
+			"flow_address": alice.Address.Hex(),
+			"amount":       100,
+		})
+		events.requireSealed(t, transactionID)
+
+		deposited := events.require(t, "Kibble.TokensDeposited", func(event indexedEvent) bool {
+			return event.TransactionID == transactionID
+		})
+		assert.Equal(t, alice.Address.Hex(), deposited.field("to"))
+		assert.Equal(t, "100.00000000", deposited.field("amount"))
+
+		assert.Equal(t, "100.00000000", api.balance(alice.Address))
+		assert.Equal(t, "999900.00000000", api.allowance())
+
+		// Reads at a height see the state of that block
+		balance, height := api.balanceAt(alice.Address, fmt.Sprint(heightBefore))
+		assert.Equal(t, "0.00000000", balance)
+		assert.Equal(t, heightBefore, height)
+		balance, _ = api.balanceAt(alice.Address, fmt.Sprint(deposited.BlockHeight))
+		assert.Equal(t, "100.00000000", balance)
+		var allowance struct {
+			AllowedAmount string `json:"allowed_amount"`
+			Height        uint64 `json:"height"`
+		}
+		require.Equal(t, http.StatusOK, api.get(fmt.Sprintf("/admin/minter?at=%d", heightBefore), &allowance))
+		assert.Equal(t, "1000000.00000000", allowance.AllowedAmount)
+		assert.Equal(t, heightBefore, allowance.Height)
+		assert.Equal(t, http.StatusBadRequest, api.get("/kibbles/balance/"+alice.Address.Hex()+"?at=latest", nil))
+	})
+
+	t.Run("Should top up the minter", func(t *testing.T) {
+		transactionID := api.submit("/admin/minter/top-up", map[string]interface{}{"amount": "50.0"})
+		events.requireSealed(t, transactionID)
+
+		assert.Equal(t, "999950.00000000", api.allowance())
+	})
+
+	t.Run("Should fail to provision a second minter", func(t *testing.T) {
+		transactionID := api.submit("/admin/minter", map[string]interface{}{"amount": "5.0"})
+		update := events.requireTransaction(t, transactionID)
+		assert.Contains(t, update.Error, "A minter already exists")
+
+		assert.Equal(t, "999950.00000000", api.allowance())
+	})
+
+	t.Run("Should read transferred Kibble", func(t *testing.T) {
+		require.NoError(t, e.TransferKibble(alice, bob.Address, ufix64(t, "30.0")))
+
+		deposited := events.require(t, "Kibble.TokensDeposited", func(event indexedEvent) bool {
+			return event.field("to") == bob.Address.Hex()
+		})
+		assert.Equal(t, "30.00000000", deposited.field("amount"))
+
+		assert.Equal(t, "70.00000000", api.balance(alice.Address))
+		assert.Equal(t, "30.00000000", api.balance(bob.Address))
+	})
+
+	var itemID uint64
+
+	t.Run("Should mint a kitty item", func(t *testing.T) {
+		transactionID := api.submit("/kitty-items/mint", map[string]interface{}{
+			"flow_address": alice.Address.Hex(),
+			"type_id":      7,
+		})
+		events.requireSealed(t, transactionID)
+
+		minted := events.require(t, "KittyItems.Minted", func(event indexedEvent) bool {
+			return event.TransactionID == transactionID
+		})
+		assert.Equal(t, "7", minted.field("typeID"))
+		deposit := events.require(t, "KittyItems.Deposit", func(event indexedEvent) bool {
+			return event.TransactionID == transactionID
+		})
+		assert.Equal(t, alice.Address.Hex(), deposit.field("to"))
+		assert.Equal(t, minted.field("id"), deposit.field("id"))
+
+		itemID = minted.uint64Field(t, "id")
+		assert.Equal(t, []uint64{itemID}, api.ids("/kitty-items/collection/"+alice.Address.Hex()))
+	})
+
+	t.Run("Should read the market flows", func(t *testing.T) {
+		require.NoError(t, e.ListItem(alice, itemID, ufix64(t, "20.0")))
+
+		created := events.require(t, "KittyItemsMarket.SaleOfferCreated", func(event indexedEvent) bool {
+			return event.field("itemID") == fmt.Sprint(itemID)
+		})
+		assert.Equal(t, "20.00000000", created.field("price"))
+
+		assert.Equal(t, []uint64{itemID}, api.ids("/market/collection/"+alice.Address.Hex()))
+		offerPath := fmt.Sprintf("/market/collection/%s/offers/%d", alice.Address.Hex(), itemID)
+		var offer struct {
+			SaleCompleted bool   `json:"sale_completed"`
+			ItemID        uint64 `json:"item_id"`
+			Price         string `json:"price"`
+		}
+		require.Equal(t, http.StatusOK, api.get(offerPath, &offer))
+		assert.False(t, offer.SaleCompleted)
+		assert.Equal(t, itemID, offer.ItemID)
+		assert.Equal(t, "20.00000000", offer.Price)
+
+		require.NoError(t, e.BuyItem(bob, alice.Address, itemID))
+
+		events.require(t, "KittyItemsMarket.SaleOfferAccepted", func(event indexedEvent) bool {
+			return event.field("itemID") == fmt.Sprint(itemID)
+		})
+		events.require(t, "KittyItems.Deposit", func(event indexedEvent) bool {
+			return event.field("to") == bob.Address.Hex()
+		})
+
+		assert.Empty(t, api.ids("/kitty-items/collection/"+alice.Address.Hex()))
+		assert.Equal(t, []uint64{itemID}, api.ids("/kitty-items/collection/"+bob.Address.Hex()))
+		assert.Empty(t, api.ids("/market/collection/"+alice.Address.Hex()))
+		assert.Equal(t, http.StatusNotFound, api.get(offerPath, nil))
+
+		assert.Equal(t, "90.00000000", api.balance(alice.Address))
+		assert.Equal(t, "10.00000000", api.balance(bob.Address))
+	})
+
+	t.Run("Should deliver events to webhooks", func(t *testing.T) {
+		deliveries := make(chan *http.Request, 10)
+		bodies := make(chan []byte, 10)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			deliveries <- r
+			bodies <- body
+		}))
+		defer receiver.Close()
+
+		var webhook services.Webhook
+		require.Equal(t, http.StatusCreated, api.post("/admin/webhooks", map[string]interface{}{
+			"url":         receiver.URL,
+			"event_types": []string{"Kibble.TokensDeposited"},
+			"addresses":   []string{bob.Address.Hex()},
+		}, &webhook))
+		require.NotEmpty(t, webhook.Secret)
+
+		transactionID := api.submit("/kibbles/new", map[string]interface{}{
+			"flow_address": bob.Address.Hex(),
+			"amount":       5,
+		})
+		events.requireSealed(t, transactionID)
+
+		var delivery *http.Request
+		var body []byte
+		select {
+		case delivery = <-deliveries:
+			body = <-bodies
+		case <-time.After(e2eTimeout):
+			require.FailNow(t, "no webhook delivery")
+		}
+		assert.Equal(t, "Kibble.TokensDeposited", delivery.Header.Get(services.WebhookEventHeader))
+		require.NoError(t, services.VerifyWebhook(
+			webhook.Secret,
+			delivery.Header.Get(services.WebhookTimestampHeader),
+			delivery.Header.Get(services.WebhookSignatureHeader),
+			body,
+			time.Minute,
+		))
+
+		var payload struct {
+			WebhookID string       `json:"webhook_id"`
+			Event     indexedEvent `json:"event"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, webhook.ID, payload.WebhookID)
+		assert.Equal(t, transactionID, payload.Event.TransactionID)
+		assert.Equal(t, bob.Address.Hex(), payload.Event.field("to"))
+
+		var webhooks []services.Webhook
+		require.Equal(t, http.StatusOK, api.get("/admin/webhooks", &webhooks))
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, webhook.ID, webhooks[0].ID)
+
+		assert.Equal(t, http.StatusNoContent, api.do(http.MethodDelete, "/admin/webhooks/"+webhook.ID, nil, nil))
+		webhooks = nil
+		require.Equal(t, http.StatusOK, api.get("/admin/webhooks", &webhooks))
+		assert.Empty(t, webhooks)
+	})
+
+	t.Run("Should keep the deliveries a webhook rejects as dead letters and replay them", func(t *testing.T) {
+		statuses := make(chan int, 1)
+		statuses <- http.StatusBadRequest
+		deliveries := make(chan string, 10)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deliveries <- r.Header.Get(services.WebhookDeliveryHeader)
+			select {
+			case status := <-statuses:
+				w.WriteHeader(status)
+			default:
+			}
+		}))
+		defer receiver.Close()
+		awaitDelivery := func() string {
+			select {
+			case id := <-deliveries:
+				return id
+			case <-time.After(e2eTimeout):
+				require.FailNow(t, "no webhook delivery")
+				return ""
+			}
+		}
+
+		var webhook services.Webhook
+		require.Equal(t, http.StatusCreated, api.post("/admin/webhooks", map[string]interface{}{
+			"url":         receiver.URL,
+			"event_types": []string{"Kibble.TokensDeposited"},
+			"addresses":   []string{alice.Address.Hex()},
+		}, &webhook))
+		defer api.do(http.MethodDelete, "/admin/webhooks/"+webhook.ID, nil, nil)
+
+		transactionID := api.submit("/kibbles/new", map[string]interface{}{
+			"flow_address": alice.Address.Hex(),
+			"amount":       1,
+		})
+		deposited := events.require(t, "Kibble.TokensDeposited", func(event indexedEvent) bool {
+			return event.TransactionID == transactionID
+		})
+		rejectedID := awaitDelivery()
+
+		// The rejection is not retried, the delivery is a dead letter right away
+		deadLettersPath := "/admin/webhooks/" + webhook.ID + "/dead-letters"
+		var deadLetters []services.WebhookDelivery
+		deadline := time.Now().Add(e2eTimeout)
+		for len(deadLetters) == 0 {
+			require.True(t, time.Now().Before(deadline), "no dead letter in time")
+			time.Sleep(50 * time.Millisecond)
+			require.Equal(t, http.StatusOK, api.get(deadLettersPath, &deadLetters))
+		}
+		require.Len(t, deadLetters, 1)
+		assert.Equal(t, rejectedID, deadLetters[0].ID)
+		assert.Equal(t, 1, deadLetters[0].Attempts)
+		assert.Equal(t, transactionID, deadLetters[0].Event.TransactionID)
+		assert.Equal(t, http.StatusNotFound, api.get("/admin/webhooks/unknown/dead-letters", nil))
+
+		var replay struct {
+			Queued int `json:"queued"`
+		}
+		require.Equal(t, http.StatusAccepted, api.post(deadLettersPath+"/"+rejectedID+"/replay", nil, &replay))
+		assert.Equal(t, 1, replay.Queued)
+		assert.Equal(t, rejectedID, awaitDelivery())
+		require.Equal(t, http.StatusOK, api.get(deadLettersPath, &deadLetters))
+		assert.Empty(t, deadLetters)
+		assert.Equal(t, http.StatusNotFound, api.post(deadLettersPath+"/"+rejectedID+"/replay", nil, nil))
+
+		// A replay of the heights fetches the events again and delivers them as new deliveries
+		require.Equal(t, http.StatusAccepted, api.post("/admin/webhooks/"+webhook.ID+"/replay", map[string]interface{}{
+			"from_height": deposited.BlockHeight,
+			"to_height":   deposited.BlockHeight,
+		}, &replay))
+		assert.Equal(t, 1, replay.Queued)
+		assert.NotEqual(t, rejectedID, awaitDelivery())
+	})
+
+	t.Run("Should resume the event stream after the last event received", func(t *testing.T) {
+		firstCtx, closeFirst := context.WithCancel(ctx)
+		defer closeFirst()
+		first := api.stream(firstCtx, "")
+		transactionID := api.submit("/kibbles/new", map[string]interface{}{
+			"flow_address": bob.Address.Hex(),
+			"amount":       1,
+		})
+		first.requireSealed(t, transactionID)
+		require.NotEmpty(t, first.lastID)
+		closeFirst()
+
+		missedID := api.submit("/kibbles/new", map[string]interface{}{
+			"flow_address": bob.Address.Hex(),
+			"amount":       1,
+		})
+		events.requireSealed(t, missedID)
+
+		resumedCtx, closeResumed := context.WithCancel(ctx)
+		defer closeResumed()
+		resumed := api.stream(resumedCtx, first.lastID)
+		resumed.requireSealed(t, missedID)
+		resumed.require(t, "Kibble.TokensDeposited", func(event indexedEvent) bool {
+			return event.TransactionID == missedID
+		})
+		for _, event := range resumed.skipped {
+			assert.NotEqual(t, "reset", event.Name, "no event was missed")
+			assert.NotContains(t, string(event.Data), transactionID, "events received before are not sent again")
+		}
+	})
+
+	t.Run("Should mint Kibble with the faucet", func(t *testing.T) {
+		// The faucet server proposes with a key of its own, its sequence numbers are not shared with the first server
+		faucetKey := flow.NewAccountKey().
+			FromPrivateKey(e.Minter.PrivateKey).
+			SetHashAlgo(e.Minter.Key.HashAlgo).
+			SetWeight(flow.AccountKeyWeightThreshold)
+		_, err := e.Submit(sdktemplates.AddAccountKey(e.Minter.Address, faucetKey), e.Minter)
+		require.NoError(t, err)
+
+		faucetConf := conf
+		faucetConf.MinterAccountKeyIndex = e.Minter.Key.Index + 1
+		faucetConf.APIKeysFile = filepath.Join(t.TempDir(), "api_keys.json")
+		faucetConf.WebhooksFile = filepath.Join(t.TempDir(), "webhooks.json")
+		faucetConf.IndexerEnabled = false
+		faucetConf.FaucetMode = true
+		faucetConf.FaucetAmount = 10
+		faucetConf.FaucetDifficulty = 8
+		require.NoError(t, faucetConf.Validate())
+
+		faucetCtx, stopFaucet := context.WithCancel(ctx)
+		defer stopFaucet()
+		faucetApp, err := newApp(faucetCtx, faucetConf)
+		require.NoError(t, err)
+		defer faucetApp.close()
+		faucetApp.start(faucetCtx)
+		faucetServer := httptest.NewServer(faucetApp.router)
+		defer faucetServer.Close()
+		faucet := &apiClient{t: t, url: faucetServer.URL}
+
+		carol, err := e.CreateUser()
+		require.NoError(t, err)
+
+		var challenge services.Challenge
+		require.Equal(t, http.StatusOK, faucet.post("/kibbles/challenge", map[string]interface{}{"flow_address": carol.Address.Hex()}, &challenge))
+		assert.Equal(t, uint(8), challenge.Difficulty)
+
+		transactionID := faucet.submit("/kibbles/new", map[string]interface{}{
+			"flow_address": carol.Address.Hex(),
+			"challenge":    challenge.Challenge,
+			"nonce":        solveChallenge(t, challenge, carol.Address),
+		})
+		deposited := events.require(t, "Kibble.TokensDeposited", func(event indexedEvent) bool {
+			return event.TransactionID == transactionID
+		})
+		assert.Equal(t, carol.Address.Hex(), deposited.field("to"))
+		assert.Equal(t, "10.00000000", api.balance(carol.Address))
+
+		// The address cools down once served
+		assert.Equal(t, http.StatusTooManyRequests, faucet.post("/kibbles/challenge", map[string]interface{}{"flow_address": carol.Address.Hex()}, nil))
+	})
+
+	t.Run("Should serve metrics", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, api.get("/metrics", nil))
+	})
+}
+
+// solveChallenge finds a nonce solving the faucet proof of work for address
+func solveChallenge(t *testing.T, challenge services.Challenge, address flow.Address) string {
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		sum := sha256.Sum256([]byte(challenge.Challenge + address.Hex() + nonce))
+
+		var zeros uint
+		for _, b := range sum {
+			if b != 0 {
+				zeros += uint(bits.LeadingZeros8(b))
+				break
+			}
+			zeros += 8
+		}
+		if zeros >= challenge.Difficulty {
+			return nonce
+		}
+	}
+	require.FailNow(t, "no nonce found")
+	return ""
+}
+
+func ufix64(t *testing.T, s string) cadence.UFix64 {
+	value, err := cadence.NewUFix64(s)
+	require.NoError(t, err)
+	return value
+}
+
+// apiClient calls the API with an API key, failing the test on transport errors
+type apiClient struct {
+	t   *testing.T
+	url string
+	key string
+}
+
+// do sends body as JSON and decodes the response into out when the status is 2xx, it returns the status
+func (c *apiClient) do(method, path string, body, out interface{}) int {
+	var reader bytes.Buffer
+	if body != nil {
+		require.NoError(c.t, json.NewEncoder(&reader).Encode(body))
+	}
+	request, err := http.NewRequest(method, c.url+path, &reader)
+	require.NoError(c.t, err)
+	if c.key != "" {
+		request.Header.Set("Authorization", "Bearer "+c.key)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(c.t, err)
+	defer response.Body.Close()
+
+	if out != nil && response.StatusCode/100 == 2 {
+		require.NoError(c.t, json.NewDecoder(response.Body).Decode(out))
+	}
+	return response.StatusCode
+}
+
+func (c *apiClient) get(path string, out interface{}) int {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *apiClient) post(path string, body, out interface{}) int {
+	return c.do(http.MethodPost, path, body, out)
+}
+
+// submit posts to an endpoint sending a transaction and returns the transaction ID
+func (c *apiClient) submit(path string, body interface{}) string {
+	var response struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	require.Equal(c.t, http.StatusOK, c.post(path, body, &response))
+	require.NotEmpty(c.t, response.TransactionID)
+	return response.TransactionID
+}
+
+func (c *apiClient) balance(address flow.Address) string {
+	balance, _ := c.balanceAt(address, "")
+	return balance
+}
+
+// balanceAt reads the Kibble balance of address at the block given by at, the latest when empty,
+// and returns it with the height it was read at
+func (c *apiClient) balanceAt(address flow.Address, at string) (string, uint64) {
+	path := "/kibbles/balance/" + address.Hex()
+	if at != "" {
+		path += "?at=" + at
+	}
+
+	var response struct {
+		Balance string `json:"balance"`
+		Height  uint64 `json:"height"`
+	}
+	require.Equal(c.t, http.StatusOK, c.get(path, &response))
+	return response.Balance, response.Height
+}
+
+func (c *apiClient) allowance() string {
+	var response struct {
+		AllowedAmount string `json:"allowed_amount"`
+	}
+	require.Equal(c.t, http.StatusOK, c.get("/admin/minter", &response))
+	return response.AllowedAmount
+}
+
+func (c *apiClient) ids(path string) []uint64 {
+	var response struct {
+		IDs []uint64 `json:"ids"`
+	}
+	require.Equal(c.t, http.StatusOK, c.get(path, &response))
+	return response.IDs
+}
+
+// stream subscribes to every event of /events/stream until ctx is done, resuming after lastEventID if any
+func (c *apiClient) stream(ctx context.Context, lastEventID string) *eventStream {
+	request, err := http.NewRequest(http.MethodGet, c.url+"/events/stream", nil)
+	require.NoError(c.t, err)
+	request = request.WithContext(ctx)
+	request.Header.Set("Authorization", "Bearer "+c.key)
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(c.t, err)
+	require.Equal(c.t, http.StatusOK, response.StatusCode)
+
+	s := &eventStream{events: make(chan streamEvent, 1000)}
+	go func() {
+		defer response.Body.Close()
+		defer close(s.events)
+
+		var event streamEvent
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.Name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.Data = []byte(strings.TrimPrefix(line, "data: "))
+			case line == "" && event.Name != "":
+				s.events <- event
+				event = streamEvent{}
+			}
+		}
+	}()
+
+	return s
+}
+
+type streamEvent struct {
+	ID   string
+	Name string
+	Data []byte
+}
+
+// eventStream reads the events of a stream. Events skipped while waiting for one are kept for the next waits,
+// as the events of a transaction and its seal are streamed in no particular order.
+type eventStream struct {
+	events  chan streamEvent
+	skipped []streamEvent
+	// lastID is the ID of the last event received, to resume after
+	lastID string
+}
+
+// indexedEvent is an on-chain event as streamed and delivered to webhooks
+type indexedEvent struct {
+	BlockHeight   uint64                 `json:"block_height"`
+	TransactionID string                 `json:"transaction_id"`
+	Fields        map[string]interface{} `json:"fields"`
+}
+
+func (e indexedEvent) field(name string) string {
+	if value, ok := e.Fields[name]; ok {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+func (e indexedEvent) uint64Field(t *testing.T, name string) uint64 {
+	var value uint64
+	_, err := fmt.Sscan(e.field(name), &value)
+	require.NoError(t, err)
+	return value
+}
+
+// next removes the first event named name whose data decoded into out satisfies match
+func (s *eventStream) next(t *testing.T, name string, out interface{}, match func() bool) {
+	matches := func(event streamEvent) bool {
+		if event.Name != name {
+			return false
+		}
+		require.NoError(t, json.Unmarshal(event.Data, out))
+		return match()
+	}
+
+	for i, event := range s.skipped {
+		if matches(event) {
+			s.skipped = append(s.skipped[:i], s.skipped[i+1:]...)
+			return
+		}
+	}
+
+	timeout := time.After(e2eTimeout)
+	for {
+		select {
+		case event, ok := <-s.events:
+			require.True(t, ok, "stream closed while waiting for %s", name)
+			if event.ID != "" {
+				s.lastID = event.ID
+			}
+			if matches(event) {
+				return
+			}
+			s.skipped = append(s.skipped, event)
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for "+name)
+		}
+	}
+}
+
+// require waits for the next on-chain event of the short type name satisfying match
+func (s *eventStream) require(t *testing.T, name string, match func(indexedEvent) bool) indexedEvent {
+	var event indexedEvent
+	s.next(t, name, &event, func() bool { return match(event) })
+	return event
+}
+
+// requireTransaction waits for transactionID to be sealed or to fail
+func (s *eventStream) requireTransaction(t *testing.T, transactionID string) services.TransactionUpdate {
+	var update services.TransactionUpdate
+	s.next(t, services.StreamTransactionType, &update, func() bool {
+		return update.TransactionID == transactionID && (update.Status == "SEALED" || update.Error != "")
+	})
+	return update
+}
+
+// requireSealed waits for transactionID to be sealed without error
+func (s *eventStream) requireSealed(t *testing.T, transactionID string) {
+	update := s.requireTransaction(t, transactionID)
+	require.Empty(t, update.Error)
+	require.Equal(t, "SEALED", update.Status)
+}
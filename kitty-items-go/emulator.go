@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-emulator/server"
+	"github.com/onflow/flow-emulator/server/backend"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// emulatorMinterAllowance is how much Kibble the Minter stored in the emulator minter account may mint
+	emulatorMinterAllowance = "1000000.0"
+	// emulatorMinterFunds is the FLOW given to the emulator minter account, enough for the readiness check
+	emulatorMinterFunds = "1000.0"
+)
+
+// localEmulator is the in-process emulator of emulator mode, with its access API served on a local port
+type localEmulator struct {
+	*kittyitems.Emulator
+	// Minter holds every contract and a Minter
+	Minter kittyitems.Account
+	// Address is where the access API is served
+	Address string
+
+	grpcServer *server.GRPCServer
+}
+
+// LoadEmulatorConfig starts a localEmulator and loads the configuration like LoadConfig, with the network,
+// accounts and keys sections pointing at the emulator, which runs until stop is called
+func LoadEmulatorConfig(path string) (conf Config, stop func(), err error) {
+	conf, err = readConfig(path)
+	if err != nil {
+		return conf, nil, err
+	}
+
+	e, err := startEmulator()
+	if err != nil {
+		return conf, nil, err
+	}
+	e.configure(&conf)
+
+	if err := conf.Validate(); err != nil {
+		e.stop()
+		return conf, nil, err
+	}
+	if err := conf.Compute(); err != nil {
+		e.stop()
+		return conf, nil, err
+	}
+
+	return conf, e.stop, nil
+}
+
+// startEmulator starts an emulator with every contract deployed to a new minter account, which also stores
+// a Minter and holds some FLOW. The access API is served on a local port and every transaction sent to it is
+// committed in a block of its own.
+func startEmulator() (*localEmulator, error) {
+	allowance, err := cadence.NewUFix64(emulatorMinterAllowance)
+	if err != nil {
+		return nil, err
+	}
+	funds, err := cadence.NewUFix64(emulatorMinterFunds)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := kittyitems.NewEmulator()
+	if err != nil {
+		return nil, err
+	}
+
+	minter, err := e.DeployContractsToAccount()
+	if err != nil {
+		return nil, fmt.Errorf("error deploying contracts to the emulator = %w", err)
+	}
+	tx, err := e.Contracts.KibbleCreateMinter(minter.Address, allowance)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.Submit(tx, minter); err != nil {
+		return nil, fmt.Errorf("error creating the emulator minter = %w", err)
+	}
+	if err := e.FundAccount(minter.Address, funds); err != nil {
+		return nil, err
+	}
+
+	// The emulator backend and access API only take a logrus logger, so it cannot share ours.
+	// Only its warnings are logged, they are not structured like ours.
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	b := backend.New(logger, e.Blockchain)
+	b.EnableAutoMine()
+	grpcServer := server.NewGRPCServer(logger, b, 0, false)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error listening for the emulator access API = %w", err)
+	}
+	go func() {
+		if err := grpcServer.Server().Serve(listener); err != nil {
+			logger.WithError(err).Error("emulator access API stopped")
+		}
+	}()
+
+	return &localEmulator{
+		Emulator:   e,
+		Minter:     minter,
+		Address:    listener.Addr().String(),
+		grpcServer: grpcServer,
+	}, nil
+}
+
+// configure points the network, accounts and keys sections of conf at the emulator
+func (e *localEmulator) configure(conf *Config) {
+	conf.FlowNode = e.Address
+	conf.AccessNodes = nil
+	conf.FungibleTokenAddressHex = e.Contracts.FungibleToken.Hex()
+	conf.NonFungibleTokenAddressHex = e.Contracts.NonFungibleToken.Hex()
+	// The contracts addresses default to the minter address
+	conf.KibbleAddressHex = ""
+	conf.KittyItemsAddressHex = ""
+	conf.KittyItemsMarketAddressHex = ""
+	conf.MinterFlowAddressHex = e.Minter.Address.Hex()
+	conf.MinterPrivateKeyHex = hex.EncodeToString(e.Minter.PrivateKey.Encode())
+	conf.MinterSigAlgoName = e.Minter.PrivateKey.Algorithm().String()
+	conf.MinterHashAlgoName = e.Minter.Key.HashAlgo.String()
+	conf.MinterAccountKeyIndex = e.Minter.Key.Index
+	// Every emulator starts a new chain, a saved height would not be part of it
+	conf.IndexerCursorFile = ""
+}
+
+// stop stops serving the access API
+func (e *localEmulator) stop() {
+	e.grpcServer.Stop()
+}
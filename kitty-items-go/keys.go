@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+)
+
+const keysUsage = `usage: kitty-items-go keys <command> [flags]
+
+commands:
+  create -scopes mint:kibble,read   create a new API key and print it once
+  revoke <id>                       revoke the API key with the given id
+  list                              list all API keys
+
+The keys are stored in http.api_keys_file of the configuration, unless -file is given.`
+
+// runKeysCommand implements the `keys` admin subcommand used to create, revoke and list API keys
+func runKeysCommand(args []string) error {
+	fs := flag.NewFlagSet("keys", flag.ExitOnError)
+	configPath := fs.String("config", envOrDefault("KITTY_ITEMS_CONFIG", ""), "path to the YAML configuration file")
+	file := fs.String("file", "", "path to the API keys file, overrides the configuration")
+	scopes := fs.String("scopes", "", "comma separated scopes for `create`: "+scopeNames())
+
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+	command := args[0]
+
+	// Flags may come after the id of `revoke`, the flag package stops parsing at the first other argument
+	var positional []string
+	for rest := args[1:]; ; rest = fs.Args()[1:] {
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+	}
+	if (command == "revoke") != (len(positional) == 1) || len(positional) > 1 {
+		return errors.New(keysUsage)
+	}
+
+	if *file == "" {
+		conf, err := readConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		*file = conf.APIKeysFile
+	}
+
+	apiKeys, err := services.NewAPIKeys(*file)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "create":
+		parsed, err := services.ParseScopes(*scopes)
+		if err != nil {
+			return err
+		}
+		plaintext, key, err := apiKeys.Create(parsed)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created api key id=%s scopes=%s\n", key.ID, joinScopes(key.Scopes))
+		fmt.Printf("%s\n", plaintext)
+		fmt.Fprintln(os.Stderr, "store this key now, it cannot be displayed again")
+	case "revoke":
+		if err := apiKeys.Revoke(positional[0]); err != nil {
+			return err
+		}
+		fmt.Printf("revoked api key id=%s\n", positional[0])
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSCOPES\tCREATED\tREVOKED")
+		for _, key := range apiKeys.List() {
+			revoked := "-"
+			if key.RevokedAt != nil {
+				revoked = key.RevokedAt.Format("2006-01-02T15:04:05Z")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key.ID, joinScopes(key.Scopes), key.CreatedAt.Format("2006-01-02T15:04:05Z"), revoked)
+		}
+		return w.Flush()
+	default:
+		return errors.New(keysUsage)
+	}
+
+	return nil
+}
+
+func envOrDefault(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+func joinScopes(scopes []services.APIKeyScope) string {
+	names := make([]string, len(scopes))
+	for i, s := range scopes {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ",")
+}
+
+func scopeNames() string {
+	return joinScopes(services.AllScopes)
+}
@@ -2,61 +2,147 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/dapperlabs/kitty-items-go/controllers"
-	"github.com/dapperlabs/kitty-items-go/services"
-	"github.com/gorilla/mux"
-	"github.com/kelseyhightower/envconfig"
-	"github.com/onflow/flow-go-sdk/client"
-	"github.com/onflow/flow-go-sdk/crypto"
-	"google.golang.org/grpc"
+	"github.com/rs/zerolog/log"
 )
 
+const usage = `usage: kitty-items-go [-config file] [-emulator]
+       kitty-items-go <command> [arguments]
+
+Without a command, the API server is started.
+
+commands:
+  keys       create, revoke and list API keys
+  config     validate the configuration
+  webhooks   run a local webhook receiver`
+
 func main() {
-	var conf Config
+	// Flags go to the server, anything else must be a command: a typo must not start the server
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		if err := runServer(); err != nil {
+			log.Fatal().Err(err).Msg("server stopped")
+		}
+		return
+	}
 
-	// Parse environment variables with `KITTY_ITEMS` prefix into the Config struct
-	if err := envconfig.Process("KITTY_ITEMS", &conf); err != nil {
-		log.Fatalf("error parsing configuration = %s", err)
+	var err error
+	switch os.Args[1] {
+	case "keys":
+		err = runKeysCommand(os.Args[2:])
+	case "config":
+		err = runConfigCommand(os.Args[2:])
+	case "webhooks":
+		err = runWebhooksCommand(os.Args[2:])
+	case "help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+}
 
-	// Further process the environment variables so we can have more specific types and values on Config
-	if err := conf.Compute(); err != nil {
-		log.Fatalf("error processing configuration = %s", err)
+// runServer serves the API until it is signaled to stop or the server fails, then shuts down gracefully.
+// Either way, it returns once the in-flight requests and submitted transactions are done or the shutdown timeout elapses.
+func runServer() error {
+	configPath := flag.String("config", os.Getenv("KITTY_ITEMS_CONFIG"), "path to the YAML configuration file")
+	emulatorMode := flag.Bool("emulator", false, "run an in-process emulator with the contracts deployed, for local development")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q\n\n%s\n", flag.Args(), usage)
+		os.Exit(2)
 	}
 
-	flowClient, err := client.New(conf.FlowNode, grpc.WithInsecure())
+	// Load the configuration file, if any, with `KITTY_ITEMS` environment variables taking precedence
+	var (
+		conf         Config
+		stopEmulator = func() {}
+		err          error
+	)
+	if *emulatorMode {
+		conf, stopEmulator, err = LoadEmulatorConfig(*configPath)
+	} else {
+		conf, err = LoadConfig(*configPath)
+	}
 	if err != nil {
-		log.Fatalf("error connecting to flow node = %s", err)
+		return fmt.Errorf("error loading configuration = %w", err)
+	}
+	defer stopEmulator()
+
+	log.Logger = conf.Logger()
+
+	if *emulatorMode {
+		log.Info().Str("access_node", conf.FlowNode).Str("minter_address", conf.MinterFlowAddressHex).Msg("started emulator")
 	}
-	defer flowClient.Close()
 
 	ctx := context.Background()
 
-	// Retrieve the Flow Account with our configured minter address so we can create a transaction signer for it
-	minterAccount, err := flowClient.GetAccount(ctx, conf.MinterFlowAddress)
+	shutdownTracing, err := setupTracing(ctx, conf)
 	if err != nil {
-		log.Fatalf("error retrieving minter account = %s", err)
+		return fmt.Errorf("error setting up tracing = %w", err)
 	}
 
-	log.Printf("Minter Account = %+v", minterAccount.Address)
+	a, err := newApp(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("error starting services = %w", err)
+	}
+	defer a.close()
+
+	monitorCtx, stopMonitor := context.WithCancel(ctx)
+	defer stopMonitor()
+	a.start(monitorCtx)
+
+	server := &http.Server{Addr: conf.ListenAddress, Handler: a.router}
+	// Streams never finish on their own, end them so shutdown only waits for the other requests
+	server.RegisterOnShutdown(a.stream.Close)
 
-	minterAccountKey := minterAccount.Keys[conf.MinterAccountKeyIndex]
-	signer := crypto.NewInMemorySigner(conf.MinterPrivateKey, minterAccountKey.HashAlgo)
+	serverErrors := make(chan error, 1)
+	go func() {
+		log.Info().Str("listen_address", conf.ListenAddress).Msg("listening")
+		serverErrors <- server.ListenAndServe()
+	}()
 
-	// Instantiate our internal services
-	flowService := services.NewFlow(flowClient, signer, conf.MinterFlowAddress, minterAccountKey)
-	kibblesService := services.NewKibbles(flowService)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
-	r := mux.NewRouter()
+	// A server that failed still shuts down gracefully, the transactions it submitted are drained
+	var serverErr error
+	select {
+	case serverErr = <-serverErrors:
+		serverErr = fmt.Errorf("error serving = %w", serverErr)
+		log.Error().Err(serverErr).Dur("timeout", conf.ShutdownTimeout).Msg("shutting down")
+	case sig := <-signals:
+		log.Info().Str("signal", sig.String()).Dur("timeout", conf.ShutdownTimeout).Msg("shutting down")
+	}
 
-	kibblesC := controllers.NewKibbles(kibblesService)
-	r.HandleFunc("/kibbles/new", kibblesC.HandleMintKibbles).Methods(http.MethodPost)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
 
-	log.Printf("listening port on 8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatalf("error starting server = %s", err)
+	// Stop accepting connections and let in-flight requests finish, so no mint is cut off mid-submission
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("error waiting for in-flight requests")
 	}
+	// Stop the background work, the indexer saves its cursor as it returns
+	stopMonitor()
+	a.wait()
+
+	// Submitted transactions get the rest of the deadline to seal, the ones still pending are logged
+	a.flowService.Drain(shutdownCtx)
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("error flushing traces")
+	}
+
+	log.Info().Msg("shutdown complete")
+	return serverErr
 }
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+)
+
+// RequireScope only lets requests through when they carry a valid API key granted the given scope.
+// The key is read from the `Authorization: Bearer <key>` header and stored in the request context.
+func RequireScope(apiKeys *services.APIKeysService, scope services.APIKeyScope) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if !strings.HasPrefix(header, "Bearer ") {
+				http.Error(w, "missing api key", http.StatusUnauthorized)
+				return
+			}
+
+			key, err := apiKeys.Authenticate(strings.TrimPrefix(header, "Bearer "))
+			if err != nil {
+				if !errors.Is(err, services.ErrInvalidAPIKey) {
+					services.Logger(r.Context()).Error().Err(err).Msg("error authenticating api key")
+				}
+				http.Error(w, "invalid api key", http.StatusUnauthorized)
+				return
+			}
+
+			if !key.HasScope(scope) {
+				http.Error(w, "api key is missing scope "+string(scope), http.StatusForbidden)
+				return
+			}
+
+			ctx := services.WithLogFields(r.Context(), map[string]interface{}{"api_key_id": key.ID})
+			next.ServeHTTP(w, r.WithContext(services.WithAPIKey(ctx, key)))
+		})
+	}
+}
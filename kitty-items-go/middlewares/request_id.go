@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the request IDs accepted from clients to something safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID tags every request with an ID, taken from the X-Request-ID header when the client sent a valid one.
+// The ID is returned in the response headers and added to the logger carried by the request context,
+// so every line logged while serving the request can be correlated. Each request is logged once it completes.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := services.WithLogFields(r.Context(), map[string]interface{}{"request_id": requestID})
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		services.Logger(ctx).Info().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", recorder.status).
+			Dur("duration", time.Since(start)).
+			Msg("request served")
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs sends the global logger to a buffer until the test ends and returns a function decoding the lines
+func captureLogs(t *testing.T) func() []map[string]interface{} {
+	var buffer bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buffer)
+	t.Cleanup(func() { log.Logger = previous })
+
+	return func() []map[string]interface{} {
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(&buffer)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		return lines
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	cases := []struct {
+		name string
+		// header is the X-Request-ID sent by the client, kept is whether it is used as the request ID
+		header string
+		kept   bool
+	}{
+		{"Should generate an ID when the client sends none", "", false},
+		{"Should keep the ID sent by the client", "trace-1234:abc", true},
+		{"Should replace an ID that is not safe to log", "bad id\n", false},
+		{"Should replace an ID that is too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				services.Logger(r.Context()).Info().Msg("handling")
+				w.WriteHeader(http.StatusTeapot)
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/kibbles/balance/01", nil)
+			if c.header != "" {
+				request.Header.Set(RequestIDHeader, c.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if c.kept {
+				assert.Equal(t, c.header, requestID)
+			} else {
+				assert.Regexp(t, generated, requestID)
+			}
+
+			lines := logs()
+			require.Len(t, lines, 2)
+			assert.Equal(t, "handling", lines[0]["message"])
+			assert.Equal(t, "request served", lines[1]["message"])
+			for _, line := range lines {
+				assert.Equal(t, requestID, line["request_id"], "every line of the request carries its ID")
+			}
+			assert.Equal(t, "/kibbles/balance/01", lines[1]["path"])
+			assert.Equal(t, float64(http.StatusTeapot), lines[1]["status"])
+		})
+	}
+
+	t.Run("Should give every request its own ID", func(t *testing.T) {
+		captureLogs(t)
+		handler := RequestID(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+		ids := make(map[string]bool)
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			ids[w.Header().Get(RequestIDHeader)] = true
+		}
+		assert.Len(t, ids, 3)
+	})
+}
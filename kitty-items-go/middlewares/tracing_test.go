@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording every span and W3C trace context propagation until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTracing(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	cases := []struct {
+		name   string
+		status int
+		// traceparent is the trace context sent by the caller, if any
+		traceparent string
+		spanStatus  codes.Code
+	}{
+		{"Should emit a server span for a request", http.StatusOK, "", codes.Unset},
+		{"Should continue the trace of the caller", http.StatusOK, traceparent, codes.Unset},
+		{"Should not mark a client error as a failed span", http.StatusNotFound, "", codes.Unset},
+		{"Should mark a server error as a failed span", http.StatusInternalServerError, "", codes.Error},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			logs := captureLogs(t)
+
+			r := mux.NewRouter()
+			r.Use(Tracing)
+			r.HandleFunc("/kibbles/balance/{address}", func(w http.ResponseWriter, r *http.Request) {
+				services.Logger(r.Context()).Info().Msg("handling")
+				w.WriteHeader(c.status)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/kibbles/balance/01", nil)
+			if c.traceparent != "" {
+				request.Header.Set("traceparent", c.traceparent)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), request)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, "GET /kibbles/balance/{address}", span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Contains(t, span.Attributes(), attribute.String("http.route", "/kibbles/balance/{address}"))
+			assert.Contains(t, span.Attributes(), attribute.String("http.target", "/kibbles/balance/01"))
+			assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", c.status))
+			assert.Equal(t, c.spanStatus, span.Status().Code)
+
+			if c.traceparent != "" {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+				assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+			} else {
+				assert.False(t, span.Parent().IsValid())
+			}
+
+			lines := logs()
+			require.Len(t, lines, 1)
+			assert.Equal(t, span.SpanContext().TraceID().String(), lines[0]["trace_id"], "the logs can be joined to the trace")
+		})
+	}
+}
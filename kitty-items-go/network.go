@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+	"github.com/onflow/flow-go-sdk/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// AccessNodeConfigs returns the configured access nodes, or the single insecure FlowNode when none are listed
+func (c *Config) AccessNodeConfigs() []AccessNodeConfig {
+	if len(c.AccessNodes) > 0 {
+		return c.AccessNodes
+	}
+	return []AccessNodeConfig{{Address: c.FlowNode}}
+}
+
+// dialAccessNodes connects to every configured access node, with TLS where enabled
+func dialAccessNodes(conf Config, opts ...grpc.DialOption) (*services.AccessNodePool, error) {
+	var nodes []*services.AccessNode
+	for _, nodeConf := range conf.AccessNodeConfigs() {
+		credentialsOpt, err := transportCredentials(nodeConf)
+		if err != nil {
+			return nil, err
+		}
+
+		flowClient, err := client.New(nodeConf.Address, append([]grpc.DialOption{credentialsOpt}, opts...)...)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to access node %s = %w", nodeConf.Address, err)
+		}
+
+		nodes = append(nodes, services.NewAccessNode(nodeConf.Address, flowClient))
+	}
+
+	return services.NewAccessNodePool(nodes, conf.AccessNodeMaxLag), nil
+}
+
+func transportCredentials(nodeConf AccessNodeConfig) (grpc.DialOption, error) {
+	if !nodeConf.TLS {
+		return grpc.WithInsecure(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: nodeConf.TLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if nodeConf.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(nodeConf.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file of access node %s = %w", nodeConf.Address, err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in CA file of access node " + nodeConf.Address)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
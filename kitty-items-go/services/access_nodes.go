@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onflow/flow-go-sdk/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoAccessNode is returned when every access node failed with a transport error
+var ErrNoAccessNode = errors.New("no access node available")
+
+// AccessNode is an access node endpoint with the health observed by the last check
+type AccessNode struct {
+	Address string
+	client  *client.Client
+
+	mu        sync.RWMutex
+	healthy   bool
+	height    uint64
+	latency   time.Duration
+	lastError error
+	checkedAt time.Time
+}
+
+// AccessNodeStatus is a snapshot of the health of an access node
+type AccessNodeStatus struct {
+	Address   string    `json:"address"`
+	Healthy   bool      `json:"healthy"`
+	Height    uint64    `json:"sealed_height"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// NewAccessNode wraps the client connected to address. Nodes are assumed healthy until checked.
+func NewAccessNode(address string, client *client.Client) *AccessNode {
+	return &AccessNode{Address: address, client: client, healthy: true}
+}
+
+func (n *AccessNode) status() AccessNodeStatus {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	s := AccessNodeStatus{
+		Address:   n.Address,
+		Healthy:   n.healthy,
+		Height:    n.height,
+		LatencyMs: n.latency.Milliseconds(),
+		CheckedAt: n.checkedAt,
+	}
+	if n.lastError != nil {
+		s.Error = n.lastError.Error()
+	}
+	return s
+}
+
+func (n *AccessNode) markUnhealthy(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.healthy = false
+	n.lastError = err
+	accessNodeHealthy.WithLabelValues(n.Address).Set(0)
+}
+
+// AccessNodePool spreads reads over the healthy access nodes that are close to the highest sealed height
+// and fails over to the other nodes when a call fails with a transport error
+type AccessNodePool struct {
+	nodes  []*AccessNode
+	maxLag uint64
+	next   uint32
+}
+
+// NewAccessNodePool builds a pool over nodes. Nodes more than maxLag blocks behind the most advanced one
+// only serve calls once the others failed.
+func NewAccessNodePool(nodes []*AccessNode, maxLag uint64) *AccessNodePool {
+	return &AccessNodePool{nodes: nodes, maxLag: maxLag}
+}
+
+// Check fetches the latest sealed height of every node concurrently and updates their health
+func (p *AccessNodePool) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, node := range p.nodes {
+		wg.Add(1)
+		go func(node *AccessNode) {
+			defer wg.Done()
+
+			start := time.Now()
+			header, err := node.client.GetLatestBlockHeader(ctx, true)
+			latency := time.Since(start)
+
+			node.mu.Lock()
+			defer node.mu.Unlock()
+
+			node.checkedAt = time.Now()
+			node.latency = latency
+			node.lastError = observeRPC("GetLatestBlockHeader", err)
+			node.healthy = err == nil
+			if err != nil {
+				accessNodeHealthy.WithLabelValues(node.Address).Set(0)
+				return
+			}
+			node.height = header.Height
+			accessNodeHealthy.WithLabelValues(node.Address).Set(1)
+			accessNodeHeight.WithLabelValues(node.Address).Set(float64(header.Height))
+		}(node)
+	}
+	wg.Wait()
+}
+
+// Monitor checks the nodes every interval until ctx is done, each check bounded by the interval
+func (p *AccessNodePool) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		p.Check(checkCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the health of every node
+func (p *AccessNodePool) Status() []AccessNodeStatus {
+	statuses := make([]AccessNodeStatus, len(p.nodes))
+	for i, node := range p.nodes {
+		statuses[i] = node.status()
+	}
+	return statuses
+}
+
+// Healthy reports whether at least one node passed its last check
+func (p *AccessNodePool) Healthy() bool {
+	for _, node := range p.nodes {
+		if node.status().Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// Read calls fn with the clients of the nodes in turn, starting with the next node in round-robin order
+// among the healthy nodes close to the highest height, until a call does not fail with a transport error
+func (p *AccessNodePool) Read(ctx context.Context, fn func(*client.Client) error) error {
+	return p.do(ctx, p.ordered(true), fn)
+}
+
+// Submit is like Read but always starts with the most advanced healthy node. Resubmitting a signed
+// transaction to another node after a transport error is safe, it has the same ID and can only execute once.
+func (p *AccessNodePool) Submit(ctx context.Context, fn func(*client.Client) error) error {
+	return p.do(ctx, p.ordered(false), fn)
+}
+
+// Close closes the connections to every node
+func (p *AccessNodePool) Close() error {
+	var firstErr error
+	for _, node := range p.nodes {
+		if err := node.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *AccessNodePool) do(ctx context.Context, nodes []*AccessNode, fn func(*client.Client) error) error {
+	err := ErrNoAccessNode
+	for i, node := range nodes {
+		if i > 0 {
+			accessNodeFailovers.Inc()
+			Logger(ctx).Warn().Err(err).Str("access_node", node.Address).Msg("failing over to the next access node")
+		}
+
+		err = fn(node.client)
+		if !isTransportError(ctx, err) {
+			return err
+		}
+		node.markUnhealthy(err)
+	}
+	return err
+}
+
+// ordered returns the nodes in the order they should be tried: healthy nodes within maxLag blocks of the
+// highest height first, rotated when balance is set, then the other healthy nodes, then the unhealthy ones
+func (p *AccessNodePool) ordered(balance bool) []*AccessNode {
+	statuses := make([]AccessNodeStatus, len(p.nodes))
+	var best uint64
+	for i, node := range p.nodes {
+		statuses[i] = node.status()
+		if statuses[i].Healthy && statuses[i].Height > best {
+			best = statuses[i].Height
+		}
+	}
+
+	var current, behind, unhealthy []int
+	for i, s := range statuses {
+		switch {
+		case !s.Healthy:
+			unhealthy = append(unhealthy, i)
+		case s.Height+p.maxLag >= best:
+			current = append(current, i)
+		default:
+			behind = append(behind, i)
+		}
+	}
+
+	byHeight := func(indexes []int) {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return statuses[indexes[a]].Height > statuses[indexes[b]].Height
+		})
+	}
+	byHeight(current)
+	byHeight(behind)
+
+	if balance && len(current) > 1 {
+		offset := int(atomic.AddUint32(&p.next, 1) % uint32(len(current)))
+		rotated := make([]int, 0, len(current))
+		current = append(append(rotated, current[offset:]...), current[:offset]...)
+	}
+
+	ordered := make([]*AccessNode, 0, len(p.nodes))
+	for _, group := range [][]int{current, behind, unhealthy} {
+		for _, i := range group {
+			ordered = append(ordered, p.nodes[i])
+		}
+	}
+	return ordered
+}
+
+// isTransportError reports whether err means the node could not be reached, rather than a rejected call
+func isTransportError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/onflow/flow-go-sdk/client"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubAccessAPI answers Ping with err and GetLatestBlockHeader with height or err, counting the pings.
+// The other methods of the access API are not implemented.
+type stubAccessAPI struct {
+	access.AccessAPIClient
+	height uint64
+	err    error
+	pings  int32
+}
+
+func (s *stubAccessAPI) Ping(context.Context, *access.PingRequest, ...grpc.CallOption) (*access.PingResponse, error) {
+	atomic.AddInt32(&s.pings, 1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &access.PingResponse{}, nil
+}
+
+func (s *stubAccessAPI) GetLatestBlockHeader(context.Context, *access.GetLatestBlockHeaderRequest, ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &access.BlockHeaderResponse{Block: &entities.BlockHeader{Height: s.height}}, nil
+}
+
+// newTestAccessNodes returns a node per stub, named after its position
+func newTestAccessNodes(stubs ...*stubAccessAPI) []*AccessNode {
+	nodes := make([]*AccessNode, len(stubs))
+	for i, stub := range stubs {
+		nodes[i] = NewAccessNode(fmt.Sprintf("node-%d", i), client.NewFromRPCClient(stub))
+	}
+	return nodes
+}
+
+// setStatus sets the health observed by the last check of a node
+func setStatus(node *AccessNode, healthy bool, height uint64) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.healthy, node.height = healthy, height
+}
+
+func nodeAddresses(nodes []*AccessNode) []string {
+	addresses := make([]string, len(nodes))
+	for i, node := range nodes {
+		addresses[i] = node.Address
+	}
+	return addresses
+}
+
+func TestAccessNodePoolOrdered(t *testing.T) {
+	type nodeStatus struct {
+		healthy bool
+		height  uint64
+	}
+
+	cases := []struct {
+		name     string
+		statuses []nodeStatus
+		expected []string
+	}{
+		{
+			name:     "Should order the nodes within the lag by height",
+			statuses: []nodeStatus{{true, 10}, {true, 12}, {true, 11}},
+			expected: []string{"node-1", "node-2", "node-0"},
+		},
+		{
+			name:     "Should try the nodes behind after the ones within the lag",
+			statuses: []nodeStatus{{true, 90}, {true, 100}, {true, 96}},
+			expected: []string{"node-1", "node-2", "node-0"},
+		},
+		{
+			name:     "Should try the unhealthy nodes last, whatever their height",
+			statuses: []nodeStatus{{false, 200}, {true, 100}, {true, 90}},
+			expected: []string{"node-1", "node-2", "node-0"},
+		},
+		{
+			name:     "Should keep the order of the nodes at the same height",
+			statuses: []nodeStatus{{true, 100}, {false, 0}, {true, 100}, {false, 0}},
+			expected: []string{"node-0", "node-2", "node-1", "node-3"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nodes := newTestAccessNodes(make([]*stubAccessAPI, len(c.statuses))...)
+			for i, s := range c.statuses {
+				setStatus(nodes[i], s.healthy, s.height)
+			}
+			pool := NewAccessNodePool(nodes, 5)
+
+			assert.Equal(t, c.expected, nodeAddresses(pool.ordered(false)))
+		})
+	}
+
+	t.Run("Should spread reads over the nodes within the lag in turn", func(t *testing.T) {
+		nodes := newTestAccessNodes(make([]*stubAccessAPI, 4)...)
+		setStatus(nodes[0], true, 100)
+		setStatus(nodes[1], true, 98)
+		setStatus(nodes[2], true, 80)
+		setStatus(nodes[3], true, 97)
+		pool := NewAccessNodePool(nodes, 5)
+
+		first := make(map[string]int)
+		for i := 0; i < 6; i++ {
+			ordered := nodeAddresses(pool.ordered(true))
+			assert.ElementsMatch(t, []string{"node-0", "node-1", "node-3"}, ordered[:3])
+			assert.Equal(t, "node-2", ordered[3], "the node behind is tried last")
+			first[ordered[0]]++
+		}
+		assert.Equal(t, map[string]int{"node-0": 2, "node-1": 2, "node-3": 2}, first)
+	})
+}
+
+func TestAccessNodePoolFailover(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		// failover is whether the call moves on to the second node, marking the first unhealthy
+		failover bool
+	}{
+		{"Should fail over when a node is unavailable", status.Error(codes.Unavailable, "connection refused"), true},
+		{"Should fail over when a call times out", status.Error(codes.DeadlineExceeded, "deadline exceeded"), true},
+		{"Should fail over when a call is cancelled by the node", status.Error(codes.Canceled, "cancelled"), true},
+		{"Should not fail over when the node rejects the call", status.Error(codes.InvalidArgument, "invalid"), false},
+		{"Should not fail over when the node does not find the data", status.Error(codes.NotFound, "not found"), false},
+		{"Should not fail over on other errors", errors.New("decoding failed"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			failing, other := &stubAccessAPI{err: c.err}, &stubAccessAPI{}
+			nodes := newTestAccessNodes(failing, other)
+			setStatus(nodes[0], true, 100)
+			setStatus(nodes[1], true, 99)
+			pool := NewAccessNodePool(nodes, 0)
+
+			err := pool.Submit(context.Background(), func(c *client.Client) error {
+				return c.Ping(context.Background())
+			})
+
+			assert.Equal(t, int32(1), atomic.LoadInt32(&failing.pings))
+			if c.failover {
+				assert.NoError(t, err)
+				assert.Equal(t, int32(1), atomic.LoadInt32(&other.pings))
+				assert.False(t, pool.Status()[0].Healthy)
+				assert.NotEmpty(t, pool.Status()[0].Error)
+				return
+			}
+			assert.Error(t, err)
+			assert.Equal(t, int32(0), atomic.LoadInt32(&other.pings))
+			assert.True(t, pool.Status()[0].Healthy)
+		})
+	}
+
+	t.Run("Should return the last error when every node is unavailable", func(t *testing.T) {
+		nodes := newTestAccessNodes(
+			&stubAccessAPI{err: status.Error(codes.Unavailable, "first")},
+			&stubAccessAPI{err: status.Error(codes.Unavailable, "second")},
+		)
+		pool := NewAccessNodePool(nodes, 0)
+
+		err := pool.Read(context.Background(), func(c *client.Client) error {
+			return c.Ping(context.Background())
+		})
+		require.Error(t, err)
+		assert.False(t, pool.Healthy())
+	})
+
+	t.Run("Should not fail over once the context is done", func(t *testing.T) {
+		failing, other := &stubAccessAPI{err: status.Error(codes.Canceled, "cancelled")}, &stubAccessAPI{}
+		pool := NewAccessNodePool(newTestAccessNodes(failing, other), 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := pool.Submit(ctx, func(c *client.Client) error {
+			return c.Ping(ctx)
+		})
+		assert.Error(t, err)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&other.pings))
+		assert.True(t, pool.Status()[0].Healthy, "the node is not to blame")
+	})
+
+	t.Run("Should report no access node without nodes", func(t *testing.T) {
+		err := NewAccessNodePool(nil, 0).Read(context.Background(), func(*client.Client) error { return nil })
+		assert.True(t, errors.Is(err, ErrNoAccessNode))
+	})
+}
+
+func TestAccessNodePoolCheck(t *testing.T) {
+	nodes := newTestAccessNodes(
+		&stubAccessAPI{height: 100},
+		&stubAccessAPI{height: 80},
+		&stubAccessAPI{err: status.Error(codes.Unavailable, "connection refused")},
+		&stubAccessAPI{height: 98},
+	)
+	pool := NewAccessNodePool(nodes, 5)
+	// Unhealthy nodes recover once a check reaches them
+	setStatus(nodes[0], false, 0)
+
+	pool.Check(context.Background())
+
+	statuses := pool.Status()
+	for i, expected := range []struct {
+		healthy bool
+		height  uint64
+	}{{true, 100}, {true, 80}, {false, 0}, {true, 98}} {
+		assert.Equal(t, expected.healthy, statuses[i].Healthy, statuses[i].Address)
+		assert.Equal(t, expected.height, statuses[i].Height, statuses[i].Address)
+		assert.False(t, statuses[i].CheckedAt.IsZero(), statuses[i].Address)
+	}
+	assert.Contains(t, statuses[2].Error, "connection refused")
+	assert.True(t, pool.Healthy())
+
+	// The lagging node and the unreachable one are only tried once the others failed
+	assert.Equal(t, []string{"node-0", "node-3", "node-1", "node-2"}, nodeAddresses(pool.ordered(false)))
+}
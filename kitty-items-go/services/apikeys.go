@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type APIKeyScope string
+
+const (
+	ScopeMintKibble  APIKeyScope = "mint:kibble"
+	ScopeMintItem    APIKeyScope = "mint:item"
+	ScopeMarketWrite APIKeyScope = "market:write"
+	ScopeRead        APIKeyScope = "read"
+	ScopeAdmin       APIKeyScope = "admin"
+)
+
+// AllScopes lists every scope an API key can be granted
+var AllScopes = []APIKeyScope{ScopeMintKibble, ScopeMintItem, ScopeMarketWrite, ScopeRead, ScopeAdmin}
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey is the stored representation of a key. Only the SHA-256 hash of the secret is kept.
+type APIKey struct {
+	ID        string        `json:"id"`
+	Hash      string        `json:"hash"`
+	Scopes    []APIKeyScope `json:"scopes"`
+	CreatedAt time.Time     `json:"created_at"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted the given scope
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeysService keeps API keys in a local JSON file. The file is re-read whenever it changes on disk,
+// so keys created or revoked with the admin CLI take effect without restarting the server.
+type APIKeysService struct {
+	path    string
+	mu      sync.RWMutex
+	keys    []*APIKey
+	modTime time.Time
+}
+
+func NewAPIKeys(path string) (*APIKeysService, error) {
+	a := &APIKeysService{path: path}
+	if err := a.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ParseScopes converts a comma separated list of scopes, rejecting unknown values
+func ParseScopes(s string) ([]APIKeyScope, error) {
+	var scopes []APIKeyScope
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		scope := APIKeyScope(name)
+		known := false
+		for _, candidate := range AllScopes {
+			if scope == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown scope %q", name)
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// Create generates a new key with the given scopes and persists its hash.
+// The returned plaintext key is in the form `<id>.<secret>` and cannot be recovered later.
+func (a *APIKeysService) Create(scopes []APIKeyScope) (string, *APIKey, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := &APIKey{
+		ID:        id,
+		Hash:      hashSecret(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys = append(a.keys, key)
+	if err := a.save(); err != nil {
+		return "", nil, err
+	}
+
+	return id + "." + secret, key, nil
+}
+
+// Revoke marks the key with the given id as revoked
+func (a *APIKeysService) Revoke(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, key := range a.keys {
+		if key.ID == id {
+			if key.RevokedAt == nil {
+				now := time.Now().UTC()
+				key.RevokedAt = &now
+			}
+			return a.save()
+		}
+	}
+
+	return ErrAPIKeyNotFound
+}
+
+// List returns every stored key, including revoked ones
+func (a *APIKeysService) List() []APIKey {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(a.keys))
+	for _, key := range a.keys {
+		keys = append(keys, *key)
+	}
+	return keys
+}
+
+// Authenticate looks up a plaintext key and returns it if it exists and has not been revoked
+func (a *APIKeysService) Authenticate(plaintext string) (*APIKey, error) {
+	if err := a.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(plaintext, ".", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidAPIKey
+	}
+	id, hash := parts[0], hashSecret(parts[1])
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, key := range a.keys {
+		if key.ID != id {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 || key.RevokedAt != nil {
+			return nil, ErrInvalidAPIKey
+		}
+		return key, nil
+	}
+
+	return nil, ErrInvalidAPIKey
+}
+
+// reloadIfChanged reads the keys file again when it changed on disk. When the file is removed or can't be
+// read, every key is rejected until it can be read again, rather than keeping the keys loaded earlier.
+func (a *APIKeysService) reloadIfChanged() error {
+	info, err := os.Stat(a.path)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if os.IsNotExist(err) {
+		a.keys, a.modTime = nil, time.Time{}
+		return nil
+	}
+	if err != nil {
+		a.keys, a.modTime = nil, time.Time{}
+		return fmt.Errorf("error reading api keys file = %w", err)
+	}
+
+	if info.ModTime().Equal(a.modTime) {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		a.keys, a.modTime = nil, time.Time{}
+		return fmt.Errorf("error reading api keys file = %w", err)
+	}
+
+	var keys []*APIKey
+	if err := json.Unmarshal(contents, &keys); err != nil {
+		a.keys, a.modTime = nil, time.Time{}
+		return fmt.Errorf("error decoding api keys file = %w", err)
+	}
+
+	a.keys = keys
+	a.modTime = info.ModTime()
+
+	return nil
+}
+
+// save writes the keys to disk, must be called with the lock held
+func (a *APIKeysService) save() error {
+	contents, err := json.MarshalIndent(a.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(a.path, contents, 0600); err != nil {
+		return fmt.Errorf("error writing api keys file = %w", err)
+	}
+
+	if info, err := os.Stat(a.path); err == nil {
+		a.modTime = info.ModTime()
+	}
+
+	return nil
+}
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a copy of ctx carrying the authenticated API key
+func WithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the API key that authenticated the current request, if any
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key, ok
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
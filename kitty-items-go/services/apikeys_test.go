@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKeys(t *testing.T) (*APIKeysService, string) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	apiKeys, err := NewAPIKeys(path)
+	require.NoError(t, err)
+	return apiKeys, path
+}
+
+// touch moves the modification time of path forward, so the change is seen even within the timestamp resolution
+func touch(t *testing.T, path string) {
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func TestParseScopes(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected []APIKeyScope
+		err      bool
+	}{
+		{"Should parse a single scope", "read", []APIKeyScope{ScopeRead}, false},
+		{"Should parse a list with spaces", "mint:kibble, read", []APIKeyScope{ScopeMintKibble, ScopeRead}, false},
+		{"Should reject an unknown scope", "read,mint:everything", nil, true},
+		{"Should require a scope", " , ", nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			scopes, err := ParseScopes(c.input)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, scopes)
+		})
+	}
+}
+
+func TestAPIKeysServiceAuthenticate(t *testing.T) {
+	apiKeys, _ := newTestAPIKeys(t)
+	plaintext, key, err := apiKeys.Create([]APIKeyScope{ScopeMintKibble})
+	require.NoError(t, err)
+	revokedPlaintext, revoked, err := apiKeys.Create([]APIKeyScope{ScopeMarketWrite})
+	require.NoError(t, err)
+	require.NoError(t, apiKeys.Revoke(revoked.ID))
+
+	cases := []struct {
+		name      string
+		plaintext string
+		expected  string
+	}{
+		{"Should accept a valid key", plaintext, key.ID},
+		{"Should reject a revoked key", revokedPlaintext, ""},
+		{"Should reject a wrong secret", key.ID + ".0000", ""},
+		{"Should reject an unknown id", "unknown." + strings.SplitN(plaintext, ".", 2)[1], ""},
+		{"Should reject a key without a secret", key.ID, ""},
+		{"Should reject an empty key", "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			authenticated, err := apiKeys.Authenticate(c.plaintext)
+			if c.expected == "" {
+				assert.True(t, errors.Is(err, ErrInvalidAPIKey), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, authenticated.ID)
+			assert.True(t, authenticated.HasScope(ScopeMintKibble))
+			assert.False(t, authenticated.HasScope(ScopeMarketWrite))
+		})
+	}
+}
+
+func TestAPIKeysServiceRevoke(t *testing.T) {
+	t.Run("Should keep a revoked key in the list", func(t *testing.T) {
+		apiKeys, _ := newTestAPIKeys(t)
+		_, key, err := apiKeys.Create([]APIKeyScope{ScopeRead})
+		require.NoError(t, err)
+
+		require.NoError(t, apiKeys.Revoke(key.ID))
+		// Revoking again keeps the first revocation time
+		keys := apiKeys.List()
+		require.Len(t, keys, 1)
+		revokedAt := keys[0].RevokedAt
+		require.NotNil(t, revokedAt)
+		require.NoError(t, apiKeys.Revoke(key.ID))
+		assert.Equal(t, revokedAt, apiKeys.List()[0].RevokedAt)
+	})
+
+	t.Run("Should not revoke an unknown key", func(t *testing.T) {
+		apiKeys, _ := newTestAPIKeys(t)
+		assert.Equal(t, ErrAPIKeyNotFound, apiKeys.Revoke("unknown"))
+	})
+}
+
+func TestAPIKeysServiceReload(t *testing.T) {
+	t.Run("Should accept a key created by another process", func(t *testing.T) {
+		apiKeys, path := newTestAPIKeys(t)
+		// The admin CLI writes to the same file as the server
+		cli, err := NewAPIKeys(path)
+		require.NoError(t, err)
+		plaintext, _, err := cli.Create([]APIKeyScope{ScopeRead})
+		require.NoError(t, err)
+		touch(t, path)
+
+		_, err = apiKeys.Authenticate(plaintext)
+		assert.NoError(t, err)
+	})
+
+	cases := []struct {
+		name   string
+		change func(t *testing.T, path string)
+	}{
+		{
+			name: "Should reject every key once the file is removed",
+			change: func(t *testing.T, path string) {
+				require.NoError(t, os.Remove(path))
+			},
+		},
+		{
+			name: "Should reject every key when the file can't be decoded",
+			change: func(t *testing.T, path string) {
+				require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+				touch(t, path)
+			},
+		},
+		{
+			name: "Should reject every key when the file can't be read",
+			change: func(t *testing.T, path string) {
+				require.NoError(t, os.Remove(path))
+				require.NoError(t, os.Mkdir(path, 0700))
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			apiKeys, path := newTestAPIKeys(t)
+			plaintext, _, err := apiKeys.Create([]APIKeyScope{ScopeRead})
+			require.NoError(t, err)
+			_, err = apiKeys.Authenticate(plaintext)
+			require.NoError(t, err)
+
+			c.change(t, path)
+
+			_, err = apiKeys.Authenticate(plaintext)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+type ScriptCacheConfig struct {
+	// LatestTTL is how long a result at the latest sealed block is served before the script runs again
+	LatestTTL time.Duration
+	// MaxEntries bounds the cache, the least recently used results are evicted first
+	MaxEntries int
+}
+
+// ScriptCache executes read-only scripts and caches their results, keyed by the script hash, the arguments and
+// the block. Results at a fixed height or block ID never change and stay cached until evicted. Results at the latest
+// sealed block expire after the TTL, or earlier when an indexed event names one of the addresses they depend on.
+// Identical calls in flight at the same time share a single script execution.
+type ScriptCache struct {
+	flowService Flow
+	conf        ScriptCacheConfig
+
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	lru         *list.List
+	byAddress   map[flow.Address]map[string]struct{}
+	generations map[flow.Address]uint64
+	inflight    map[string]*scriptCall
+
+	// now is the clock the results expire with, replaced in tests
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	key       string
+	value     cadence.Value
+	height    uint64
+	expiresAt time.Time
+	addresses []flow.Address
+}
+
+type scriptCall struct {
+	done   chan struct{}
+	value  cadence.Value
+	height uint64
+	err    error
+}
+
+func NewScriptCache(flowService Flow, conf ScriptCacheConfig) *ScriptCache {
+	return &ScriptCache{
+		flowService: flowService,
+		conf:        conf,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		byAddress:   make(map[flow.Address]map[string]struct{}),
+		generations: make(map[flow.Address]uint64),
+		inflight:    make(map[string]*scriptCall),
+		now:         time.Now,
+	}
+}
+
+// ExecutorAt runs the scripts of the kittyitems bindings through Execute, storing the height they ran at in height
+func (c *ScriptCache) ExecutorAt(at BlockRef, height *uint64, addresses ...flow.Address) kittyitems.ScriptExecutor {
+	return kittyitems.ScriptExecutorFunc(func(ctx context.Context, script []byte, arguments []cadence.Value) (value cadence.Value, err error) {
+		value, *height, err = c.Execute(ctx, script, arguments, at, addresses...)
+		return value, err
+	})
+}
+
+// Execute runs script with arguments at the block referenced by at, unless the result is cached, and returns
+// the height it ran at. addresses are the accounts whose state the result depends on.
+func (c *ScriptCache) Execute(ctx context.Context, script []byte, arguments []cadence.Value, at BlockRef, addresses ...flow.Address) (cadence.Value, uint64, error) {
+	key, err := scriptCacheKey(script, arguments, at)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.mu.Lock()
+	if entry, ok := c.get(key); ok {
+		c.mu.Unlock()
+		scriptCacheRequests.WithLabelValues("hit").Inc()
+		return entry.value, entry.height, nil
+	}
+
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		scriptCacheRequests.WithLabelValues("coalesced").Inc()
+		select {
+		case <-call.done:
+			return call.value, call.height, call.err
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+
+	call := &scriptCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	generations := c.addressGenerations(addresses)
+	c.mu.Unlock()
+
+	scriptCacheRequests.WithLabelValues("miss").Inc()
+
+	// The script outlives a cancelled caller, others may be waiting for its result
+	runCtx, cancel := context.WithTimeout(detachedContext{ctx}, scriptTimeout(ctx))
+	defer cancel()
+
+	call.value, call.height, call.err = c.flowService.ExecuteScriptAt(runCtx, at, script, arguments...)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		// A result read while one of its addresses was invalidated may already be stale, don't keep it as the latest
+		if !at.IsLatest() || c.addressGenerationsEqual(addresses, generations) {
+			c.put(key, call.value, call.height, at.IsLatest(), addresses)
+		}
+		// The height the latest result ran at is known now, later reads at that height can use it too
+		if at.IsLatest() {
+			if heightKey, err := scriptCacheKey(script, arguments, AtHeight(call.height)); err == nil {
+				c.put(heightKey, call.value, call.height, false, nil)
+			}
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.value, call.height, call.err
+}
+
+// InvalidateAddress drops the results at the latest block that depend on address
+func (c *ScriptCache) InvalidateAddress(address flow.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[address]++
+	for key := range c.byAddress[address] {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+	delete(c.byAddress, address)
+}
+
+// HandleEvent invalidates the addresses named in an indexed event, it is meant to be subscribed to the indexer
+func (c *ScriptCache) HandleEvent(event IndexedEvent) {
+	for _, address := range event.Addresses {
+		c.InvalidateAddress(address)
+	}
+}
+
+// get returns the cached entry for key if it has not expired, must be called with the lock held
+func (c *ScriptCache) get(key string) (*cacheEntry, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !entry.expiresAt.IsZero() && c.now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	return entry, true
+}
+
+// put stores a result, evicting the least recently used ones over the limit, must be called with the lock held
+func (c *ScriptCache) put(key string, value cadence.Value, height uint64, latest bool, addresses []flow.Address) {
+	entry := &cacheEntry{key: key, value: value, height: height}
+	if latest {
+		entry.expiresAt = c.now().Add(c.conf.LatestTTL)
+		entry.addresses = addresses
+		for _, address := range addresses {
+			if c.byAddress[address] == nil {
+				c.byAddress[address] = make(map[string]struct{})
+			}
+			c.byAddress[address][key] = struct{}{}
+		}
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.conf.MaxEntries > 0 && c.lru.Len() > c.conf.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+
+	scriptCacheEntries.Set(float64(c.lru.Len()))
+}
+
+// remove drops an entry, must be called with the lock held
+func (c *ScriptCache) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+	for _, address := range entry.addresses {
+		delete(c.byAddress[address], entry.key)
+		if len(c.byAddress[address]) == 0 {
+			delete(c.byAddress, address)
+		}
+	}
+
+	scriptCacheEntries.Set(float64(c.lru.Len()))
+}
+
+func (c *ScriptCache) addressGenerations(addresses []flow.Address) []uint64 {
+	generations := make([]uint64, len(addresses))
+	for i, address := range addresses {
+		generations[i] = c.generations[address]
+	}
+	return generations
+}
+
+func (c *ScriptCache) addressGenerationsEqual(addresses []flow.Address, generations []uint64) bool {
+	for i, address := range addresses {
+		if c.generations[address] != generations[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scriptTimeout keeps the deadline of the caller, if any, for the detached script execution
+func scriptTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return defaultScriptTimeout
+}
+
+const defaultScriptTimeout = 30 * time.Second
+
+// detachedContext keeps the values of its parent, such as the logger and the trace, but not its cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+func scriptCacheKey(script []byte, arguments []cadence.Value, at BlockRef) (string, error) {
+	hash := sha256.New()
+	hash.Write(script)
+	for _, argument := range arguments {
+		encoded, err := jsoncdc.Encode(argument)
+		if err != nil {
+			return "", fmt.Errorf("error encoding script argument = %w", err)
+		}
+		hash.Write([]byte{0})
+		hash.Write(encoded)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)) + "@" + at.String(), nil
+}
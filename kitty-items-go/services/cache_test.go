@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCache is a ScriptCache over a FakeFlow answering the balance script, which counts how often it runs
+type testCache struct {
+	*ScriptCache
+	fake   *FakeFlow
+	clock  *testClock
+	script []byte
+	// executions counts the balance scripts run, handle is called by each of them when set
+	executions int32
+	handle     func(arguments []cadence.Value) (cadence.Value, error)
+}
+
+func newTestCache(maxEntries int) *testCache {
+	contracts := kittyitems.Contracts{FungibleToken: testFungibleTokenAddress, Kibble: testMinterAddress}
+	c := &testCache{
+		fake:   NewFakeFlow(testMinterAddress, contracts),
+		clock:  &testClock{now: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)},
+		script: contracts.Code(kittyitems.KibbleGetBalanceScript),
+	}
+	c.fake.HandleScript(kittyitems.KibbleGetBalanceScript, func(arguments []cadence.Value) (cadence.Value, error) {
+		atomic.AddInt32(&c.executions, 1)
+		if c.handle != nil {
+			return c.handle(arguments)
+		}
+		return cadence.NewUInt64(uint64(atomic.LoadInt32(&c.executions))), nil
+	})
+
+	c.ScriptCache = NewScriptCache(c.fake, ScriptCacheConfig{LatestTTL: time.Minute, MaxEntries: maxEntries})
+	c.ScriptCache.now = func() time.Time { return c.clock.now }
+	return c
+}
+
+// balance reads the balance of address at the block referenced by at, the result depending on address
+func (c *testCache) balance(t *testing.T, address flow.Address, at BlockRef) cadence.Value {
+	value, _, err := c.Execute(context.Background(), c.script, []cadence.Value{cadence.NewAddress(address)}, at, address)
+	require.NoError(t, err)
+	return value
+}
+
+func (c *testCache) assertExecutions(t *testing.T, expected int) {
+	assert.Equal(t, int32(expected), atomic.LoadInt32(&c.executions), "script executions")
+}
+
+func TestScriptCacheExecute(t *testing.T) {
+	latest := BlockRef{}
+
+	cases := []struct {
+		name string
+		// read reads from the cache, expecting the script to have run executions times in total
+		read       func(t *testing.T, c *testCache)
+		executions int
+	}{
+		{
+			name: "Should serve a result at the latest block until the TTL elapses",
+			read: func(t *testing.T, c *testCache) {
+				first := c.balance(t, testRecipientAddress, latest)
+				c.clock.now = c.clock.now.Add(time.Minute)
+				assert.Equal(t, first, c.balance(t, testRecipientAddress, latest))
+
+				c.clock.now = c.clock.now.Add(time.Second)
+				assert.NotEqual(t, first, c.balance(t, testRecipientAddress, latest))
+			},
+			executions: 2,
+		},
+		{
+			name: "Should keep a result at a fixed height",
+			read: func(t *testing.T, c *testCache) {
+				c.balance(t, testRecipientAddress, AtHeight(1))
+				c.clock.now = c.clock.now.Add(24 * time.Hour)
+				c.InvalidateAddress(testRecipientAddress)
+				c.balance(t, testRecipientAddress, AtHeight(1))
+			},
+			executions: 1,
+		},
+		{
+			name: "Should serve reads at the height a latest read ran at",
+			read: func(t *testing.T, c *testCache) {
+				c.fake.SetHeight(5)
+				value := c.balance(t, testRecipientAddress, latest)
+				assert.Equal(t, value, c.balance(t, testRecipientAddress, AtHeight(5)))
+			},
+			executions: 1,
+		},
+		{
+			name: "Should cache the results of different arguments apart",
+			read: func(t *testing.T, c *testCache) {
+				c.balance(t, testRecipientAddress, latest)
+				c.balance(t, testOtherRecipientAddress, latest)
+				c.balance(t, testRecipientAddress, latest)
+			},
+			executions: 2,
+		},
+		{
+			name: "Should drop the results at the latest block of an invalidated address",
+			read: func(t *testing.T, c *testCache) {
+				c.balance(t, testRecipientAddress, latest)
+				c.balance(t, testOtherRecipientAddress, latest)
+				c.InvalidateAddress(testRecipientAddress)
+				c.balance(t, testRecipientAddress, latest)
+				c.balance(t, testOtherRecipientAddress, latest)
+			},
+			executions: 3,
+		},
+		{
+			name: "Should drop the results of the addresses named by an event",
+			read: func(t *testing.T, c *testCache) {
+				c.balance(t, testRecipientAddress, latest)
+				c.balance(t, testOtherRecipientAddress, latest)
+				c.HandleEvent(IndexedEvent{Addresses: []flow.Address{testRecipientAddress, testOtherRecipientAddress}})
+				c.balance(t, testRecipientAddress, latest)
+				c.balance(t, testOtherRecipientAddress, latest)
+			},
+			executions: 4,
+		},
+		{
+			name: "Should not cache a latest result read while its address was invalidated",
+			read: func(t *testing.T, c *testCache) {
+				c.handle = func([]cadence.Value) (cadence.Value, error) {
+					c.handle = nil
+					c.InvalidateAddress(testRecipientAddress)
+					return cadence.NewUInt64(1), nil
+				}
+				c.balance(t, testRecipientAddress, latest)
+				c.balance(t, testRecipientAddress, latest)
+				// The result is kept for the height it ran at, which does not change
+				c.balance(t, testRecipientAddress, AtHeight(1))
+			},
+			executions: 2,
+		},
+		{
+			name: "Should not cache errors",
+			read: func(t *testing.T, c *testCache) {
+				c.handle = func([]cadence.Value) (cadence.Value, error) {
+					c.handle = nil
+					return nil, errors.New("script failed")
+				}
+				_, _, err := c.Execute(context.Background(), c.script, []cadence.Value{cadence.NewAddress(testRecipientAddress)}, latest)
+				require.Error(t, err)
+				c.balance(t, testRecipientAddress, latest)
+			},
+			executions: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cache := newTestCache(100)
+			c.read(t, cache)
+			cache.assertExecutions(t, c.executions)
+		})
+	}
+}
+
+func TestScriptCacheEviction(t *testing.T) {
+	cache := newTestCache(2)
+	cache.balance(t, testRecipientAddress, AtHeight(1))
+	cache.balance(t, testOtherRecipientAddress, AtHeight(1))
+	// Used again, the first result is now more recent than the second
+	cache.balance(t, testRecipientAddress, AtHeight(1))
+	cache.balance(t, testMinterAddress, AtHeight(1))
+	cache.assertExecutions(t, 3)
+
+	cache.balance(t, testRecipientAddress, AtHeight(1))
+	cache.assertExecutions(t, 3)
+	cache.balance(t, testOtherRecipientAddress, AtHeight(1))
+	cache.assertExecutions(t, 4)
+
+	// A latest read is kept for the latest block and for its height, evicting both older results
+	cache.balance(t, testMinterAddress, BlockRef{})
+	cache.mu.Lock()
+	assert.Len(t, cache.byAddress[testMinterAddress], 1)
+	cache.mu.Unlock()
+
+	// Evicted, the latest result no longer depends on its address
+	cache.balance(t, testRecipientAddress, AtHeight(1))
+	cache.balance(t, testOtherRecipientAddress, AtHeight(1))
+	cache.assertExecutions(t, 7)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	assert.Equal(t, 2, cache.lru.Len())
+	assert.Len(t, cache.entries, 2)
+	assert.Empty(t, cache.byAddress)
+}
+
+func TestScriptCacheCoalescing(t *testing.T) {
+	// started is closed once the first call runs the script, release lets it finish
+	setup := func() (cache *testCache, started chan struct{}, release chan struct{}) {
+		cache = newTestCache(100)
+		started, release = make(chan struct{}), make(chan struct{})
+		cache.handle = func([]cadence.Value) (cadence.Value, error) {
+			close(started)
+			<-release
+			return cadence.NewUInt64(7), nil
+		}
+		return cache, started, release
+	}
+
+	t.Run("Should share a single execution between identical calls in flight", func(t *testing.T) {
+		cache, started, release := setup()
+
+		var wg sync.WaitGroup
+		values := make([]cadence.Value, 3)
+		for i := range values {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i > 0 {
+					<-started
+				}
+				values[i] = cache.balance(t, testRecipientAddress, BlockRef{})
+			}(i)
+		}
+		<-started
+		close(release)
+		wg.Wait()
+
+		cache.assertExecutions(t, 1)
+		for _, value := range values {
+			assert.Equal(t, cadence.NewUInt64(7), value)
+		}
+	})
+
+	t.Run("Should stop waiting for a call in flight when the context is done", func(t *testing.T) {
+		cache, started, release := setup()
+
+		first := make(chan cadence.Value)
+		go func() { first <- cache.balance(t, testRecipientAddress, BlockRef{}) }()
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := cache.Execute(ctx, cache.script, []cadence.Value{cadence.NewAddress(testRecipientAddress)}, BlockRef{}, testRecipientAddress)
+		assert.True(t, errors.Is(err, context.Canceled), "got %v", err)
+
+		// The script keeps running for the first caller
+		close(release)
+		assert.Equal(t, cadence.NewUInt64(7), <-first)
+		cache.assertExecutions(t, 1)
+	})
+}
+
+func TestDetachedContext(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Minute)
+	cancel()
+
+	ctx := detachedContext{parent}
+	assert.Equal(t, "value", ctx.Value(key{}))
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	t.Run("Should keep the deadline of the caller for the script", func(t *testing.T) {
+		withDeadline, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		assert.InDelta(t, float64(time.Minute), float64(scriptTimeout(withDeadline)), float64(time.Second))
+		assert.Equal(t, defaultScriptTimeout, scriptTimeout(context.Background()))
+	})
+}
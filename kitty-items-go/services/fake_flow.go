@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/client"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+)
+
+// ErrNoScriptResult is returned by FakeFlow for the scripts no result was set for
+var ErrNoScriptResult = errors.New("no result set for script")
+
+// FakeFlow is an in-memory Flow for unit tests. It records the transactions sent instead of submitting them and
+// answers the scripts of the kittyitems bindings with the results set by the test.
+type FakeFlow struct {
+	minterAddress flow.Address
+	contracts     kittyitems.Contracts
+
+	mu           sync.Mutex
+	height       uint64
+	finalized    uint64
+	pingErr      error
+	account      *flow.Account
+	scripts      map[string]ScriptHandler
+	sendErrors   map[string]error
+	transactions []FakeTransaction
+	events       map[uint64][]flow.Event
+}
+
+// ScriptHandler answers a script run by FakeFlow with its arguments
+type ScriptHandler func(arguments []cadence.Value) (cadence.Value, error)
+
+// FakeTransaction is a transaction sent to a FakeFlow, with its decoded arguments
+type FakeTransaction struct {
+	// Name is the template name given to SendMinterTransaction
+	Name        string
+	Transaction *flow.Transaction
+	Arguments   []cadence.Value
+}
+
+// NewFakeFlow returns a FakeFlow at height 1 whose minter account holds a single key. Scripts are recognized
+// by their code with the addresses of contracts filled in, as the services build them.
+func NewFakeFlow(minterAddress flow.Address, contracts kittyitems.Contracts) *FakeFlow {
+	return &FakeFlow{
+		minterAddress: minterAddress,
+		contracts:     contracts,
+		height:        1,
+		account: &flow.Account{
+			Address: minterAddress,
+			Keys:    []*flow.AccountKey{{Index: 0, Weight: flow.AccountKeyWeightThreshold}},
+		},
+		scripts:    make(map[string]ScriptHandler),
+		sendErrors: make(map[string]error),
+		events:     make(map[uint64][]flow.Event),
+	}
+}
+
+// HandleScript has the script at path, one of the paths of the kittyitems bindings, answered by handler
+func (f *FakeFlow) HandleScript(path string, handler ScriptHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.scripts[string(f.contracts.Code(path))] = handler
+}
+
+// SetScriptResult has the script at path return value whatever its arguments
+func (f *FakeFlow) SetScriptResult(path string, value cadence.Value) {
+	f.HandleScript(path, func([]cadence.Value) (cadence.Value, error) {
+		return value, nil
+	})
+}
+
+// SetScriptError has the script at path fail with err
+func (f *FakeFlow) SetScriptError(path string, err error) {
+	f.HandleScript(path, func([]cadence.Value) (cadence.Value, error) {
+		return nil, err
+	})
+}
+
+// SetSendError has the transactions sent under the template name fail to submit with err, until it is set to nil
+func (f *FakeFlow) SetSendError(name string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.sendErrors, name)
+		return
+	}
+	f.sendErrors[name] = err
+}
+
+// SetHeight sets the height of the latest sealed block
+func (f *FakeFlow) SetHeight(height uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.height = height
+}
+
+// SetFinalizedHeight sets the height of the latest finalized block, the sealed height is used when it is lower
+func (f *FakeFlow) SetFinalizedHeight(height uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.finalized = height
+}
+
+// SetPingError has Ping fail with err, or succeed again when err is nil
+func (f *FakeFlow) SetPingError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pingErr = err
+}
+
+// SetMinterAccount replaces the minter account returned by GetMinterAccount
+func (f *FakeFlow) SetMinterAccount(account *flow.Account) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.account = account
+}
+
+// AddEvents adds events to the block at height, to be returned by GetEventsForHeightRange
+func (f *FakeFlow) AddEvents(height uint64, events ...flow.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.events[height] = append(f.events[height], events...)
+}
+
+// Transactions returns the transactions sent so far, in order
+func (f *FakeFlow) Transactions() []FakeTransaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeTransaction(nil), f.transactions...)
+}
+
+func (f *FakeFlow) MinterAddress() flow.Address {
+	return f.minterAddress
+}
+
+// SendMinterTransaction completes tx like FlowService, without signing it, and records it unless an error was set
+// for name. Every transaction gets the next sequence number, so their IDs differ.
+func (f *FakeFlow) SendMinterTransaction(_ context.Context, name string, tx *flow.Transaction) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.sendErrors[name]; err != nil {
+		return "", err
+	}
+
+	arguments := make([]cadence.Value, len(tx.Arguments))
+	for i := range tx.Arguments {
+		argument, err := tx.Argument(i)
+		if err != nil {
+			return "", fmt.Errorf("error decoding argument %d of %s = %w", i, name, err)
+		}
+		arguments[i] = argument
+	}
+
+	tx.SetProposalKey(f.minterAddress, 0, uint64(len(f.transactions))).
+		SetPayer(f.minterAddress).
+		SetReferenceBlockID(fakeBlockID(f.height))
+
+	f.transactions = append(f.transactions, FakeTransaction{Name: name, Transaction: tx, Arguments: arguments})
+
+	return tx.ID().String(), nil
+}
+
+func (f *FakeFlow) GetMinterAccount(context.Context) (*flow.Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.account, nil
+}
+
+// GetLatestBlockHeader returns the header of the block at the height set, or at the finalized height set if higher
+// when isSealed is false
+func (f *FakeFlow) GetLatestBlockHeader(_ context.Context, isSealed bool) (*flow.BlockHeader, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	height := f.height
+	if !isSealed && f.finalized > height {
+		height = f.finalized
+	}
+	return &flow.BlockHeader{ID: fakeBlockID(height), Height: height}, nil
+}
+
+// Ping fails with the error set by SetPingError, if any
+func (f *FakeFlow) Ping(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.pingErr
+}
+
+// AccessNodeStatus returns no access node, FakeFlow does not go through any
+func (f *FakeFlow) AccessNodeStatus() []AccessNodeStatus {
+	return nil
+}
+
+// ProposalKeyIndexes returns the key SendMinterTransaction proposes with, the first key of the minter account
+func (f *FakeFlow) ProposalKeyIndexes() []int {
+	return []int{0}
+}
+
+func (f *FakeFlow) GetEventsForHeightRange(_ context.Context, query client.EventRangeQuery) ([]client.BlockEvents, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if query.EndHeight > f.height {
+		return nil, fmt.Errorf("%w = %d", ErrBlockNotFound, query.EndHeight)
+	}
+
+	var blocks []client.BlockEvents
+	for height := query.StartHeight; height <= query.EndHeight; height++ {
+		block := client.BlockEvents{BlockID: fakeBlockID(height), Height: height, BlockTimestamp: time.Unix(int64(height), 0)}
+		for _, event := range f.events[height] {
+			if query.Type == "" || event.Type == query.Type {
+				block.Events = append(block.Events, event)
+			}
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// ExecuteScriptAt answers script with its handler, at the height referenced or the height set. Blocks referenced
+// by ID are always at the height set.
+func (f *FakeFlow) ExecuteScriptAt(_ context.Context, at BlockRef, script []byte, arguments ...cadence.Value) (cadence.Value, uint64, error) {
+	f.mu.Lock()
+	handler, ok := f.scripts[string(script)]
+	height := f.height
+	f.mu.Unlock()
+
+	if at.Height != nil {
+		if *at.Height > height {
+			return nil, 0, fmt.Errorf("%w = %s", ErrBlockNotFound, at)
+		}
+		height = *at.Height
+	}
+	if !ok {
+		return nil, 0, ErrNoScriptResult
+	}
+
+	value, err := handler(arguments)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return value, height, nil
+}
+
+// fakeBlockID derives a block ID from its height
+func fakeBlockID(height uint64) flow.Identifier {
+	var id flow.Identifier
+	for i := 0; i < 8; i++ {
+		id[len(id)-1-i] = byte(height >> (8 * i))
+	}
+	return id
+}
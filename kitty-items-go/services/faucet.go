@@ -0,0 +1,156 @@
+package services
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+)
+
+var (
+	ErrChallengeNotFound = errors.New("no pending challenge for this address")
+	ErrChallengeExpired  = errors.New("challenge expired")
+	ErrInvalidProof      = errors.New("invalid proof of work")
+)
+
+// CooldownError is returned when an address asks the faucet again before its cooldown elapsed
+type CooldownError struct {
+	Until time.Time
+}
+
+func (e *CooldownError) Error() string {
+	return "address is cooling down until " + e.Until.Format(time.RFC3339)
+}
+
+type FaucetConfig struct {
+	Difficulty   uint
+	ChallengeTTL time.Duration
+	Cooldown     time.Duration
+}
+
+// Challenge is a proof-of-work puzzle tied to a recipient address. The client must find a nonce such that
+// sha256(Challenge + recipient address hex without 0x + Nonce) starts with Difficulty zero bits.
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty uint      `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// FaucetService issues and verifies proof-of-work challenges and tracks per address cooldowns in memory.
+// Only the latest challenge of each address is valid and every challenge can be redeemed once.
+type FaucetService struct {
+	conf FaucetConfig
+	// now is the clock challenges expire and cooldowns elapse with, replaced in tests
+	now func() time.Time
+
+	mu         sync.Mutex
+	challenges map[flow.Address]Challenge
+	cooldowns  map[flow.Address]time.Time
+}
+
+func NewFaucet(conf FaucetConfig) *FaucetService {
+	return &FaucetService{
+		conf:       conf,
+		now:        time.Now,
+		challenges: make(map[flow.Address]Challenge),
+		cooldowns:  make(map[flow.Address]time.Time),
+	}
+}
+
+// IssueChallenge creates a new challenge for address, replacing any pending one
+func (f *FaucetService) IssueChallenge(address flow.Address) (Challenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	f.purge(now)
+
+	if until, ok := f.cooldowns[address]; ok {
+		return Challenge{}, &CooldownError{until}
+	}
+
+	value, err := randomHex(16)
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	challenge := Challenge{
+		Challenge:  value,
+		Difficulty: f.conf.Difficulty,
+		ExpiresAt:  now.Add(f.conf.ChallengeTTL).UTC(),
+	}
+	f.challenges[address] = challenge
+
+	return challenge, nil
+}
+
+// Redeem verifies the proof of work for address and consumes the challenge.
+// The address cooldown starts right away so concurrent requests with the same proof cannot both succeed.
+func (f *FaucetService) Redeem(address flow.Address, challenge, nonce string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+
+	if until, ok := f.cooldowns[address]; ok && now.Before(until) {
+		return &CooldownError{until}
+	}
+
+	pending, ok := f.challenges[address]
+	if !ok || pending.Challenge != challenge {
+		return ErrChallengeNotFound
+	}
+	if now.After(pending.ExpiresAt) {
+		delete(f.challenges, address)
+		return ErrChallengeExpired
+	}
+
+	if !verifyProof(pending.Challenge, address, nonce, pending.Difficulty) {
+		return ErrInvalidProof
+	}
+
+	delete(f.challenges, address)
+	f.cooldowns[address] = now.Add(f.conf.Cooldown)
+
+	return nil
+}
+
+// ResetCooldown lifts the cooldown of address, used when the mint following a redeemed challenge fails
+func (f *FaucetService) ResetCooldown(address flow.Address) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.cooldowns, address)
+}
+
+// purge drops expired challenges and cooldowns, must be called with the lock held
+func (f *FaucetService) purge(now time.Time) {
+	for address, challenge := range f.challenges {
+		if now.After(challenge.ExpiresAt) {
+			delete(f.challenges, address)
+		}
+	}
+	for address, until := range f.cooldowns {
+		if !now.Before(until) {
+			delete(f.cooldowns, address)
+		}
+	}
+}
+
+func verifyProof(challenge string, address flow.Address, nonce string, difficulty uint) bool {
+	sum := sha256.Sum256([]byte(challenge + address.Hex() + nonce))
+
+	var zeros uint
+	for _, b := range sum {
+		if b != 0 {
+			zeros += uint(bits.LeadingZeros8(b))
+			break
+		}
+		zeros += 8
+	}
+
+	return zeros >= difficulty
+}
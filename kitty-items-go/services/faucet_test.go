@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFaucetDifficulty = 8
+
+func newTestFaucet() (*FaucetService, *testClock) {
+	clock := &testClock{now: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)}
+	faucet := NewFaucet(FaucetConfig{Difficulty: testFaucetDifficulty, ChallengeTTL: time.Minute, Cooldown: time.Hour})
+	faucet.now = func() time.Time { return clock.now }
+	return faucet, clock
+}
+
+// solve finds a nonce proving difficulty bits of work for challenge and address, and one that does not
+func solve(t *testing.T, challenge string, address flow.Address, difficulty uint) (valid string, invalid string) {
+	for i := 0; i < 1<<20 && (valid == "" || invalid == ""); i++ {
+		nonce := strconv.Itoa(i)
+		if verifyProof(challenge, address, nonce, difficulty) {
+			if valid == "" {
+				valid = nonce
+			}
+		} else if invalid == "" {
+			invalid = nonce
+		}
+	}
+	require.NotEmpty(t, valid, "no nonce found")
+	return valid, invalid
+}
+
+func TestVerifyProof(t *testing.T) {
+	valid, invalid := solve(t, "challenge", testRecipientAddress, 12)
+
+	cases := []struct {
+		name       string
+		challenge  string
+		address    flow.Address
+		nonce      string
+		difficulty uint
+		expected   bool
+	}{
+		{"Should accept a nonce with enough leading zero bits", "challenge", testRecipientAddress, valid, 12, true},
+		{"Should accept a nonce above an easier difficulty", "challenge", testRecipientAddress, valid, 4, true},
+		{"Should reject a nonce without enough leading zero bits", "challenge", testRecipientAddress, invalid, 12, false},
+		{"Should reject a nonce found for another address", "challenge", testOtherRecipientAddress, valid, 12, false},
+		{"Should reject a nonce found for another challenge", "other", testRecipientAddress, valid, 12, false},
+		{"Should accept any nonce at difficulty 0", "challenge", testRecipientAddress, "", 0, true},
+		{"Should reject every nonce above 256 bits", "challenge", testRecipientAddress, valid, 257, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, verifyProof(c.challenge, c.address, c.nonce, c.difficulty))
+		})
+	}
+}
+
+func TestFaucetServiceRedeem(t *testing.T) {
+	cases := []struct {
+		name string
+		// redeem redeems the challenge issued to testRecipientAddress, valid being a nonce solving it
+		redeem   func(f *FaucetService, clock *testClock, challenge Challenge, valid string, invalid string) error
+		expected error
+	}{
+		{
+			name: "Should redeem a solved challenge",
+			redeem: func(f *FaucetService, _ *testClock, challenge Challenge, valid string, _ string) error {
+				return f.Redeem(testRecipientAddress, challenge.Challenge, valid)
+			},
+		},
+		{
+			name: "Should reject an invalid proof",
+			redeem: func(f *FaucetService, _ *testClock, challenge Challenge, _ string, invalid string) error {
+				return f.Redeem(testRecipientAddress, challenge.Challenge, invalid)
+			},
+			expected: ErrInvalidProof,
+		},
+		{
+			name: "Should reject a challenge issued to another address",
+			redeem: func(f *FaucetService, _ *testClock, challenge Challenge, valid string, _ string) error {
+				return f.Redeem(testOtherRecipientAddress, challenge.Challenge, valid)
+			},
+			expected: ErrChallengeNotFound,
+		},
+		{
+			name: "Should reject an expired challenge",
+			redeem: func(f *FaucetService, clock *testClock, challenge Challenge, valid string, _ string) error {
+				clock.now = clock.now.Add(2 * time.Minute)
+				return f.Redeem(testRecipientAddress, challenge.Challenge, valid)
+			},
+			expected: ErrChallengeExpired,
+		},
+		{
+			name: "Should reject a challenge replaced by a newer one",
+			redeem: func(f *FaucetService, _ *testClock, challenge Challenge, valid string, _ string) error {
+				if _, err := f.IssueChallenge(testRecipientAddress); err != nil {
+					return err
+				}
+				return f.Redeem(testRecipientAddress, challenge.Challenge, valid)
+			},
+			expected: ErrChallengeNotFound,
+		},
+		{
+			name: "Should redeem a challenge once",
+			redeem: func(f *FaucetService, _ *testClock, challenge Challenge, valid string, _ string) error {
+				if err := f.Redeem(testRecipientAddress, challenge.Challenge, valid); err != nil {
+					return err
+				}
+				f.ResetCooldown(testRecipientAddress)
+				return f.Redeem(testRecipientAddress, challenge.Challenge, valid)
+			},
+			expected: ErrChallengeNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			faucet, clock := newTestFaucet()
+			challenge, err := faucet.IssueChallenge(testRecipientAddress)
+			require.NoError(t, err)
+			assert.Equal(t, uint(testFaucetDifficulty), challenge.Difficulty)
+			assert.Equal(t, clock.now.Add(time.Minute), challenge.ExpiresAt)
+			valid, invalid := solve(t, challenge.Challenge, testRecipientAddress, challenge.Difficulty)
+
+			err = c.redeem(faucet, clock, challenge, valid, invalid)
+			if c.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, c.expected), "expected %v, got %v", c.expected, err)
+			}
+		})
+	}
+}
+
+func TestFaucetServiceCooldown(t *testing.T) {
+	redeem := func(t *testing.T, faucet *FaucetService) {
+		challenge, err := faucet.IssueChallenge(testRecipientAddress)
+		require.NoError(t, err)
+		valid, _ := solve(t, challenge.Challenge, testRecipientAddress, challenge.Difficulty)
+		require.NoError(t, faucet.Redeem(testRecipientAddress, challenge.Challenge, valid))
+	}
+
+	cases := []struct {
+		name    string
+		elapsed time.Duration
+		reset   bool
+		cooling bool
+	}{
+		{"Should not issue a challenge during the cooldown", 59 * time.Minute, false, true},
+		{"Should issue a challenge once the cooldown elapsed", time.Hour, false, false},
+		{"Should issue a challenge once the cooldown is reset", 0, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			faucet, clock := newTestFaucet()
+			redeem(t, faucet)
+			until := clock.now.Add(time.Hour)
+
+			clock.now = clock.now.Add(c.elapsed)
+			if c.reset {
+				faucet.ResetCooldown(testRecipientAddress)
+			}
+
+			_, err := faucet.IssueChallenge(testRecipientAddress)
+			if !c.cooling {
+				assert.NoError(t, err)
+				return
+			}
+			var cooldownErr *CooldownError
+			require.True(t, errors.As(err, &cooldownErr), "got %v", err)
+			assert.Equal(t, until, cooldownErr.Until)
+
+			// Other addresses are not held back
+			_, err = faucet.IssueChallenge(testOtherRecipientAddress)
+			assert.NoError(t, err)
+		})
+	}
+}
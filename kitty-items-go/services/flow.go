@@ -2,46 +2,547 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
+	"github.com/onflow/cadence"
 	"github.com/onflow/flow-go-sdk"
 	"github.com/onflow/flow-go-sdk/client"
 	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	// transactionPollInterval is how often the result of a submitted transaction is checked
+	transactionPollInterval = time.Second
+	// transactionTrackTimeout is how long a transaction is tracked before it is counted as never sealed,
+	// slightly longer than the expiry window of its reference block
+	transactionTrackTimeout = 15 * time.Minute
+)
+
+// ErrShuttingDown is returned for minter transactions sent once Drain was called, which would not be tracked
+var ErrShuttingDown = errors.New("shutting down")
+
+// ErrBlockNotFound is returned when a read targets a block the access nodes do not know, or have pruned
+var ErrBlockNotFound = errors.New("block not found")
+
+// BlockRef selects the sealed block a read executes at, by height or by ID. The zero value is the latest sealed block.
+type BlockRef struct {
+	Height *uint64
+	ID     *flow.Identifier
+}
+
+// AtHeight returns a reference to the block at height
+func AtHeight(height uint64) BlockRef {
+	return BlockRef{Height: &height}
+}
+
+// AtBlockID returns a reference to the block with the given ID
+func AtBlockID(id flow.Identifier) BlockRef {
+	return BlockRef{ID: &id}
+}
+
+// IsLatest reports whether the reference is the latest sealed block
+func (b BlockRef) IsLatest() bool {
+	return b.Height == nil && b.ID == nil
+}
+
+func (b BlockRef) String() string {
+	switch {
+	case b.Height != nil:
+		return fmt.Sprintf("%d", *b.Height)
+	case b.ID != nil:
+		return b.ID.String()
+	default:
+		return "latest"
+	}
+}
+
+// TransactionUpdate is a status of a minter transaction observed by the tracker: PENDING once submitted,
+// then FINALIZED, EXECUTED and SEALED, with Error set if it reverted, or EXPIRED if it was never sealed
+type TransactionUpdate struct {
+	TransactionID string `json:"transaction_id"`
+	Template      string `json:"template"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// transactionStatusExpired is reported for transactions that were not sealed within transactionTrackTimeout
+const transactionStatusExpired = "EXPIRED"
+
+// Flow is what the other services need from the blockchain: building, signing and sending minter transactions,
+// reading the minter account, the latest block and events, and executing scripts. FlowService implements it
+// against the access nodes, FakeFlow in memory for unit tests.
+type Flow interface {
+	// MinterAddress returns the address of the account that proposes, pays for and authorizes our transactions
+	MinterAddress() flow.Address
+	// SendMinterTransaction has the minter propose, pay for and sign tx, then submits it
+	SendMinterTransaction(ctx context.Context, name string, tx *flow.Transaction) (transactionID string, err error)
+	// GetMinterAccount fetches the current state of the minter account
+	GetMinterAccount(ctx context.Context) (*flow.Account, error)
+	// GetLatestBlockHeader returns the latest sealed or finalized block header
+	GetLatestBlockHeader(ctx context.Context, isSealed bool) (*flow.BlockHeader, error)
+	// GetEventsForHeightRange returns the events of the given type emitted in the sealed blocks of the range
+	GetEventsForHeightRange(ctx context.Context, query client.EventRangeQuery) ([]client.BlockEvents, error)
+	// ExecuteScriptAt executes a read-only script at the block referenced by at and returns the height it ran at
+	ExecuteScriptAt(ctx context.Context, at BlockRef, script []byte, arguments ...cadence.Value) (value cadence.Value, height uint64, err error)
+}
+
 type FlowService struct {
-	signer           crypto.Signer
-	minterAddress    flow.Address
-	minterAccountKey *flow.AccountKey
-	client           *client.Client
+	signer        crypto.Signer
+	minterAddress flow.Address
+	proposalKeys  *ProposalKeyPool
+	nodes         *AccessNodePool
+	// signMu serializes signing, as the in-memory signer reuses its hasher
+	signMu sync.Mutex
+
+	// pending holds the submitted transactions until they are sealed, all polled by the trackTransactions loop,
+	// and sending counts the transactions being submitted. Once draining, new transactions are rejected and
+	// the loop returns when both are empty, by which time every proposal key was released. trackingCtx stops it on shutdown.
+	pendingMu      sync.Mutex
+	pending        map[flow.Identifier]*trackedTransaction
+	sending        int
+	draining       bool
+	trackingCtx    context.Context
+	cancelTracking context.CancelFunc
+	trackingDone   chan struct{}
+
+	subscribersMu sync.RWMutex
+	subscribers   []func(TransactionUpdate)
+}
+
+// NewFlow returns a service sending the minter transactions with the given keys of the minter account, each proposing
+// a single transaction at a time. They must all be keys of the signer with full weight.
+func NewFlow(nodes *AccessNodePool, signer crypto.Signer, minterAddress flow.Address, proposalKeys []*flow.AccountKey) *FlowService {
+	trackingCtx, cancelTracking := context.WithCancel(context.Background())
+
+	f := &FlowService{
+		signer:         signer,
+		minterAddress:  minterAddress,
+		proposalKeys:   NewProposalKeyPool(proposalKeys),
+		nodes:          nodes,
+		pending:        make(map[flow.Identifier]*trackedTransaction),
+		trackingCtx:    trackingCtx,
+		cancelTracking: cancelTracking,
+		trackingDone:   make(chan struct{}),
+	}
+	go f.trackTransactions()
+
+	return f
+}
+
+// SubscribeTransactions registers fn to receive the status changes of the transactions sent by the minter.
+// Subscribers are called from the tracking loop, so they must not block.
+func (f *FlowService) SubscribeTransactions(fn func(TransactionUpdate)) {
+	f.subscribersMu.Lock()
+	defer f.subscribersMu.Unlock()
+
+	f.subscribers = append(f.subscribers, fn)
 }
 
-func NewFlow(client *client.Client, signer crypto.Signer, minterAddress flow.Address, minterAccountKey *flow.AccountKey) *FlowService {
-	return &FlowService{
-		signer:           signer,
-		minterAddress:    minterAddress,
-		minterAccountKey: minterAccountKey,
-		client:           client,
+func (f *FlowService) publishTransaction(update TransactionUpdate) {
+	f.subscribersMu.RLock()
+	defer f.subscribersMu.RUnlock()
+
+	for _, subscriber := range f.subscribers {
+		subscriber(update)
 	}
 }
 
-// Send will submit a transaction on the blockchain with the given minterAddress
-func (f *FlowService) Send(ctx context.Context, tx *flow.Transaction) (string, error) {
-	if err := tx.SignEnvelope(f.minterAddress, f.minterAccountKey.Index, f.signer); err != nil {
+// Send signs the envelope of tx with its proposal key, a key of the minter account, and submits it
+func (f *FlowService) Send(ctx context.Context, tx *flow.Transaction) (transactionID string, err error) {
+	ctx, span := startSpan(ctx, "FlowService.Send")
+	defer func() { endSpan(span, err) }()
+
+	_, signSpan := startSpan(ctx, "FlowService.SignEnvelope")
+	f.signMu.Lock()
+	err = tx.SignEnvelope(f.minterAddress, tx.ProposalKey.KeyIndex, f.signer)
+	f.signMu.Unlock()
+	endSpan(signSpan, err)
+	if err != nil {
 		return "", err
 	}
 
-	if err := f.client.SendTransaction(ctx, *tx); err != nil {
+	span.SetAttributes(attribute.String("transaction_id", tx.ID().String()))
+
+	err = f.nodes.Submit(ctx, func(c *client.Client) error {
+		return observeRPC("SendTransaction", c.SendTransaction(ctx, *tx))
+	})
+	if err != nil {
 		return "", err
 	}
 
 	return tx.ID().String(), nil
 }
 
-func (f *FlowService) GetMinterAddressSequenceNumber(ctx context.Context) (uint64, error) {
-	flowAccount, err := f.client.GetAccount(ctx, f.minterAddress)
+// refreshSequenceNumber reads the sequence number of a leased key from the minter account
+func (f *FlowService) refreshSequenceNumber(ctx context.Context, key *ProposalKey) error {
+	account, err := f.GetMinterAccount(ctx)
+	if err != nil {
+		return err
+	}
+	if key.Index >= len(account.Keys) {
+		return fmt.Errorf("minter key %d no longer exists", key.Index)
+	}
+
+	key.SequenceNumber = account.Keys[key.Index].SequenceNumber
+	return nil
+}
+
+// MinterAddress returns the address of the account that proposes, pays for and authorizes our transactions
+func (f *FlowService) MinterAddress() flow.Address {
+	return f.minterAddress
+}
+
+// SendMinterTransaction has the minter account propose and pay for tx, built by the kittyitems bindings
+// with the minter as authorizer, then signs and submits it. It waits for a free proposal key while
+// every key proposes another transaction, until ctx is done. The transaction is tracked
+// in the background until it is sealed and reported in the metrics under the template name.
+// Once Drain was called, it returns ErrShuttingDown.
+func (f *FlowService) SendMinterTransaction(ctx context.Context, name string, tx *flow.Transaction) (transactionID string, err error) {
+	ctx, span := startSpan(ctx, "FlowService.SendMinterTransaction", attribute.String("template", name))
+	defer func() { endSpan(span, err) }()
+
+	f.pendingMu.Lock()
+	if f.draining {
+		f.pendingMu.Unlock()
+		return "", ErrShuttingDown
+	}
+	f.sending++
+	f.pendingMu.Unlock()
+	defer func() {
+		f.pendingMu.Lock()
+		f.sending--
+		f.pendingMu.Unlock()
+	}()
+
+	transactionsSending.Inc()
+	defer transactionsSending.Dec()
+
+	logger := Logger(ctx).With().Str("template", name).Uint64("gas_limit", tx.GasLimit).Logger()
+
+	key, err := f.proposalKeys.Lease(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("transaction was not submitted")
+		transactionsFailed.WithLabelValues(name, "submit").Inc()
+		return "", err
+	}
+	transactionID, err = f.sendMinterTransaction(ctx, key, tx)
+	f.proposalKeys.Release(key, err == nil)
+	if err != nil {
+		logger.Warn().Err(err).Msg("transaction was not submitted")
+		transactionsFailed.WithLabelValues(name, "submit").Inc()
+		return "", err
+	}
+
+	logger = logger.With().Str("transaction_id", transactionID).Int("proposal_key", key.Index).Logger()
+	logger.Info().Msg("transaction submitted")
+
+	transactionsSubmitted.WithLabelValues(name).Inc()
+	f.publishTransaction(TransactionUpdate{TransactionID: transactionID, Template: name, Status: flow.TransactionStatusPending.String()})
+	f.pendingMu.Lock()
+	f.pending[flow.HexToID(transactionID)] = &trackedTransaction{
+		logger:      logger,
+		name:        name,
+		proposalKey: key.Index,
+		submittedAt: time.Now(),
+		lastStatus:  flow.TransactionStatusPending,
+	}
+	f.pendingMu.Unlock()
+
+	return transactionID, nil
+}
+
+func (f *FlowService) sendMinterTransaction(ctx context.Context, key *ProposalKey, tx *flow.Transaction) (string, error) {
+	if f.proposalKeys.Stale(key) {
+		if err := f.refreshSequenceNumber(ctx, key); err != nil {
+			return "", fmt.Errorf("error getting sequence number = %w", err)
+		}
+	}
+
+	var referenceBlock *flow.Block
+	err := f.nodes.Read(ctx, func(c *client.Client) (err error) {
+		referenceBlock, err = c.GetLatestBlock(ctx, true)
+		return observeRPC("GetLatestBlock", err)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting reference block = %w", err)
+	}
+
+	tx.SetProposalKey(f.minterAddress, key.Index, key.SequenceNumber).
+		SetPayer(f.minterAddress).
+		SetReferenceBlockID(referenceBlock.ID)
+
+	return f.Send(ctx, tx)
+}
+
+// trackedTransaction is a submitted transaction waiting to be sealed. It outlives the request,
+// so it keeps the request logger instead of its context.
+type trackedTransaction struct {
+	logger      zerolog.Logger
+	name        string
+	proposalKey int
+	submittedAt time.Time
+	lastStatus  flow.TransactionStatus
+}
+
+// trackTransactions polls the results of the pending transactions every transactionPollInterval, until trackingCtx
+// is done or, once draining, no transaction is left to submit or track. Each is tracked until it is sealed or transactionTrackTimeout elapses.
+func (f *FlowService) trackTransactions() {
+	defer close(f.trackingDone)
+
+	ticker := time.NewTicker(transactionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.trackingCtx.Done():
+			f.pendingMu.Lock()
+			defer f.pendingMu.Unlock()
+			for _, tx := range f.pending {
+				tx.logger.Warn().Msg("transaction still pending at shutdown")
+			}
+			return
+		case <-ticker.C:
+		}
+
+		f.pendingMu.Lock()
+		if f.draining && f.sending == 0 && len(f.pending) == 0 {
+			f.pendingMu.Unlock()
+			return
+		}
+		ids := make([]flow.Identifier, 0, len(f.pending))
+		for id := range f.pending {
+			ids = append(ids, id)
+		}
+		f.pendingMu.Unlock()
+
+		for _, id := range ids {
+			if f.trackingCtx.Err() != nil {
+				break
+			}
+			if f.pollTransaction(id) {
+				f.pendingMu.Lock()
+				delete(f.pending, id)
+				f.pendingMu.Unlock()
+			}
+		}
+	}
+}
+
+// pollTransaction checks the result of a pending transaction, publishes its status if it changed,
+// and reports whether it is done being tracked
+func (f *FlowService) pollTransaction(id flow.Identifier) (done bool) {
+	f.pendingMu.Lock()
+	tx := f.pending[id]
+	f.pendingMu.Unlock()
+
+	if time.Since(tx.submittedAt) > transactionTrackTimeout {
+		tx.logger.Error().Dur("timeout", transactionTrackTimeout).Msg("transaction was not sealed")
+		transactionsFailed.WithLabelValues(tx.name, "seal").Inc()
+		// Never executed, it did not use up its sequence number
+		f.proposalKeys.MarkStale(tx.proposalKey)
+		f.publishTransaction(TransactionUpdate{TransactionID: id.String(), Template: tx.name, Status: transactionStatusExpired})
+		return true
+	}
+
+	var result *flow.TransactionResult
+	err := f.nodes.Read(f.trackingCtx, func(c *client.Client) (err error) {
+		result, err = c.GetTransactionResult(f.trackingCtx, id)
+		return observeRPC("GetTransactionResult", err)
+	})
+	if err != nil {
+		tx.logger.Debug().Err(err).Msg("error getting transaction result")
+		return false
+	}
+	if result.Status == tx.lastStatus {
+		return false
+	}
+	tx.lastStatus = result.Status
+
+	update := TransactionUpdate{TransactionID: id.String(), Template: tx.name, Status: result.Status.String()}
+	if result.Error != nil {
+		update.Error = result.Error.Error()
+	}
+	f.publishTransaction(update)
+
+	if result.Status != flow.TransactionStatusSealed {
+		return false
+	}
+
+	if result.Error != nil {
+		tx.logger.Error().Err(result.Error).Msg("transaction failed")
+		transactionsFailed.WithLabelValues(tx.name, "execution").Inc()
+		// It may have been rejected for its sequence number, which then did not advance
+		f.proposalKeys.MarkStale(tx.proposalKey)
+		return true
+	}
+
+	transactionsSealed.WithLabelValues(tx.name).Inc()
+	transactionSealDuration.WithLabelValues(tx.name).Observe(time.Since(tx.submittedAt).Seconds())
+	tx.logger.Info().Dur("time_to_seal", time.Since(tx.submittedAt)).Msg("transaction sealed")
+	return true
+}
+
+// Drain rejects new minter transactions, then waits until the ones being submitted are tracked and every tracked
+// transaction is sealed, or ctx is done. Transactions still pending when ctx is done stop being tracked
+// and are logged with their IDs so they can be followed up.
+func (f *FlowService) Drain(ctx context.Context) {
+	f.pendingMu.Lock()
+	f.draining = true
+	f.pendingMu.Unlock()
+
+	select {
+	case <-f.trackingDone:
+	case <-ctx.Done():
+		f.cancelTracking()
+		<-f.trackingDone
+	}
+}
+
+// executorAt runs the scripts of the kittyitems bindings through f.ExecuteScriptAt, bypassing the script cache,
+// and stores the height they ran at in height
+func executorAt(f Flow, at BlockRef, height *uint64) kittyitems.ScriptExecutor {
+	return kittyitems.ScriptExecutorFunc(func(ctx context.Context, script []byte, arguments []cadence.Value) (value cadence.Value, err error) {
+		value, *height, err = f.ExecuteScriptAt(ctx, at, script, arguments...)
+		return value, err
+	})
+}
+
+// ExecuteScriptAt executes a read-only script at the block referenced by at and returns the height it ran at.
+// The block is resolved and the script executed on the same access node, so a node trailing the others
+// is never asked for a height it has not sealed yet.
+func (f *FlowService) ExecuteScriptAt(ctx context.Context, at BlockRef, script []byte, arguments ...cadence.Value) (value cadence.Value, height uint64, err error) {
+	ctx, span := startSpan(ctx, "FlowService.ExecuteScriptAt", attribute.String("block", at.String()))
+	defer func() { endSpan(span, err) }()
+
+	err = f.nodes.Read(ctx, func(c *client.Client) (err error) {
+		var header *flow.BlockHeader
+		switch {
+		case at.Height != nil:
+			height = *at.Height
+		case at.ID != nil:
+			header, err = c.GetBlockHeaderByID(ctx, *at.ID)
+			if err = observeRPC("GetBlockHeaderByID", err); err != nil {
+				return err
+			}
+			height = header.Height
+		default:
+			header, err = c.GetLatestBlockHeader(ctx, true)
+			if err = observeRPC("GetLatestBlockHeader", err); err != nil {
+				return err
+			}
+			height = header.Height
+		}
+
+		value, err = c.ExecuteScriptAtBlockHeight(ctx, height, script, arguments)
+		return observeRPC("ExecuteScriptAtBlockHeight", err)
+	})
+	if isBlockNotFound(err) {
+		return nil, 0, fmt.Errorf("%w = %s", ErrBlockNotFound, at)
+	}
+
+	return value, height, err
+}
+
+// isBlockNotFound reports whether the access node rejected a call because it does not have the block
+func isBlockNotFound(err error) bool {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.NotFound, codes.OutOfRange:
+		return true
+	}
+	return false
+}
+
+// GetEventsForHeightRange returns the events of the given type emitted in the sealed blocks of the range
+func (f *FlowService) GetEventsForHeightRange(ctx context.Context, query client.EventRangeQuery) (blocks []client.BlockEvents, err error) {
+	err = f.nodes.Read(ctx, func(c *client.Client) (err error) {
+		blocks, err = c.GetEventsForHeightRange(ctx, query)
+		return observeRPC("GetEventsForHeightRange", err)
+	})
+	return blocks, err
+}
+
+// Ping checks that the access node is reachable
+func (f *FlowService) Ping(ctx context.Context) error {
+	return f.nodes.Read(ctx, func(c *client.Client) error {
+		return observeRPC("Ping", c.Ping(ctx))
+	})
+}
+
+// GetLatestBlockHeader returns the latest sealed or finalized block header
+func (f *FlowService) GetLatestBlockHeader(ctx context.Context, isSealed bool) (*flow.BlockHeader, error) {
+	var header *flow.BlockHeader
+	err := f.nodes.Read(ctx, func(c *client.Client) (err error) {
+		header, err = c.GetLatestBlockHeader(ctx, isSealed)
+		return observeRPC("GetLatestBlockHeader", err)
+	})
+	return header, err
+}
+
+// GetMinterAccount fetches the current state of the minter account and records its balance and usable proposal keys
+func (f *FlowService) GetMinterAccount(ctx context.Context) (*flow.Account, error) {
+	var account *flow.Account
+	err := f.nodes.Read(ctx, func(c *client.Client) (err error) {
+		account, err = c.GetAccount(ctx, f.minterAddress)
+		return observeRPC("GetAccount", err)
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	minterBalance.Set(float64(account.Balance) / 1e8)
+	proposalKeys.Set(float64(f.usableProposalKeys(account)))
+
+	return account, nil
+}
+
+// usableProposalKeys counts the keys of the pool the service can still propose transactions with, those not revoked
+func (f *FlowService) usableProposalKeys(account *flow.Account) int {
+	usable := 0
+	for _, index := range f.proposalKeys.Indexes() {
+		if index < len(account.Keys) && !account.Keys[index].Revoked {
+			usable++
+		}
 	}
+	return usable
+}
+
+// MonitorMinterBalance refreshes the minter balance and proposal key metrics every interval until ctx is done
+func (f *FlowService) MonitorMinterBalance(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := f.GetMinterAccount(ctx); err != nil && ctx.Err() == nil {
+			Logger(ctx).Warn().Err(err).Msg("error refreshing minter balance")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AccessNodeStatus returns the health of every access node as last observed
+func (f *FlowService) AccessNodeStatus() []AccessNodeStatus {
+	return f.nodes.Status()
+}
 
-	return flowAccount.Keys[f.minterAccountKey.Index].SequenceNumber, nil
+// ProposalKeyIndexes returns the indexes of the minter keys used to propose and sign transactions
+func (f *FlowService) ProposalKeyIndexes() []int {
+	return f.proposalKeys.Indexes()
 }
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/client"
+	"github.com/onflow/flow-go-sdk/client/convert"
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestFlow returns a FlowService without access nodes, for what does not reach them
+func newTestFlow() *FlowService {
+	return NewFlow(nil, nil, testMinterAddress, []*flow.AccountKey{{Index: 0}})
+}
+
+func TestFlowServiceDrain(t *testing.T) {
+	t.Run("Should return once no transaction is tracked", func(t *testing.T) {
+		f := newTestFlow()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		f.Drain(ctx)
+		assert.NoError(t, ctx.Err())
+	})
+
+	t.Run("Should reject transactions sent after Drain", func(t *testing.T) {
+		f := newTestFlow()
+		f.Drain(context.Background())
+
+		_, err := f.SendMinterTransaction(context.Background(), "mint_tokens", flow.NewTransaction())
+		assert.True(t, errors.Is(err, ErrShuttingDown), "got %v", err)
+	})
+
+	t.Run("Should stop tracking the pending transactions when ctx is done", func(t *testing.T) {
+		f := newTestFlow()
+		f.pending[flow.HexToID("01")] = &trackedTransaction{logger: zerolog.Nop(), name: "mint_tokens", submittedAt: time.Now()}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		f.Drain(ctx)
+		require.Error(t, ctx.Err())
+		assert.Len(t, f.pending, 1)
+	})
+}
+
+// stubSendAPI stands in for an access node receiving the minter transactions. It records the proposal key of each
+// transaction and answers GetAccountAtLatestBlock with account, failing SendTransaction with sendErr once when set.
+type stubSendAPI struct {
+	access.AccessAPIClient
+
+	mu       sync.Mutex
+	account  *entities.Account
+	sendErr  error
+	result   *access.TransactionResultResponse
+	proposed []flow.ProposalKey
+	signedBy []int
+	// inFlight counts the transactions being submitted by key index, maxInFlight the most seen at once
+	inFlight    map[int]int
+	maxInFlight int
+}
+
+func (s *stubSendAPI) GetAccountAtLatestBlock(context.Context, *access.GetAccountAtLatestBlockRequest, ...grpc.CallOption) (*access.AccountResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &access.AccountResponse{Account: s.account}, nil
+}
+
+func (s *stubSendAPI) GetLatestBlock(context.Context, *access.GetLatestBlockRequest, ...grpc.CallOption) (*access.BlockResponse, error) {
+	return &access.BlockResponse{Block: &entities.Block{Id: flow.HexToID("0a").Bytes(), Height: 10}}, nil
+}
+
+func (s *stubSendAPI) SendTransaction(_ context.Context, request *access.SendTransactionRequest, _ ...grpc.CallOption) (*access.SendTransactionResponse, error) {
+	tx, err := convert.MessageToTransaction(request.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.sendErr != nil {
+		err, s.sendErr = s.sendErr, nil
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.proposed = append(s.proposed, tx.ProposalKey)
+	for _, signature := range tx.EnvelopeSignatures {
+		s.signedBy = append(s.signedBy, signature.KeyIndex)
+	}
+	s.inFlight[tx.ProposalKey.KeyIndex]++
+	if s.inFlight[tx.ProposalKey.KeyIndex] > s.maxInFlight {
+		s.maxInFlight = s.inFlight[tx.ProposalKey.KeyIndex]
+	}
+	s.mu.Unlock()
+
+	// Give the other sends time to collide on the key
+	time.Sleep(5 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight[tx.ProposalKey.KeyIndex]--
+	s.mu.Unlock()
+	return &access.SendTransactionResponse{Id: tx.ID().Bytes()}, nil
+}
+
+func (s *stubSendAPI) GetTransactionResult(context.Context, *access.GetTransactionRequest, ...grpc.CallOption) (*access.TransactionResultResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.result == nil {
+		return &access.TransactionResultResponse{Status: entities.TransactionStatus_PENDING}, nil
+	}
+	return s.result, nil
+}
+
+// newTestSendingFlow returns a FlowService sending through stub with the given keys of the minter account,
+// which holds them with the sequence numbers of the chain in stub.account
+func newTestSendingFlow(t *testing.T, keys ...*flow.AccountKey) (*FlowService, *stubSendAPI) {
+	privateKey, err := crypto.GeneratePrivateKey(crypto.ECDSA_P256, []byte(strings.Repeat("a", 32)))
+	require.NoError(t, err)
+
+	account := &entities.Account{Address: testMinterAddress.Bytes()}
+	for _, key := range keys {
+		key.PublicKey, key.SigAlgo, key.HashAlgo, key.Weight = privateKey.PublicKey(), crypto.ECDSA_P256, crypto.SHA3_256, flow.AccountKeyWeightThreshold
+		account.Keys = append(account.Keys, convert.AccountKeyToMessage(key))
+	}
+
+	stub := &stubSendAPI{account: account, inFlight: make(map[int]int)}
+	nodes := NewAccessNodePool([]*AccessNode{NewAccessNode("node-0", client.NewFromRPCClient(stub))}, 0)
+	f := NewFlow(nodes, crypto.NewInMemorySigner(privateKey, crypto.SHA3_256), testMinterAddress, keys)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		f.Drain(ctx)
+	})
+	return f, stub
+}
+
+// setChainSequenceNumber sets the sequence number the chain holds for the key at index
+func (s *stubSendAPI) setChainSequenceNumber(index int, sequenceNumber uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account.Keys[index].SequenceNumber = uint32(sequenceNumber)
+}
+
+func TestFlowServiceSendMinterTransaction(t *testing.T) {
+	t.Run("Should propose concurrent transactions with one key at a time and distinct sequence numbers", func(t *testing.T) {
+		f, stub := newTestSendingFlow(t, &flow.AccountKey{Index: 0, SequenceNumber: 5}, &flow.AccountKey{Index: 1, SequenceNumber: 9})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := f.SendMinterTransaction(context.Background(), "mint_tokens", flow.NewTransaction())
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, stub.maxInFlight, "transactions proposed at once with the same key")
+		sequenceNumbers := map[int][]uint64{}
+		for _, key := range stub.proposed {
+			assert.Equal(t, testMinterAddress, key.Address)
+			sequenceNumbers[key.KeyIndex] = append(sequenceNumbers[key.KeyIndex], key.SequenceNumber)
+		}
+		for index, first := range map[int]uint64{0: 5, 1: 9} {
+			for i, sequenceNumber := range sequenceNumbers[index] {
+				assert.Equal(t, first+uint64(i), sequenceNumber, "key %d", index)
+			}
+		}
+		assert.Len(t, stub.proposed, 8)
+		for i, key := range stub.proposed {
+			assert.Equal(t, key.KeyIndex, stub.signedBy[i], "the envelope is signed with the proposal key")
+		}
+	})
+
+	t.Run("Should read the sequence number again after a failed submission", func(t *testing.T) {
+		f, stub := newTestSendingFlow(t, &flow.AccountKey{Index: 0, SequenceNumber: 5})
+		stub.sendErr = status.Error(codes.InvalidArgument, "invalid")
+
+		_, err := f.SendMinterTransaction(context.Background(), "mint_tokens", flow.NewTransaction())
+		require.Error(t, err)
+
+		stub.setChainSequenceNumber(0, 6)
+		_, err = f.SendMinterTransaction(context.Background(), "mint_tokens", flow.NewTransaction())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(6), stub.proposed[0].SequenceNumber)
+	})
+
+	t.Run("Should read the sequence number again after a transaction failed", func(t *testing.T) {
+		f, stub := newTestSendingFlow(t, &flow.AccountKey{Index: 0, SequenceNumber: 5})
+		transactionID, err := f.SendMinterTransaction(context.Background(), "mint_tokens", flow.NewTransaction())
+		require.NoError(t, err)
+
+		stub.mu.Lock()
+		stub.result = &access.TransactionResultResponse{Status: entities.TransactionStatus_SEALED, StatusCode: 1, ErrorMessage: "invalid proposal key"}
+		stub.mu.Unlock()
+		assert.True(t, f.pollTransaction(flow.HexToID(transactionID)))
+
+		_, err = f.SendMinterTransaction(context.Background(), "mint_tokens", flow.NewTransaction())
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{5, 5}, []uint64{stub.proposed[0].SequenceNumber, stub.proposed[1].SequenceNumber})
+	})
+}
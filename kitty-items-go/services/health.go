@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+)
+
+const (
+	CheckStatusOK   = "ok"
+	CheckStatusFail = "fail"
+)
+
+// HealthCheck is a named readiness probe. Check returns details that are reported whether or not it fails.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) (map[string]interface{}, error)
+}
+
+type CheckResult struct {
+	Name       string                 `json:"name"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// FlowHealth is what the Flow checks read the access nodes and the minter account through, a *FlowService
+type FlowHealth interface {
+	// Ping checks that an access node is reachable
+	Ping(ctx context.Context) error
+	// GetLatestBlockHeader returns the latest sealed or finalized block header
+	GetLatestBlockHeader(ctx context.Context, isSealed bool) (*flow.BlockHeader, error)
+	// GetMinterAccount fetches the current state of the minter account
+	GetMinterAccount(ctx context.Context) (*flow.Account, error)
+	// AccessNodeStatus returns the health of every access node as last observed
+	AccessNodeStatus() []AccessNodeStatus
+	// ProposalKeyIndexes returns the indexes of the minter keys transactions are proposed with
+	ProposalKeyIndexes() []int
+}
+
+type HealthConfig struct {
+	// MaxSealedLag is the maximum number of blocks the latest sealed block may trail the latest finalized block
+	MaxSealedLag uint64
+	// MinMinterBalance is the minimum FLOW balance of the minter account, in units of 10^-8 FLOW
+	MinMinterBalance uint64
+	// Timeout bounds each check
+	Timeout time.Duration
+}
+
+// HealthService runs the readiness checks of our dependencies
+type HealthService struct {
+	timeout time.Duration
+	checks  []HealthCheck
+}
+
+func NewHealth(timeout time.Duration, checks ...HealthCheck) *HealthService {
+	return &HealthService{timeout, checks}
+}
+
+// Ready runs every check concurrently and reports whether all of them passed
+func (h *HealthService) Ready(ctx context.Context) (bool, []CheckResult) {
+	results := make([]CheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check.Check(ctx)
+			results[i] = CheckResult{
+				Name:       check.Name,
+				Status:     CheckStatusOK,
+				Details:    details,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				results[i].Status = CheckStatusFail
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, result := range results {
+		if result.Status != CheckStatusOK {
+			ready = false
+		}
+	}
+
+	return ready, results
+}
+
+// FlowHealthChecks returns the checks for the access node, the minter balance and the minter proposal keys
+func FlowHealthChecks(f FlowHealth, conf HealthConfig) []HealthCheck {
+	return []HealthCheck{
+		{
+			Name: "access_node",
+			Check: func(ctx context.Context) (map[string]interface{}, error) {
+				if err := f.Ping(ctx); err != nil {
+					return map[string]interface{}{"nodes": f.AccessNodeStatus()}, fmt.Errorf("no access node reachable: %w", err)
+				}
+
+				sealed, err := f.GetLatestBlockHeader(ctx, true)
+				if err != nil {
+					return nil, fmt.Errorf("error getting latest sealed block: %w", err)
+				}
+
+				finalized, err := f.GetLatestBlockHeader(ctx, false)
+				if err != nil {
+					return nil, fmt.Errorf("error getting latest finalized block: %w", err)
+				}
+
+				var lag uint64
+				if finalized.Height > sealed.Height {
+					lag = finalized.Height - sealed.Height
+				}
+
+				details := map[string]interface{}{
+					"sealed_height":    sealed.Height,
+					"finalized_height": finalized.Height,
+					"sealed_lag":       lag,
+					"nodes":            f.AccessNodeStatus(),
+				}
+				if lag > conf.MaxSealedLag {
+					return details, fmt.Errorf("sealed height lags %d blocks behind, more than %d", lag, conf.MaxSealedLag)
+				}
+
+				return details, nil
+			},
+		},
+		{
+			Name: "minter_balance",
+			Check: func(ctx context.Context) (map[string]interface{}, error) {
+				account, err := f.GetMinterAccount(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("error getting minter account: %w", err)
+				}
+
+				details := map[string]interface{}{
+					"address": account.Address.Hex(),
+					"balance": FormatUFix64(cadence.UFix64(account.Balance)),
+					"minimum": FormatUFix64(cadence.UFix64(conf.MinMinterBalance)),
+				}
+				if account.Balance < conf.MinMinterBalance {
+					return details, fmt.Errorf("minter balance is below the minimum")
+				}
+
+				return details, nil
+			},
+		},
+		{
+			Name: "proposal_key",
+			Check: func(ctx context.Context) (map[string]interface{}, error) {
+				account, err := f.GetMinterAccount(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("error getting minter account: %w", err)
+				}
+
+				var keys []map[string]interface{}
+				for _, index := range f.ProposalKeyIndexes() {
+					if index >= len(account.Keys) {
+						return map[string]interface{}{"keys": keys}, fmt.Errorf("minter key %d no longer exists", index)
+					}
+
+					key := account.Keys[index]
+					keys = append(keys, map[string]interface{}{
+						"index":           key.Index,
+						"sequence_number": key.SequenceNumber,
+						"weight":          key.Weight,
+					})
+					if key.Revoked {
+						return map[string]interface{}{"keys": keys}, fmt.Errorf("minter key %d is revoked", index)
+					}
+				}
+
+				return map[string]interface{}{"keys": keys}, nil
+			},
+		},
+	}
+}
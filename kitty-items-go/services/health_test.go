@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowHealthChecks(t *testing.T) {
+	conf := HealthConfig{MaxSealedLag: 10, MinMinterBalance: 5e8}
+	account := func(balance uint64, keys ...*flow.AccountKey) *flow.Account {
+		if keys == nil {
+			keys = []*flow.AccountKey{{Index: 0, Weight: flow.AccountKeyWeightThreshold}}
+		}
+		return &flow.Account{Address: testMinterAddress, Balance: balance, Keys: keys}
+	}
+
+	cases := []struct {
+		name  string
+		check string
+		setup func(f *FakeFlow)
+		// problem is the error the check fails with, it passes when empty
+		problem string
+	}{
+		{
+			name:  "Should pass the access node check with the sealed height at the maximum lag",
+			check: "access_node",
+			setup: func(f *FakeFlow) {
+				f.SetHeight(100)
+				f.SetFinalizedHeight(110)
+			},
+		},
+		{
+			name:  "Should fail the access node check with the sealed height past the maximum lag",
+			check: "access_node",
+			setup: func(f *FakeFlow) {
+				f.SetHeight(100)
+				f.SetFinalizedHeight(111)
+			},
+			problem: "sealed height lags 11 blocks behind, more than 10",
+		},
+		{
+			name:    "Should fail the access node check when no node is reachable",
+			check:   "access_node",
+			setup:   func(f *FakeFlow) { f.SetPingError(errors.New("connection refused")) },
+			problem: "no access node reachable: connection refused",
+		},
+		{
+			name:  "Should pass the minter balance check at the minimum",
+			check: "minter_balance",
+			setup: func(f *FakeFlow) { f.SetMinterAccount(account(5e8)) },
+		},
+		{
+			name:    "Should fail the minter balance check below the minimum",
+			check:   "minter_balance",
+			setup:   func(f *FakeFlow) { f.SetMinterAccount(account(5e8 - 1)) },
+			problem: "minter balance is below the minimum",
+		},
+		{
+			name:  "Should pass the proposal key check with the key in place",
+			check: "proposal_key",
+			setup: func(f *FakeFlow) { f.SetMinterAccount(account(5e8)) },
+		},
+		{
+			name:    "Should fail the proposal key check when the key is revoked",
+			check:   "proposal_key",
+			setup:   func(f *FakeFlow) { f.SetMinterAccount(account(5e8, &flow.AccountKey{Index: 0, Revoked: true})) },
+			problem: "minter key 0 is revoked",
+		},
+		{
+			name:    "Should fail the proposal key check when the key is gone",
+			check:   "proposal_key",
+			setup:   func(f *FakeFlow) { f.SetMinterAccount(account(5e8, []*flow.AccountKey{}...)) },
+			problem: "minter key 0 no longer exists",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fake := NewFakeFlow(testMinterAddress, kittyitems.Contracts{})
+			fake.SetMinterAccount(account(5e8))
+			c.setup(fake)
+
+			var check HealthCheck
+			for _, healthCheck := range FlowHealthChecks(fake, conf) {
+				if healthCheck.Name == c.check {
+					check = healthCheck
+				}
+			}
+			require.NotNil(t, check.Check, "no %s check", c.check)
+
+			_, err := check.Check(context.Background())
+			if c.problem == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, c.problem, err.Error())
+		})
+	}
+}
+
+func TestHealthServiceReady(t *testing.T) {
+	passing := HealthCheck{Name: "passing", Check: func(context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"height": 1}, nil
+	}}
+	failing := HealthCheck{Name: "failing", Check: func(context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"height": 2}, errors.New("behind")
+	}}
+	slow := HealthCheck{Name: "slow", Check: func(ctx context.Context) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	t.Run("Should be ready when every check passes", func(t *testing.T) {
+		ready, results := NewHealth(time.Second, passing).Ready(context.Background())
+		assert.True(t, ready)
+		require.Len(t, results, 1)
+		assert.Equal(t, CheckStatusOK, results[0].Status)
+		assert.Equal(t, map[string]interface{}{"height": 1}, results[0].Details)
+	})
+
+	t.Run("Should not be ready when a check fails, reporting its details", func(t *testing.T) {
+		ready, results := NewHealth(time.Second, passing, failing).Ready(context.Background())
+		assert.False(t, ready)
+		require.Len(t, results, 2)
+		assert.Equal(t, CheckStatusOK, results[0].Status)
+		failed := results[1]
+		failed.DurationMs = 0
+		assert.Equal(t, CheckResult{Name: "failing", Status: CheckStatusFail, Error: "behind", Details: map[string]interface{}{"height": 2}}, failed)
+	})
+
+	t.Run("Should fail a check that outlasts the timeout", func(t *testing.T) {
+		ready, results := NewHealth(10*time.Millisecond, slow).Ready(context.Background())
+		assert.False(t, ready)
+		assert.Equal(t, context.DeadlineExceeded.Error(), results[0].Error)
+	})
+}
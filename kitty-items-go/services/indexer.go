@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/client"
+)
+
+// IndexedEvent is an event emitted by one of our contracts, with the position it was emitted at
+type IndexedEvent struct {
+	Type             string                 `json:"type"`
+	BlockID          string                 `json:"block_id"`
+	BlockHeight      uint64                 `json:"block_height"`
+	BlockTimestamp   time.Time              `json:"block_timestamp"`
+	TransactionID    string                 `json:"transaction_id"`
+	TransactionIndex int                    `json:"transaction_index"`
+	EventIndex       int                    `json:"event_index"`
+	Fields           map[string]interface{} `json:"fields"`
+	// Addresses are the accounts named in the event fields, whose state the event changed
+	Addresses []flow.Address `json:"-"`
+	// TransactionAddresses are the accounts named by any of our events in the same transaction, e.g. the
+	// seller of an accepted sale offer, which SaleOfferAccepted itself does not name
+	TransactionAddresses []flow.Address `json:"-"`
+}
+
+type IndexerConfig struct {
+	// EventTypes are the fully qualified event types to index, e.g. `A.0ae53cb6e3f42a79.Kibble.TokensDeposited`
+	EventTypes []string
+	// StartHeight is the first height to index, 0 starts at the latest sealed block
+	StartHeight  uint64
+	PollInterval time.Duration
+	// MaxBlocksPerPoll bounds the height range of a single events query
+	MaxBlocksPerPoll uint64
+	// CursorFile is where the indexed height is saved, empty keeps it in memory only
+	CursorFile string
+}
+
+// indexerCursor is the content of the cursor file
+type indexerCursor struct {
+	Height uint64 `json:"height"`
+}
+
+// IndexerService follows the sealed blocks and hands the events of our contracts to its subscribers, in order.
+// The indexed height is saved to the cursor file after every poll that indexed blocks and when Run returns.
+// A restart resumes after the saved height, taking precedence over the start height, so the events
+// indexed since the last save are handed to the subscribers again.
+type IndexerService struct {
+	flowService Flow
+	conf        IndexerConfig
+
+	// saveMu serializes the writes of the cursor file
+	saveMu sync.Mutex
+
+	mu          sync.RWMutex
+	height      uint64
+	latest      uint64
+	lastSuccess time.Time
+	lastErr     error
+	subscribers []func(IndexedEvent)
+}
+
+func NewIndexer(flowService Flow, conf IndexerConfig) (*IndexerService, error) {
+	i := &IndexerService{flowService: flowService, conf: conf}
+	if conf.CursorFile == "" {
+		return i, nil
+	}
+
+	contents, err := ioutil.ReadFile(conf.CursorFile)
+	if os.IsNotExist(err) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading indexer cursor file = %w", err)
+	}
+
+	var cursor indexerCursor
+	if err := json.Unmarshal(contents, &cursor); err != nil {
+		return nil, fmt.Errorf("error decoding indexer cursor file = %w", err)
+	}
+	i.height = cursor.Height
+
+	return i, nil
+}
+
+// Subscribe registers fn to receive every indexed event. Subscribers are called in turn from
+// the indexing goroutine, so they must not block.
+func (i *IndexerService) Subscribe(fn func(IndexedEvent)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.subscribers = append(i.subscribers, fn)
+}
+
+// Height returns the last indexed height and the latest sealed height seen
+func (i *IndexerService) Height() (indexed uint64, latest uint64) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.height, i.latest
+}
+
+// LastPoll returns when the indexer last polled the access node successfully and the error of the last poll
+func (i *IndexerService) LastPoll() (lastSuccess time.Time, lastErr error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.lastSuccess, i.lastErr
+}
+
+// Run indexes new sealed blocks every poll interval until ctx is done, then saves the indexed height
+func (i *IndexerService) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.conf.PollInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		if err := i.SaveCursor(); err != nil {
+			Logger(ctx).Error().Err(err).Msg("error saving indexer cursor")
+		}
+	}()
+
+	for {
+		before, _ := i.Height()
+		err := i.poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			Logger(ctx).Warn().Err(err).Msg("error indexing events")
+		}
+		if after, _ := i.Height(); after != before {
+			if err := i.SaveCursor(); err != nil {
+				Logger(ctx).Warn().Err(err).Msg("error saving indexer cursor")
+			}
+		}
+
+		i.mu.Lock()
+		if err == nil {
+			i.lastSuccess = time.Now()
+		}
+		i.lastErr = err
+		i.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SaveCursor writes the indexed height to the cursor file, if any
+func (i *IndexerService) SaveCursor() error {
+	i.saveMu.Lock()
+	defer i.saveMu.Unlock()
+
+	height, _ := i.Height()
+	if i.conf.CursorFile == "" || height == 0 {
+		return nil
+	}
+
+	contents, err := json.Marshal(&indexerCursor{Height: height})
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(i.conf.CursorFile, contents, 0600); err != nil {
+		return fmt.Errorf("error writing indexer cursor file = %w", err)
+	}
+
+	return nil
+}
+
+// Events returns the indexed events of the sealed blocks from height to height, in emission order,
+// fetched again from the access node. It is used to replay past events.
+func (i *IndexerService) Events(ctx context.Context, from, to uint64) ([]IndexedEvent, error) {
+	var events []IndexedEvent
+	for from <= to {
+		end := from + i.conf.MaxBlocksPerPoll - 1
+		if end > to || end < from {
+			end = to
+		}
+
+		chunk, err := i.fetch(ctx, from, end)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, chunk...)
+
+		if end == to {
+			break
+		}
+		from = end + 1
+	}
+	return events, nil
+}
+
+func (i *IndexerService) poll(ctx context.Context) error {
+	header, err := i.flowService.GetLatestBlockHeader(ctx, true)
+	if err != nil {
+		return fmt.Errorf("error getting latest sealed block = %w", err)
+	}
+
+	i.mu.Lock()
+	i.latest = header.Height
+	if i.height > header.Height {
+		// The cursor was saved on another chain, e.g. an emulator since restarted
+		Logger(ctx).Warn().Uint64("cursor_height", i.height).Uint64("latest_height", header.Height).
+			Msg("indexer cursor is ahead of the latest sealed block, starting over")
+		i.height = 0
+	}
+	if i.height == 0 {
+		i.height = header.Height
+		if i.conf.StartHeight > 0 && i.conf.StartHeight <= header.Height {
+			i.height = i.conf.StartHeight - 1
+		}
+	}
+	from := i.height + 1
+	i.mu.Unlock()
+
+	i.observeHeight()
+
+	for from <= header.Height {
+		to := from + i.conf.MaxBlocksPerPoll - 1
+		if to > header.Height {
+			to = header.Height
+		}
+
+		events, err := i.fetch(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		i.mu.RLock()
+		subscribers := i.subscribers
+		i.mu.RUnlock()
+
+		for _, event := range events {
+			indexerEvents.WithLabelValues(event.Type).Inc()
+			for _, subscriber := range subscribers {
+				subscriber(event)
+			}
+		}
+
+		i.mu.Lock()
+		i.height = to
+		i.mu.Unlock()
+		i.observeHeight()
+
+		from = to + 1
+	}
+
+	return nil
+}
+
+// fetch queries every event type over the range and merges the results in emission order
+func (i *IndexerService) fetch(ctx context.Context, from, to uint64) ([]IndexedEvent, error) {
+	var events []IndexedEvent
+	for _, eventType := range i.conf.EventTypes {
+		blocks, err := i.flowService.GetEventsForHeightRange(ctx, client.EventRangeQuery{
+			Type:        eventType,
+			StartHeight: from,
+			EndHeight:   to,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s events for heights %d-%d = %w", eventType, from, to, err)
+		}
+
+		for _, block := range blocks {
+			for _, event := range block.Events {
+				events = append(events, newIndexedEvent(block, event))
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(a, b int) bool {
+		if events[a].BlockHeight != events[b].BlockHeight {
+			return events[a].BlockHeight < events[b].BlockHeight
+		}
+		if events[a].TransactionIndex != events[b].TransactionIndex {
+			return events[a].TransactionIndex < events[b].TransactionIndex
+		}
+		return events[a].EventIndex < events[b].EventIndex
+	})
+
+	byTransaction := make(map[string][]flow.Address)
+	for _, event := range events {
+		byTransaction[event.TransactionID] = appendAddresses(byTransaction[event.TransactionID], event.Addresses...)
+	}
+	for index := range events {
+		events[index].TransactionAddresses = byTransaction[events[index].TransactionID]
+	}
+
+	return events, nil
+}
+
+func (i *IndexerService) observeHeight() {
+	indexed, latest := i.Height()
+	indexerHeight.Set(float64(indexed))
+	indexerLag.Set(float64(latest - indexed))
+}
+
+// appendAddresses appends the addresses not already in list
+func appendAddresses(list []flow.Address, addresses ...flow.Address) []flow.Address {
+	for _, address := range addresses {
+		if !containsAddress(list, address) {
+			list = append(list, address)
+		}
+	}
+	return list
+}
+
+func containsAddress(list []flow.Address, address flow.Address) bool {
+	for _, existing := range list {
+		if existing == address {
+			return true
+		}
+	}
+	return false
+}
+
+func newIndexedEvent(block client.BlockEvents, event flow.Event) IndexedEvent {
+	indexed := IndexedEvent{
+		Type:             event.Type,
+		BlockID:          block.BlockID.String(),
+		BlockHeight:      block.Height,
+		BlockTimestamp:   block.BlockTimestamp,
+		TransactionID:    event.TransactionID.String(),
+		TransactionIndex: event.TransactionIndex,
+		EventIndex:       event.EventIndex,
+		Fields:           make(map[string]interface{}),
+	}
+
+	for index, field := range event.Value.Fields {
+		name := fmt.Sprintf("%d", index)
+		if event.Value.EventType != nil && index < len(event.Value.EventType.Fields) {
+			name = event.Value.EventType.Fields[index].Identifier
+		}
+
+		if optional, ok := field.(cadence.Optional); ok {
+			field = optional.Value
+		}
+		if address, ok := field.(cadence.Address); ok {
+			indexed.Addresses = append(indexed.Addresses, flow.Address(address))
+		}
+
+		indexed.Fields[name] = exportValue(field)
+	}
+
+	return indexed
+}
+
+// exportValue converts a Cadence value to a JSON friendly Go value, with addresses
+// as hex strings and fixed point numbers as decimal strings
+func exportValue(value cadence.Value) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case cadence.Optional:
+		return exportValue(v.Value)
+	case cadence.Address:
+		return flow.Address(v).Hex()
+	case cadence.UFix64:
+		return FormatUFix64(v)
+	case cadence.UInt64:
+		return uint64(v)
+	case cadence.Bool:
+		return bool(v)
+	case cadence.String:
+		return string(v)
+	case cadence.Array:
+		values := make([]interface{}, len(v.Values))
+		for i, element := range v.Values {
+			values[i] = exportValue(element)
+		}
+		return values
+	default:
+		return v.ToGoValue()
+	}
+}
+
+// IndexerHealthCheck fails when the indexer trails the latest sealed block by more than maxLag blocks,
+// or has not completed a poll for longer than maxStale
+func IndexerHealthCheck(indexer *IndexerService, maxLag uint64, maxStale time.Duration) HealthCheck {
+	return HealthCheck{
+		Name: "indexer",
+		Check: func(ctx context.Context) (map[string]interface{}, error) {
+			indexed, latest := indexer.Height()
+			lastSuccess, lastErr := indexer.LastPoll()
+
+			details := map[string]interface{}{
+				"indexed_height": indexed,
+				"latest_height":  latest,
+				"lag":            latest - indexed,
+				"last_success":   lastSuccess,
+			}
+			if lastErr != nil {
+				details["last_error"] = lastErr.Error()
+			}
+
+			if lastSuccess.IsZero() {
+				return details, fmt.Errorf("indexer has not completed a poll yet")
+			}
+			if time.Since(lastSuccess) > maxStale {
+				return details, fmt.Errorf("indexer has not completed a poll since %s", lastSuccess.Format(time.RFC3339))
+			}
+			if latest-indexed > maxLag {
+				return details, fmt.Errorf("indexer lags %d blocks behind, more than %d", latest-indexed, maxLag)
+			}
+
+			return details, nil
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEventType = "A.01cf0e2f2f715450.Kibble.TokensDeposited"
+
+func TestIndexerServiceCursor(t *testing.T) {
+	cases := []struct {
+		name string
+		// cursor is the content of the cursor file, none when empty
+		cursor      string
+		startHeight uint64
+		// heights are the heights of the events handed to the subscribers, out of events at 3, 7 and 10
+		heights []uint64
+	}{
+		{"Should start at the latest sealed block without a cursor", "", 0, nil},
+		{"Should start at the start height without a cursor", "", 5, []uint64{7, 10}},
+		{"Should resume after the saved height", `{"height":5}`, 0, []uint64{7, 10}},
+		{"Should prefer the saved height to the start height", `{"height":7}`, 2, []uint64{10}},
+		{"Should start over when the saved height is ahead of the chain", `{"height":20}`, 0, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fake := NewFakeFlow(testMinterAddress, kittyitems.Contracts{})
+			fake.SetHeight(10)
+			for _, height := range []uint64{3, 7, 10} {
+				fake.AddEvents(height, flow.Event{Type: testEventType})
+			}
+
+			path := filepath.Join(t.TempDir(), "indexer_cursor.json")
+			if c.cursor != "" {
+				require.NoError(t, ioutil.WriteFile(path, []byte(c.cursor), 0600))
+			}
+
+			indexer, err := NewIndexer(fake, IndexerConfig{
+				EventTypes:       []string{testEventType},
+				StartHeight:      c.startHeight,
+				PollInterval:     time.Hour,
+				MaxBlocksPerPoll: 100,
+				CursorFile:       path,
+			})
+			require.NoError(t, err)
+
+			var heights []uint64
+			indexer.Subscribe(func(event IndexedEvent) { heights = append(heights, event.BlockHeight) })
+
+			// Run polls once, then returns as ctx is done and saves the cursor
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			indexer.Run(ctx)
+
+			assert.Equal(t, c.heights, heights)
+			contents, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"height":10}`, string(contents))
+		})
+	}
+
+	t.Run("Should reject an undecodable cursor file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "indexer_cursor.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+
+		_, err := NewIndexer(NewFakeFlow(testMinterAddress, kittyitems.Contracts{}), IndexerConfig{CursorFile: path})
+		assert.Error(t, err)
+	})
+
+	t.Run("Should not save the height without a cursor file", func(t *testing.T) {
+		indexer, err := NewIndexer(NewFakeFlow(testMinterAddress, kittyitems.Contracts{}), IndexerConfig{})
+		require.NoError(t, err)
+		assert.NoError(t, indexer.SaveCursor())
+	})
+}
@@ -2,49 +2,146 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 
-	"github.com/dapperlabs/kitty-items-go/templates"
 	"github.com/onflow/cadence"
 	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrAllowanceExceeded is returned when a mint would go over the remaining allowance of the on-chain Minter
+var ErrAllowanceExceeded = errors.New("mint exceeds the remaining minter allowance")
+
 type KibblesService struct {
-	flowService *FlowService
+	flowService   Flow
+	limitsService *LimitsService
+	scriptCache   *ScriptCache
+	contracts     kittyitems.Contracts
 }
 
-func NewKibbles(service *FlowService) *KibblesService {
-	return &KibblesService{service}
+func NewKibbles(service Flow, limits *LimitsService, cache *ScriptCache, fungibleTokenAddress, kibbleAddress flow.Address) *KibblesService {
+	return &KibblesService{
+		flowService:   service,
+		limitsService: limits,
+		scriptCache:   cache,
+		contracts:     kittyitems.Contracts{FungibleToken: fungibleTokenAddress, Kibble: kibbleAddress},
+	}
 }
 
 // Mint sends a transaction to the Flow blockchain and returns the generated transactionID as a string.
-func (k *KibblesService) Mint(ctx context.Context, destinationAddress flow.Address, amount uint) (string, error) {
-	log.Printf("minting kibbles to address=%s", destinationAddress.String())
-	sequenceNumber, err := k.flowService.GetMinterAddressSequenceNumber(ctx)
+// The request is counted against the configured rate limits and mint quotas, whose state is returned as a Quota.
+func (k *KibblesService) Mint(ctx context.Context, destinationAddress flow.Address, amount uint) (transactionID string, quota Quota, err error) {
+	ctx, span := startSpan(ctx, "KibblesService.Mint",
+		attribute.String("flow_address", destinationAddress.Hex()),
+		attribute.Int64("amount", int64(amount)),
+	)
+	defer func() { endSpan(span, err) }()
+
+	quota, err = k.limitsService.AllowRequest(ctx)
 	if err != nil {
-		return "", fmt.Errorf("error getting sequence number = %w", err)
+		return "", quota, err
 	}
 
-	referenceBlock, err := k.flowService.client.GetLatestBlock(ctx, true)
+	quota, release, err := k.limitsService.ReserveKibble(quota, destinationAddress, uint64(amount))
 	if err != nil {
-		return "", fmt.Errorf("error getting reference block = %w", err)
+		return "", quota, err
 	}
 
-	tx := flow.NewTransaction().
-		SetScript([]byte(templates.MintKibblesTemplate)).
-		SetProposalKey(k.flowService.minterAddress, k.flowService.minterAccountKey.Index, sequenceNumber).
-		SetPayer(k.flowService.minterAddress).
-		SetReferenceBlockID(referenceBlock.ID).
-		SetGasLimit(100)
+	transactionID, err = k.mint(ctx, destinationAddress, amount)
+	if err != nil {
+		release()
+		return "", quota, err
+	}
 
-	if err := tx.AddArgument(cadence.NewAddress(destinationAddress)); err != nil {
+	return transactionID, quota, nil
+}
+
+func (k *KibblesService) mint(ctx context.Context, destinationAddress flow.Address, amount uint) (string, error) {
+	value, err := cadence.NewUFix64(fmt.Sprintf("%d.0", amount))
+	if err != nil {
+		return "", fmt.Errorf("invalid amount = %w", err)
+	}
+
+	// The Minter would reject the transaction anyway, checking first saves a doomed submission
+	allowance, _, err := k.MinterAllowance(ctx, BlockRef{})
+	if err != nil {
 		return "", err
 	}
+	if value > allowance {
+		return "", ErrAllowanceExceeded
+	}
 
-	if err := tx.AddArgument(cadence.NewUInt(amount)); err != nil {
+	tx, err := k.contracts.KibbleMintTokensWithMinter(k.flowService.MinterAddress(), destinationAddress, value)
+	if err != nil {
 		return "", err
 	}
 
-	return k.flowService.Send(ctx, tx)
+	return k.flowService.SendMinterTransaction(ctx, "mint_kibbles", tx)
+}
+
+// MinterAllowance returns the remaining amount the minter account's Minter was allowed to mint at the block
+// referenced by at, and the height it was read at. It bypasses the script cache so mints see the current allowance.
+func (k *KibblesService) MinterAllowance(ctx context.Context, at BlockRef) (allowance cadence.UFix64, height uint64, err error) {
+	ctx, span := startSpan(ctx, "KibblesService.MinterAllowance", attribute.String("block", at.String()))
+	defer func() { endSpan(span, err) }()
+
+	allowance, err = k.contracts.KibbleGetMinterAllowance(ctx, executorAt(k.flowService, at, &height), k.flowService.MinterAddress())
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading minter allowance = %w", err)
+	}
+
+	return allowance, height, nil
+}
+
+// Balance returns the Kibble balance of address at the block referenced by at, and the height it was read at.
+// It is served from the script cache when possible.
+func (k *KibblesService) Balance(ctx context.Context, address flow.Address, at BlockRef) (balance cadence.UFix64, height uint64, err error) {
+	ctx, span := startSpan(ctx, "KibblesService.Balance",
+		attribute.String("flow_address", address.Hex()),
+		attribute.String("block", at.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	balance, err = k.contracts.KibbleGetBalance(ctx, k.scriptCache.ExecutorAt(at, &height, address), address)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading balance = %w", err)
+	}
+
+	return balance, height, nil
+}
+
+// ProvisionMinter stores a new long-lived Minter with the given allowance in the minter account.
+// The minter account must hold the Kibble Administrator resource.
+func (k *KibblesService) ProvisionMinter(ctx context.Context, allowedAmount cadence.UFix64) (transactionID string, err error) {
+	ctx, span := startSpan(ctx, "KibblesService.ProvisionMinter", attribute.String("allowed_amount", FormatUFix64(allowedAmount)))
+	defer func() { endSpan(span, err) }()
+
+	Logger(ctx).Info().Str("allowed_amount", FormatUFix64(allowedAmount)).Msg("provisioning kibble minter")
+	tx, err := k.contracts.KibbleCreateMinter(k.flowService.MinterAddress(), allowedAmount)
+	if err != nil {
+		return "", err
+	}
+
+	return k.flowService.SendMinterTransaction(ctx, "create_minter", tx)
+}
+
+// TopUpMinter raises the allowance of the stored Minter by amount
+func (k *KibblesService) TopUpMinter(ctx context.Context, amount cadence.UFix64) (transactionID string, err error) {
+	ctx, span := startSpan(ctx, "KibblesService.TopUpMinter", attribute.String("amount", FormatUFix64(amount)))
+	defer func() { endSpan(span, err) }()
+
+	Logger(ctx).Info().Str("amount", FormatUFix64(amount)).Msg("topping up kibble minter")
+	tx, err := k.contracts.KibbleTopUpMinter(k.flowService.MinterAddress(), amount)
+	if err != nil {
+		return "", err
+	}
+
+	return k.flowService.SendMinterTransaction(ctx, "top_up_minter", tx)
+}
+
+// FormatUFix64 renders a UFix64 as a decimal string with the 8 fractional digits Cadence uses
+func FormatUFix64(v cadence.UFix64) string {
+	return fmt.Sprintf("%d.%08d", uint64(v)/1e8, uint64(v)%1e8)
 }
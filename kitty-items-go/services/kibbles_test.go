@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFungibleTokenAddress = flow.HexToAddress("ee82856bf20e2aa6")
+	testMinterAddress        = flow.HexToAddress("01cf0e2f2f715450")
+	testRecipientAddress     = flow.HexToAddress("179b6b1cb6755e31")
+)
+
+func newTestKibbles(t *testing.T, limits LimitsConfig) (*KibblesService, *FakeFlow) {
+	fake := NewFakeFlow(testMinterAddress, kittyitems.Contracts{FungibleToken: testFungibleTokenAddress, Kibble: testMinterAddress})
+	cache := NewScriptCache(fake, ScriptCacheConfig{LatestTTL: time.Minute, MaxEntries: 100})
+	return NewKibbles(fake, NewLimits(limits), cache, testFungibleTokenAddress, testMinterAddress), fake
+}
+
+func testUFix64(t *testing.T, s string) cadence.UFix64 {
+	value, err := cadence.NewUFix64(s)
+	require.NoError(t, err)
+	return value
+}
+
+func TestKibblesServiceMint(t *testing.T) {
+	t.Run("Should send a mint transaction authorized by the minter", func(t *testing.T) {
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "100.0"))
+
+		transactionID, _, err := kibbles.Mint(context.Background(), testRecipientAddress, 10)
+		require.NoError(t, err)
+
+		transactions := fake.Transactions()
+		require.Len(t, transactions, 1)
+		sent := transactions[0]
+		assert.Equal(t, "mint_kibbles", sent.Name)
+		assert.Equal(t, transactionID, sent.Transaction.ID().String())
+		assert.Equal(t, []flow.Address{testMinterAddress}, sent.Transaction.Authorizers)
+		assert.Equal(t, testMinterAddress, sent.Transaction.Payer)
+		assert.Equal(t, kittyitems.GasLimit(kittyitems.KibbleMintTokensWithMinterTransaction), sent.Transaction.GasLimit)
+		assert.Equal(t, []cadence.Value{cadence.NewAddress(testRecipientAddress), testUFix64(t, "10.0")}, sent.Arguments)
+	})
+
+	t.Run("Should not send a mint over the minter allowance", func(t *testing.T) {
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "5.0"))
+
+		_, _, err := kibbles.Mint(context.Background(), testRecipientAddress, 10)
+		assert.True(t, errors.Is(err, ErrAllowanceExceeded))
+		assert.Empty(t, fake.Transactions())
+	})
+
+	t.Run("Should release the recipient quota when the transaction is not sent", func(t *testing.T) {
+		kibbles, fake := newTestKibbles(t, LimitsConfig{MaxKibblePerRecipientPerDay: 10})
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "100.0"))
+		sendErr := errors.New("no access node")
+		fake.SetSendError("mint_kibbles", sendErr)
+
+		_, _, err := kibbles.Mint(context.Background(), testRecipientAddress, 10)
+		assert.True(t, errors.Is(err, sendErr))
+
+		fake.SetSendError("mint_kibbles", nil)
+		_, quota, err := kibbles.Mint(context.Background(), testRecipientAddress, 10)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), quota.RecipientRemaining)
+		assert.Len(t, fake.Transactions(), 1)
+
+		_, _, err = kibbles.Mint(context.Background(), testRecipientAddress, 1)
+		var quotaErr *QuotaExceededError
+		assert.True(t, errors.As(err, &quotaErr))
+	})
+}
+
+func TestKibblesServiceBalance(t *testing.T) {
+	t.Run("Should read the balance of the address at the latest block", func(t *testing.T) {
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		fake.SetHeight(42)
+		fake.HandleScript(kittyitems.KibbleGetBalanceScript, func(arguments []cadence.Value) (cadence.Value, error) {
+			require.Equal(t, []cadence.Value{cadence.NewAddress(testRecipientAddress)}, arguments)
+			return testUFix64(t, "12.5"), nil
+		})
+
+		balance, height, err := kibbles.Balance(context.Background(), testRecipientAddress, BlockRef{})
+		require.NoError(t, err)
+		assert.Equal(t, testUFix64(t, "12.5"), balance)
+		assert.Equal(t, uint64(42), height)
+	})
+
+	t.Run("Should not read a block past the latest one", func(t *testing.T) {
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		fake.SetScriptResult(kittyitems.KibbleGetBalanceScript, testUFix64(t, "12.5"))
+
+		_, _, err := kibbles.Balance(context.Background(), testRecipientAddress, AtHeight(2))
+		assert.True(t, errors.Is(err, ErrBlockNotFound))
+	})
+
+	t.Run("Should return the script errors", func(t *testing.T) {
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		scriptErr := errors.New("vault not found")
+		fake.SetScriptError(kittyitems.KibbleGetBalanceScript, scriptErr)
+
+		_, _, err := kibbles.Balance(context.Background(), testRecipientAddress, BlockRef{})
+		assert.True(t, errors.Is(err, scriptErr))
+	})
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type KittyItemsService struct {
+	flowService   Flow
+	limitsService *LimitsService
+	scriptCache   *ScriptCache
+	contracts     kittyitems.Contracts
+}
+
+func NewKittyItems(service Flow, limits *LimitsService, cache *ScriptCache, nonFungibleTokenAddress, kittyItemsAddress flow.Address) *KittyItemsService {
+	return &KittyItemsService{
+		flowService:   service,
+		limitsService: limits,
+		scriptCache:   cache,
+		contracts:     kittyitems.Contracts{NonFungibleToken: nonFungibleTokenAddress, KittyItems: kittyItemsAddress},
+	}
+}
+
+// CollectionIDs returns the IDs of the KittyItems owned by address at the block referenced by at, and the height
+// they were read at. It is served from the script cache when possible.
+func (k *KittyItemsService) CollectionIDs(ctx context.Context, address flow.Address, at BlockRef) (ids []uint64, height uint64, err error) {
+	ctx, span := startSpan(ctx, "KittyItemsService.CollectionIDs",
+		attribute.String("flow_address", address.Hex()),
+		attribute.String("block", at.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	ids, err = k.contracts.KittyItemsReadCollectionIDs(ctx, k.scriptCache.ExecutorAt(at, &height, address), address)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading collection ids = %w", err)
+	}
+
+	return ids, height, nil
+}
+
+// Mint sends a transaction minting a KittyItem of the given type to destinationAddress and returns the transactionID.
+// The request is counted against the configured rate limits and the recipient's KittyItems quota.
+func (k *KittyItemsService) Mint(ctx context.Context, destinationAddress flow.Address, typeID uint64) (transactionID string, quota Quota, err error) {
+	ctx, span := startSpan(ctx, "KittyItemsService.Mint",
+		attribute.String("flow_address", destinationAddress.Hex()),
+		attribute.Int64("type_id", int64(typeID)),
+	)
+	defer func() { endSpan(span, err) }()
+
+	quota, err = k.limitsService.AllowRequest(ctx)
+	if err != nil {
+		return "", quota, err
+	}
+
+	quota, release, err := k.limitsService.ReserveKittyItems(quota, destinationAddress, 1)
+	if err != nil {
+		return "", quota, err
+	}
+
+	transactionID, err = k.mint(ctx, destinationAddress, typeID)
+	if err != nil {
+		release()
+		return "", quota, err
+	}
+
+	return transactionID, quota, nil
+}
+
+func (k *KittyItemsService) mint(ctx context.Context, destinationAddress flow.Address, typeID uint64) (string, error) {
+	tx, err := k.contracts.KittyItemsMintKittyItem(k.flowService.MinterAddress(), destinationAddress, typeID)
+	if err != nil {
+		return "", err
+	}
+
+	return k.flowService.SendMinterTransaction(ctx, "mint_kitty_item", tx)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+)
+
+// LimitsConfig holds the configurable mint limits. A zero value disables the corresponding limit.
+type LimitsConfig struct {
+	RequestsPerMinute           uint64
+	MaxKibblePerRecipientPerDay uint64
+	MaxKittyItemsPerRecipient   uint64
+	MaxKibbleMintedPerDay       uint64
+}
+
+// QuotaScope is how far the limits apply: they are counted in memory by each process, so a restart resets them
+// and every replica enforces them on its own
+const QuotaScope = "process"
+
+// Quota describes the state of the limits that applied to a request. Limits left at zero were not enforced.
+type Quota struct {
+	RequestsLimit     uint64
+	RequestsRemaining uint64
+	RequestsReset     time.Time
+
+	RecipientLimit     uint64
+	RecipientRemaining uint64
+
+	GlobalLimit     uint64
+	GlobalRemaining uint64
+
+	DailyReset time.Time
+}
+
+// QuotaExceededError is returned when a request would go over one of the configured limits
+type QuotaExceededError struct {
+	Reason string
+	Quota  Quota
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s", e.Reason)
+}
+
+// LimitsService tracks per API key request rates and per recipient and global mint quotas in memory.
+// Counters are process-local and reset when the service restarts, see QuotaScope. They protect a single
+// instance from bursts, they are not a global cap on what gets minted.
+type LimitsService struct {
+	conf LimitsConfig
+	// now is the clock the windows roll with, replaced in tests
+	now func() time.Time
+
+	mu             sync.Mutex
+	minute         time.Time
+	requests       map[string]uint64
+	day            time.Time
+	kibblePerDay   map[flow.Address]uint64
+	kibbleMinted   uint64
+	itemsMintedFor map[flow.Address]uint64
+}
+
+func NewLimits(conf LimitsConfig) *LimitsService {
+	return &LimitsService{
+		conf:           conf,
+		now:            time.Now,
+		requests:       make(map[string]uint64),
+		kibblePerDay:   make(map[flow.Address]uint64),
+		itemsMintedFor: make(map[flow.Address]uint64),
+	}
+}
+
+// AllowRequest counts a request against the per minute limit of the API key that authenticated ctx
+func (l *LimitsService) AllowRequest(ctx context.Context) (Quota, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollWindows()
+
+	var q Quota
+	key, ok := APIKeyFromContext(ctx)
+	if !ok || l.conf.RequestsPerMinute == 0 {
+		return q, nil
+	}
+
+	q.RequestsLimit = l.conf.RequestsPerMinute
+	q.RequestsReset = l.minute.Add(time.Minute)
+
+	used := l.requests[key.ID]
+	if used >= l.conf.RequestsPerMinute {
+		return q, &QuotaExceededError{Reason: "too many requests for this api key", Quota: q}
+	}
+
+	l.requests[key.ID] = used + 1
+	q.RequestsRemaining = l.conf.RequestsPerMinute - used - 1
+
+	return q, nil
+}
+
+// ReserveKibble reserves amount against the recipient and global daily Kibble budgets.
+// The returned release function gives the amount back and must be called if the mint is not submitted.
+func (l *LimitsService) ReserveKibble(q Quota, recipient flow.Address, amount uint64) (Quota, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollWindows()
+
+	q.DailyReset = l.day.Add(24 * time.Hour)
+
+	used := l.kibblePerDay[recipient]
+	if limit := l.conf.MaxKibblePerRecipientPerDay; limit > 0 {
+		q.RecipientLimit = limit
+		q.RecipientRemaining = remaining(limit, used)
+		if used+amount > limit {
+			return q, nil, &QuotaExceededError{Reason: "daily kibble limit reached for recipient", Quota: q}
+		}
+	}
+
+	if limit := l.conf.MaxKibbleMintedPerDay; limit > 0 {
+		q.GlobalLimit = limit
+		q.GlobalRemaining = remaining(limit, l.kibbleMinted)
+		if l.kibbleMinted+amount > limit {
+			return q, nil, &QuotaExceededError{Reason: "global daily mint budget exhausted", Quota: q}
+		}
+	}
+
+	l.kibblePerDay[recipient] = used + amount
+	l.kibbleMinted += amount
+	if q.RecipientLimit > 0 {
+		q.RecipientRemaining -= amount
+	}
+	if q.GlobalLimit > 0 {
+		q.GlobalRemaining -= amount
+	}
+
+	day := l.day
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.day.Equal(day) {
+			return
+		}
+		l.kibblePerDay[recipient] -= amount
+		l.kibbleMinted -= amount
+	}
+
+	return q, release, nil
+}
+
+// ReserveKittyItems reserves count items against the recipient's KittyItems limit, which counts the items minted
+// since the process started. The returned release function gives the items back and must be called if the
+// mint is not submitted.
+func (l *LimitsService) ReserveKittyItems(q Quota, recipient flow.Address, count uint64) (Quota, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	used := l.itemsMintedFor[recipient]
+	if limit := l.conf.MaxKittyItemsPerRecipient; limit > 0 {
+		q.RecipientLimit = limit
+		q.RecipientRemaining = remaining(limit, used)
+		if used+count > limit {
+			return q, nil, &QuotaExceededError{Reason: "kitty items limit reached for recipient", Quota: q}
+		}
+		q.RecipientRemaining -= count
+	}
+
+	l.itemsMintedFor[recipient] = used + count
+
+	release := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.itemsMintedFor[recipient] -= count
+	}
+
+	return q, release, nil
+}
+
+// rollWindows resets the counters whose window has elapsed, must be called with the lock held
+func (l *LimitsService) rollWindows() {
+	now := l.now().UTC()
+
+	if minute := now.Truncate(time.Minute); !minute.Equal(l.minute) {
+		l.minute = minute
+		l.requests = make(map[string]uint64)
+	}
+
+	if day := now.Truncate(24 * time.Hour); !day.Equal(l.day) {
+		l.day = day
+		l.kibblePerDay = make(map[flow.Address]uint64)
+		l.kibbleMinted = 0
+	}
+}
+
+func remaining(limit, used uint64) uint64 {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
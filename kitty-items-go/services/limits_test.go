@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOtherRecipientAddress = flow.HexToAddress("f3fcd2c1a78f5eee")
+
+// testClock is a clock the tests move by hand, starting at noon so that a few minutes don't roll the day
+type testClock struct {
+	now time.Time
+}
+
+func newTestLimits(conf LimitsConfig) (*LimitsService, *testClock) {
+	clock := &testClock{now: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)}
+	limits := NewLimits(conf)
+	limits.now = func() time.Time { return clock.now }
+	return limits, clock
+}
+
+func testAPIKeyContext(id string) context.Context {
+	return WithAPIKey(context.Background(), &APIKey{ID: id})
+}
+
+func assertQuotaExceeded(t *testing.T, err error) {
+	var quotaErr *QuotaExceededError
+	assert.True(t, errors.As(err, &quotaErr), "expected a quota error, got %v", err)
+}
+
+func TestLimitsServiceAllowRequest(t *testing.T) {
+	// request is made with the API key key, and is expected to be allowed or not
+	type request struct {
+		key     string
+		allowed bool
+	}
+
+	cases := []struct {
+		name     string
+		requests []request
+		// elapsed is how long passes between two requests
+		elapsed time.Duration
+	}{
+		{
+			name:     "Should allow the requests of a key up to the limit",
+			requests: []request{{"a", true}, {"a", true}, {"a", false}, {"a", false}},
+		},
+		{
+			name:     "Should count the requests of every key apart",
+			requests: []request{{"a", true}, {"a", true}, {"b", true}, {"a", false}, {"b", true}, {"b", false}},
+		},
+		{
+			name:     "Should allow requests again in the next minute",
+			requests: []request{{"a", true}, {"a", true}, {"a", true}, {"a", true}},
+			elapsed:  40 * time.Second,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limits, clock := newTestLimits(LimitsConfig{RequestsPerMinute: 2})
+			for i, request := range c.requests {
+				if i > 0 {
+					clock.now = clock.now.Add(c.elapsed)
+				}
+				q, err := limits.AllowRequest(testAPIKeyContext(request.key))
+				if !request.allowed {
+					assertQuotaExceeded(t, err)
+					continue
+				}
+				require.NoError(t, err, "request %d", i)
+				assert.Equal(t, uint64(2), q.RequestsLimit)
+				assert.Equal(t, clock.now.Truncate(time.Minute).Add(time.Minute), q.RequestsReset)
+			}
+		})
+	}
+
+	t.Run("Should not limit requests without an API key or a limit", func(t *testing.T) {
+		limited, _ := newTestLimits(LimitsConfig{RequestsPerMinute: 1})
+		unlimited, _ := newTestLimits(LimitsConfig{})
+		for i := 0; i < 3; i++ {
+			q, err := limited.AllowRequest(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, Quota{}, q)
+
+			q, err = unlimited.AllowRequest(testAPIKeyContext("a"))
+			require.NoError(t, err)
+			assert.Equal(t, Quota{}, q)
+		}
+	})
+}
+
+func TestLimitsServiceReserveKibble(t *testing.T) {
+	type reservation struct {
+		recipient flow.Address
+		amount    uint64
+		// elapsed is how long passes before the reservation
+		elapsed time.Duration
+		// release gives the amount back once reserved, as for a mint that could not be sent
+		release bool
+		allowed bool
+	}
+
+	cases := []struct {
+		name         string
+		conf         LimitsConfig
+		reservations []reservation
+	}{
+		{
+			name: "Should cap the kibble of a recipient per day",
+			conf: LimitsConfig{MaxKibblePerRecipientPerDay: 10},
+			reservations: []reservation{
+				{recipient: testRecipientAddress, amount: 6, allowed: true},
+				{recipient: testRecipientAddress, amount: 5},
+				{recipient: testRecipientAddress, amount: 4, allowed: true},
+				{recipient: testOtherRecipientAddress, amount: 10, allowed: true},
+			},
+		},
+		{
+			name: "Should cap the kibble minted in total per day",
+			conf: LimitsConfig{MaxKibbleMintedPerDay: 10},
+			reservations: []reservation{
+				{recipient: testRecipientAddress, amount: 6, allowed: true},
+				{recipient: testOtherRecipientAddress, amount: 5},
+				{recipient: testOtherRecipientAddress, amount: 4, allowed: true},
+			},
+		},
+		{
+			name: "Should give released kibble back",
+			conf: LimitsConfig{MaxKibblePerRecipientPerDay: 10, MaxKibbleMintedPerDay: 10},
+			reservations: []reservation{
+				{recipient: testRecipientAddress, amount: 10, release: true, allowed: true},
+				{recipient: testRecipientAddress, amount: 10, allowed: true},
+			},
+		},
+		{
+			name: "Should reset the daily limits the next day",
+			conf: LimitsConfig{MaxKibblePerRecipientPerDay: 10, MaxKibbleMintedPerDay: 10},
+			reservations: []reservation{
+				{recipient: testRecipientAddress, amount: 10, allowed: true},
+				{recipient: testRecipientAddress, amount: 1, elapsed: 11 * time.Hour},
+				{recipient: testRecipientAddress, amount: 10, elapsed: time.Hour, allowed: true},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limits, clock := newTestLimits(c.conf)
+			for i, r := range c.reservations {
+				clock.now = clock.now.Add(r.elapsed)
+				q, release, err := limits.ReserveKibble(Quota{}, r.recipient, r.amount)
+				if !r.allowed {
+					assertQuotaExceeded(t, err)
+					continue
+				}
+				require.NoError(t, err, "reservation %d", i)
+				assert.Equal(t, clock.now.Truncate(24*time.Hour).Add(24*time.Hour), q.DailyReset)
+				if r.release {
+					release()
+				}
+			}
+		})
+	}
+
+	t.Run("Should not give back kibble reserved the day before", func(t *testing.T) {
+		limits, clock := newTestLimits(LimitsConfig{MaxKibbleMintedPerDay: 10})
+		_, release, err := limits.ReserveKibble(Quota{}, testRecipientAddress, 5)
+		require.NoError(t, err)
+
+		clock.now = clock.now.Add(12 * time.Hour)
+		_, _, err = limits.ReserveKibble(Quota{}, testRecipientAddress, 10)
+		require.NoError(t, err)
+		release()
+
+		_, _, err = limits.ReserveKibble(Quota{}, testRecipientAddress, 1)
+		assertQuotaExceeded(t, err)
+	})
+
+	t.Run("Should report the remaining quotas", func(t *testing.T) {
+		limits, _ := newTestLimits(LimitsConfig{MaxKibblePerRecipientPerDay: 10, MaxKibbleMintedPerDay: 25})
+		_, _, err := limits.ReserveKibble(Quota{}, testOtherRecipientAddress, 5)
+		require.NoError(t, err)
+
+		q, _, err := limits.ReserveKibble(Quota{}, testRecipientAddress, 4)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(10), q.RecipientLimit)
+		assert.Equal(t, uint64(6), q.RecipientRemaining)
+		assert.Equal(t, uint64(25), q.GlobalLimit)
+		assert.Equal(t, uint64(16), q.GlobalRemaining)
+	})
+}
+
+func TestLimitsServiceReserveKittyItems(t *testing.T) {
+	cases := []struct {
+		name    string
+		limit   uint64
+		elapsed time.Duration
+		// counts are the items reserved in turn for the recipient, and whether each is allowed
+		counts  []uint64
+		allowed []bool
+	}{
+		{"Should cap the items of a recipient", 2, 0, []uint64{1, 1, 1}, []bool{true, true, false}},
+		{"Should reject a reservation going over the limit", 2, 0, []uint64{1, 2, 1}, []bool{true, false, true}},
+		{"Should keep counting across days", 2, 48 * time.Hour, []uint64{2, 1}, []bool{true, false}},
+		{"Should not limit the items without a limit", 0, 0, []uint64{5, 5}, []bool{true, true}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limits, clock := newTestLimits(LimitsConfig{MaxKittyItemsPerRecipient: c.limit})
+			for i, count := range c.counts {
+				if i > 0 {
+					clock.now = clock.now.Add(c.elapsed)
+				}
+				q, _, err := limits.ReserveKittyItems(Quota{}, testRecipientAddress, count)
+				if !c.allowed[i] {
+					assertQuotaExceeded(t, err)
+					continue
+				}
+				require.NoError(t, err, "reservation %d", i)
+				assert.Equal(t, c.limit, q.RecipientLimit)
+			}
+		})
+	}
+
+	t.Run("Should give released items back", func(t *testing.T) {
+		limits, _ := newTestLimits(LimitsConfig{MaxKittyItemsPerRecipient: 1})
+		_, release, err := limits.ReserveKittyItems(Quota{}, testRecipientAddress, 1)
+		require.NoError(t, err)
+		release()
+
+		q, _, err := limits.ReserveKittyItems(Quota{}, testRecipientAddress, 1)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), q.RecipientRemaining)
+
+		_, _, err = limits.ReserveKittyItems(Quota{}, testOtherRecipientAddress, 1)
+		assert.NoError(t, err)
+	})
+}
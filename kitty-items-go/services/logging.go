@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields are log fields whose values are replaced before they are written, wherever they appear
+var sensitiveFields = []string{
+	"authorization",
+	"api_key",
+	"secret",
+	"private_key",
+	"minter_private_key",
+	"password",
+	"token",
+}
+
+// Logger returns the request scoped logger carried by ctx, falling back to the global logger
+func Logger(ctx context.Context) *zerolog.Logger {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		return &log.Logger
+	}
+	return logger
+}
+
+// WithLogFields returns a copy of ctx whose logger adds the given fields to every line
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	logger := Logger(ctx).With().Fields(fields).Logger()
+	return logger.WithContext(ctx)
+}
+
+// redactingWriter rewrites JSON log lines so sensitive fields never reach the output
+type redactingWriter struct {
+	out io.Writer
+}
+
+// NewRedactingWriter wraps out so that the values of sensitive fields in JSON log lines are redacted
+func NewRedactingWriter(out io.Writer) io.Writer {
+	return &redactingWriter{out}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if !containsSensitiveField(p) {
+		return r.out.Write(p)
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(p, &event); err != nil {
+		return r.out.Write(p)
+	}
+	redact(event)
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.out.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+
+	// zerolog expects the length of the line it handed us
+	return len(p), nil
+}
+
+func containsSensitiveField(p []byte) bool {
+	lower := bytes.ToLower(p)
+	for _, field := range sensitiveFields {
+		if bytes.Contains(lower, []byte(`"`+field+`"`)) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(values map[string]interface{}) {
+	for key, value := range values {
+		if isSensitiveField(key) {
+			values[key] = redactedValue
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			redact(nested)
+		}
+	}
+}
+
+func isSensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range sensitiveFields {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
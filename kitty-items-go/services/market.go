@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SaleOffer is the public view of a KittyItem offered for sale in a market collection
+type SaleOffer struct {
+	SaleCompleted bool
+	SaleItemID    uint64
+	SalePrice     cadence.UFix64
+}
+
+type MarketService struct {
+	scriptCache *ScriptCache
+	contracts   kittyitems.Contracts
+}
+
+func NewMarket(cache *ScriptCache, kittyItemsMarketAddress flow.Address) *MarketService {
+	return &MarketService{
+		scriptCache: cache,
+		contracts:   kittyitems.Contracts{KittyItemsMarket: kittyItemsMarketAddress},
+	}
+}
+
+// SaleOfferIDs returns the IDs of the items offered for sale in the market collection of address
+// at the block referenced by at, and the height they were read at
+func (m *MarketService) SaleOfferIDs(ctx context.Context, address flow.Address, at BlockRef) (ids []uint64, height uint64, err error) {
+	ctx, span := startSpan(ctx, "MarketService.SaleOfferIDs",
+		attribute.String("flow_address", address.Hex()),
+		attribute.String("block", at.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	ids, err = m.contracts.MarketReadCollectionIDs(ctx, m.scriptCache.ExecutorAt(at, &height, address), address)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading sale offer ids = %w", err)
+	}
+
+	return ids, height, nil
+}
+
+// SaleOffer returns the offer for itemID in the market collection of address at the block referenced by at,
+// or nil if there was none, and the height it was read at
+func (m *MarketService) SaleOffer(ctx context.Context, address flow.Address, itemID uint64, at BlockRef) (offer *SaleOffer, height uint64, err error) {
+	ctx, span := startSpan(ctx, "MarketService.SaleOffer",
+		attribute.String("flow_address", address.Hex()),
+		attribute.Int64("item_id", int64(itemID)),
+		attribute.String("block", at.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	details, err := m.contracts.MarketReadSaleOfferDetails(ctx, m.scriptCache.ExecutorAt(at, &height, address), address, itemID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading sale offer = %w", err)
+	}
+	if details == nil {
+		return nil, height, nil
+	}
+
+	return &SaleOffer{details.SaleCompleted, details.SaleItemID, details.SalePrice}, height, nil
+}
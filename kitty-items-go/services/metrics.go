@@ -0,0 +1,151 @@
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "kitty_items"
+
+var (
+	transactionsSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transactions_submitted_total",
+		Help:      "Transactions accepted by the access node, by template.",
+	}, []string{"template"})
+
+	transactionsSealed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transactions_sealed_total",
+		Help:      "Submitted transactions that were sealed without error, by template.",
+	}, []string{"template"})
+
+	transactionsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "transactions_failed_total",
+		Help:      "Transactions that were rejected, reverted or never sealed, by template and stage.",
+	}, []string{"template", "stage"})
+
+	transactionSealDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "transaction_seal_duration_seconds",
+		Help:      "Time from submission until the transaction is sealed, by template.",
+		Buckets:   []float64{1, 2, 5, 10, 15, 20, 30, 45, 60, 90, 120, 300, 600},
+	}, []string{"template"})
+
+	proposalKeys = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "proposal_keys",
+		Help:      "Minter keys the service can propose transactions with, updated whenever the minter account is read.",
+	})
+
+	proposalKeysInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "proposal_keys_in_use",
+		Help:      "Minter keys leased to a transaction being prepared, signed and submitted.",
+	})
+
+	transactionsSending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "transactions_sending",
+		Help:      "Transactions currently being prepared, signed and submitted by the minter.",
+	})
+
+	accessNodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "access_node_errors_total",
+		Help:      "Failed access node RPCs, by method.",
+	}, []string{"method"})
+
+	accessNodeHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "access_node_healthy",
+		Help:      "Whether the access node passed its last health check, by address.",
+	}, []string{"address"})
+
+	accessNodeHeight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "access_node_sealed_height",
+		Help:      "Latest sealed height reported by the access node, by address.",
+	}, []string{"address"})
+
+	accessNodeFailovers = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "access_node_failovers_total",
+		Help:      "Calls retried on another access node after a transport error.",
+	})
+
+	indexerHeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "indexer_height",
+		Help:      "Last block height whose events were indexed.",
+	})
+
+	indexerLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "indexer_lag_blocks",
+		Help:      "Blocks between the latest sealed block and the last indexed one.",
+	})
+
+	indexerEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "indexer_events_total",
+		Help:      "Indexed events, by event type.",
+	}, []string{"type"})
+
+	scriptCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "script_cache_requests_total",
+		Help:      "Cached script executions, by result: hit, miss or coalesced with an identical call in flight.",
+	}, []string{"result"})
+
+	scriptCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "script_cache_entries",
+		Help:      "Script results currently cached.",
+	})
+
+	minterBalance = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "minter_balance_flow",
+		Help:      "FLOW balance of the minter account when it was last fetched.",
+	})
+
+	streamSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "stream_subscribers",
+		Help:      "Clients connected to the event stream.",
+	})
+
+	streamSubscribersDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stream_subscribers_dropped_total",
+		Help:      "Event stream clients disconnected for falling behind.",
+	})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by result: delivered, retried or dead.",
+	}, []string{"result"})
+
+	webhookQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "webhook_queued_deliveries",
+		Help:      "Webhook deliveries waiting for a worker.",
+	})
+
+	webhookDeadLetters = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "webhook_dead_letters",
+		Help:      "Webhook deliveries given up on, waiting to be replayed.",
+	})
+)
+
+// observeRPC counts err against the access node method that returned it
+func observeRPC(method string, err error) error {
+	if err != nil {
+		accessNodeErrors.WithLabelValues(method).Inc()
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/onflow/flow-go-sdk"
+)
+
+// ProposalKey is a minter account key of a ProposalKeyPool with the sequence number of the next transaction it proposes
+type ProposalKey struct {
+	Index          int
+	SequenceNumber uint64
+	// stale is set once a send failed in a way that may have left the local sequence number behind or ahead
+	// of the chain. The sequence number is read from the account again before the key is used next.
+	stale bool
+}
+
+// ProposalKeyPool leases the minter account keys transactions are proposed with, so that each key proposes a single
+// transaction at a time and the sequence numbers kept locally never collide. Lease blocks while every key is leased.
+type ProposalKeyPool struct {
+	keys []*ProposalKey
+	free chan *ProposalKey
+
+	// mu guards the stale flags, set by the tracking loop while keys may be leased
+	mu sync.Mutex
+}
+
+// NewProposalKeyPool returns a pool of the given keys, starting from their sequence numbers
+func NewProposalKeyPool(keys []*flow.AccountKey) *ProposalKeyPool {
+	p := &ProposalKeyPool{free: make(chan *ProposalKey, len(keys))}
+	for _, key := range keys {
+		proposalKey := &ProposalKey{Index: key.Index, SequenceNumber: key.SequenceNumber}
+		p.keys = append(p.keys, proposalKey)
+		p.free <- proposalKey
+	}
+	return p
+}
+
+// Lease takes a free key out of the pool, waiting for one to be released until ctx is done.
+// The key must be released once the transaction it proposes is submitted or abandoned.
+func (p *ProposalKeyPool) Lease(ctx context.Context) (*ProposalKey, error) {
+	select {
+	case key := <-p.free:
+		proposalKeysInUse.Inc()
+		return key, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("error waiting for a free proposal key = %w", ctx.Err())
+	}
+}
+
+// Release returns a leased key to the pool. submitted reports whether the transaction reached an access node,
+// which advances the sequence number. Otherwise the key is marked stale, as the node may have accepted it anyway.
+func (p *ProposalKeyPool) Release(key *ProposalKey, submitted bool) {
+	p.mu.Lock()
+	if submitted {
+		key.SequenceNumber++
+	} else {
+		key.stale = true
+	}
+	p.mu.Unlock()
+
+	proposalKeysInUse.Dec()
+	p.free <- key
+}
+
+// MarkStale has the sequence number of the key at index read again before its next use, after a transaction it
+// proposed expired or failed, as the chain then did not advance the sequence number the way it was counted locally
+func (p *ProposalKeyPool) MarkStale(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, key := range p.keys {
+		if key.Index == index {
+			key.stale = true
+		}
+	}
+}
+
+// Stale reports whether the sequence number of a leased key must be read again, clearing the flag
+func (p *ProposalKeyPool) Stale(key *ProposalKey) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := key.stale
+	key.stale = false
+	return stale
+}
+
+// Indexes returns the indexes of the keys of the pool
+func (p *ProposalKeyPool) Indexes() []int {
+	indexes := make([]int, len(p.keys))
+	for i, key := range p.keys {
+		indexes[i] = key.Index
+	}
+	return indexes
+}
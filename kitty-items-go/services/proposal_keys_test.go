@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposalKeyPool(t *testing.T) {
+	newPool := func() *ProposalKeyPool {
+		return NewProposalKeyPool([]*flow.AccountKey{{Index: 2, SequenceNumber: 5}, {Index: 4, SequenceNumber: 9}})
+	}
+
+	t.Run("Should lease each key once until it is released", func(t *testing.T) {
+		p := newPool()
+		first, err := p.Lease(context.Background())
+		require.NoError(t, err)
+		second, err := p.Lease(context.Background())
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{2, 4}, []int{first.Index, second.Index})
+
+		leased := make(chan *ProposalKey)
+		go func() {
+			key, _ := p.Lease(context.Background())
+			leased <- key
+		}()
+		select {
+		case <-leased:
+			t.Fatal("a key was leased twice")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		p.Release(first, true)
+		assert.Equal(t, first, <-leased)
+	})
+
+	t.Run("Should stop waiting for a key when the context is done", func(t *testing.T) {
+		p := NewProposalKeyPool([]*flow.AccountKey{{Index: 0}})
+		_, err := p.Lease(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = p.Lease(ctx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	})
+
+	t.Run("Should advance the sequence number of a key that submitted its transaction", func(t *testing.T) {
+		p := NewProposalKeyPool([]*flow.AccountKey{{Index: 0, SequenceNumber: 5}})
+		key, _ := p.Lease(context.Background())
+		p.Release(key, true)
+
+		key, _ = p.Lease(context.Background())
+		assert.Equal(t, uint64(6), key.SequenceNumber)
+		assert.False(t, p.Stale(key))
+	})
+
+	t.Run("Should mark a key stale when its transaction was not submitted", func(t *testing.T) {
+		p := NewProposalKeyPool([]*flow.AccountKey{{Index: 0, SequenceNumber: 5}})
+		key, _ := p.Lease(context.Background())
+		p.Release(key, false)
+
+		key, _ = p.Lease(context.Background())
+		assert.Equal(t, uint64(5), key.SequenceNumber)
+		assert.True(t, p.Stale(key))
+		assert.False(t, p.Stale(key), "the flag is cleared once reported")
+	})
+
+	t.Run("Should mark a released key stale by its index", func(t *testing.T) {
+		p := newPool()
+		p.MarkStale(4)
+
+		for range p.Indexes() {
+			key, _ := p.Lease(context.Background())
+			assert.Equal(t, key.Index == 4, p.Stale(key), "key %d", key.Index)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+)
+
+// StreamTransactionType is the type of the stream events carrying a TransactionUpdate
+const StreamTransactionType = "transaction"
+
+// StreamEvent is a message of the event stream, an IndexedEvent or a TransactionUpdate
+type StreamEvent struct {
+	// ID is unique within the process, see StreamService.Subscribe for resuming after it
+	ID string
+	// Type is the fully qualified event type, or StreamTransactionType
+	Type          string
+	TransactionID string
+	Addresses     []flow.Address
+	Data          interface{}
+
+	seq uint64
+}
+
+// Name is the short event type, e.g. `KittyItems.Deposit`, which is what clients filter and listen on
+func (e StreamEvent) Name() string {
+	return shortEventType(e.Type)
+}
+
+// StreamFilter selects the events a subscriber receives. Events must have one of EventTypes, if any, and
+// belong to one of TransactionIDs or name one of Addresses, if any of those are set. A zero filter matches everything.
+type StreamFilter struct {
+	TransactionIDs []string
+	Addresses      []flow.Address
+	// EventTypes are short types like `KittyItems.Deposit`, fully qualified types, or StreamTransactionType
+	EventTypes []string
+}
+
+// Matches reports whether the filter selects event
+func (f StreamFilter) Matches(event StreamEvent) bool {
+	if len(f.EventTypes) > 0 {
+		matched := false
+		for _, eventType := range f.EventTypes {
+			if eventType == event.Type || eventType == event.Name() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.TransactionIDs) == 0 && len(f.Addresses) == 0 {
+		return true
+	}
+	for _, id := range f.TransactionIDs {
+		if id == event.TransactionID {
+			return true
+		}
+	}
+	for _, address := range f.Addresses {
+		for _, named := range event.Addresses {
+			if address == named {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StreamService fans the indexed events and the transaction updates out to the stream subscribers and keeps
+// the most recent ones, so that a client reconnecting with the ID of the last event it saw misses nothing
+type StreamService struct {
+	// epoch tells the IDs of this process apart from the ones handed out before a restart
+	epoch string
+
+	mu            sync.Mutex
+	seq           uint64
+	history       []StreamEvent
+	historySize   int
+	subscriptions map[*StreamSubscription]struct{}
+	closed        bool
+}
+
+// StreamSubscription receives the events matching its filter until it is closed. Its channel is closed when
+// the subscriber falls too far behind or the service shuts down.
+type StreamSubscription struct {
+	filter  StreamFilter
+	events  chan StreamEvent
+	service *StreamService
+}
+
+// streamSubscriptionBuffer is how many events a subscriber may fall behind before it is dropped
+const streamSubscriptionBuffer = 256
+
+// NewStream keeps the last historySize events for resuming subscribers
+func NewStream(historySize int) *StreamService {
+	return &StreamService{
+		epoch:         strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize:   historySize,
+		subscriptions: make(map[*StreamSubscription]struct{}),
+	}
+}
+
+// PublishEvent sends an indexed event to the subscribers, it is meant to be subscribed to the indexer
+func (s *StreamService) PublishEvent(event IndexedEvent) {
+	s.publish(StreamEvent{
+		Type:          event.Type,
+		TransactionID: event.TransactionID,
+		Addresses:     event.Addresses,
+		Data:          event,
+	})
+}
+
+// PublishTransaction sends a transaction update to the subscribers, it is meant to be subscribed to the FlowService
+func (s *StreamService) PublishTransaction(update TransactionUpdate) {
+	s.publish(StreamEvent{
+		Type:          StreamTransactionType,
+		TransactionID: update.TransactionID,
+		Data:          update,
+	})
+}
+
+func (s *StreamService) publish(event StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.seq++
+	event.seq = s.seq
+	event.ID = fmt.Sprintf("%s-%d", s.epoch, s.seq)
+
+	if s.historySize > 0 {
+		if len(s.history) == s.historySize {
+			s.history = append(s.history[:0], s.history[1:]...)
+		}
+		s.history = append(s.history, event)
+	}
+
+	for subscription := range s.subscriptions {
+		if !subscription.filter.Matches(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			// The client reconnects with the ID of the last event it got and resumes from the history
+			streamSubscribersDropped.Inc()
+			s.remove(subscription)
+		}
+	}
+}
+
+// Subscribe starts a subscription with filter. When lastEventID is set, the events published after it that
+// match the filter are returned to be sent first, and complete reports whether the history still held them all.
+func (s *StreamService) Subscribe(filter StreamFilter, lastEventID string) (subscription *StreamSubscription, missed []StreamEvent, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription = &StreamSubscription{
+		filter:  filter,
+		events:  make(chan StreamEvent, streamSubscriptionBuffer),
+		service: s,
+	}
+	if s.closed {
+		close(subscription.events)
+		return subscription, nil, true
+	}
+
+	s.subscriptions[subscription] = struct{}{}
+	streamSubscribers.Inc()
+
+	if lastEventID == "" {
+		return subscription, nil, true
+	}
+
+	after, ok := s.parseID(lastEventID)
+	if !ok {
+		after = 0
+	}
+	complete = ok && (after >= s.seq || (len(s.history) > 0 && s.history[0].seq <= after+1))
+
+	for _, event := range s.history {
+		if event.seq > after && filter.Matches(event) {
+			missed = append(missed, event)
+		}
+	}
+
+	return subscription, missed, complete
+}
+
+// Close ends every subscription, for the HTTP server to shut down without waiting for the streams
+func (s *StreamService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for subscription := range s.subscriptions {
+		s.remove(subscription)
+	}
+}
+
+// remove closes a subscription, must be called with the lock held
+func (s *StreamService) remove(subscription *StreamSubscription) {
+	if _, ok := s.subscriptions[subscription]; !ok {
+		return
+	}
+	delete(s.subscriptions, subscription)
+	close(subscription.events)
+	streamSubscribers.Dec()
+}
+
+// parseID returns the sequence number of an ID handed out by this process
+func (s *StreamService) parseID(id string) (uint64, bool) {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 || parts[0] != s.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	return seq, err == nil
+}
+
+// Events returns the channel the matching events are delivered on
+func (s *StreamSubscription) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *StreamSubscription) Close() {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+
+	s.service.remove(s)
+}
+
+// shortEventType drops the `A.<address>.` prefix of a fully qualified event type
+func shortEventType(eventType string) string {
+	parts := strings.SplitN(eventType, ".", 3)
+	if len(parts) == 3 && parts[0] == "A" {
+		return parts[2]
+	}
+	return eventType
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/onflow/flow-go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// publishTransactions publishes a transaction update for each ID and returns the stream IDs they were given
+func publishTransactions(t *testing.T, s *StreamService, transactionIDs ...string) []string {
+	subscription, _, _ := s.Subscribe(StreamFilter{}, "")
+	defer subscription.Close()
+
+	ids := make([]string, len(transactionIDs))
+	for i, transactionID := range transactionIDs {
+		s.PublishTransaction(TransactionUpdate{TransactionID: transactionID, Status: "PENDING"})
+		event := <-subscription.Events()
+		require.Equal(t, transactionID, event.TransactionID)
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func transactionIDs(events []StreamEvent) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.TransactionID)
+	}
+	return ids
+}
+
+func TestStreamServiceSubscribe(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter StreamFilter
+		// lastEventID picks the ID to resume after out of the IDs of the published transactions a to e
+		lastEventID func(ids []string) string
+		missed      []string
+		complete    bool
+	}{
+		{
+			name:        "Should not resume without a last event ID",
+			lastEventID: func([]string) string { return "" },
+			complete:    true,
+		},
+		{
+			name:        "Should resume after an event in the history",
+			lastEventID: func(ids []string) string { return ids[2] },
+			missed:      []string{"d", "e"},
+			complete:    true,
+		},
+		{
+			name:        "Should resume after the event just before the history",
+			lastEventID: func(ids []string) string { return ids[1] },
+			missed:      []string{"c", "d", "e"},
+			complete:    true,
+		},
+		{
+			name:        "Should resume after the last event with nothing missed",
+			lastEventID: func(ids []string) string { return ids[4] },
+			complete:    true,
+		},
+		{
+			name:        "Should report events trimmed from the history as missed",
+			lastEventID: func(ids []string) string { return ids[0] },
+			missed:      []string{"c", "d", "e"},
+		},
+		{
+			name:        "Should replay the whole history for an ID of another process",
+			lastEventID: func([]string) string { return "previous-3" },
+			missed:      []string{"c", "d", "e"},
+		},
+		{
+			name:        "Should replay the whole history for an ID that does not parse",
+			lastEventID: func(ids []string) string { return ids[2] + "x" },
+			missed:      []string{"c", "d", "e"},
+		},
+		{
+			name:        "Should only resume with the events matching the filter",
+			filter:      StreamFilter{TransactionIDs: []string{"b", "d"}},
+			lastEventID: func(ids []string) string { return ids[1] },
+			missed:      []string{"d"},
+			complete:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewStream(3)
+			ids := publishTransactions(t, s, "a", "b", "c", "d", "e")
+
+			subscription, missed, complete := s.Subscribe(c.filter, c.lastEventID(ids))
+			defer subscription.Close()
+
+			assert.Equal(t, c.missed, transactionIDs(missed))
+			assert.Equal(t, c.complete, complete)
+
+			// The subscription receives what is published next
+			s.PublishTransaction(TransactionUpdate{TransactionID: "d"})
+			event := <-subscription.Events()
+			assert.Equal(t, "d", event.TransactionID)
+		})
+	}
+}
+
+func TestStreamFilterMatches(t *testing.T) {
+	event := StreamEvent{
+		Type:          "A.01cf0e2f2f715450.KittyItems.Deposit",
+		TransactionID: "a",
+		Addresses:     []flow.Address{testRecipientAddress},
+	}
+
+	cases := []struct {
+		name     string
+		filter   StreamFilter
+		expected bool
+	}{
+		{"Should match everything with a zero filter", StreamFilter{}, true},
+		{"Should match a short event type", StreamFilter{EventTypes: []string{"KittyItems.Deposit"}}, true},
+		{"Should match a fully qualified event type", StreamFilter{EventTypes: []string{event.Type}}, true},
+		{"Should not match another event type", StreamFilter{EventTypes: []string{"KittyItems.Withdraw", StreamTransactionType}}, false},
+		{"Should match a transaction ID", StreamFilter{TransactionIDs: []string{"b", "a"}}, true},
+		{"Should match an address named by the event", StreamFilter{Addresses: []flow.Address{testRecipientAddress}}, true},
+		{"Should match a transaction ID or an address", StreamFilter{TransactionIDs: []string{"b"}, Addresses: []flow.Address{testRecipientAddress}}, true},
+		{"Should not match other transactions and addresses", StreamFilter{TransactionIDs: []string{"b"}, Addresses: []flow.Address{testMinterAddress}}, false},
+		{
+			"Should require the event type and the transaction to match",
+			StreamFilter{EventTypes: []string{"KittyItems.Withdraw"}, TransactionIDs: []string{"a"}},
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, c.filter.Matches(event))
+		})
+	}
+}
+
+func TestStreamServicePublish(t *testing.T) {
+	t.Run("Should close the subscription of a subscriber that fell behind", func(t *testing.T) {
+		s := NewStream(10)
+		slow, _, _ := s.Subscribe(StreamFilter{}, "")
+		other, _, _ := s.Subscribe(StreamFilter{TransactionIDs: []string{"other"}}, "")
+		defer other.Close()
+
+		for i := 0; i < streamSubscriptionBuffer+1; i++ {
+			s.PublishTransaction(TransactionUpdate{TransactionID: "a"})
+		}
+
+		received := 0
+		for range slow.Events() {
+			received++
+		}
+		assert.Equal(t, streamSubscriptionBuffer, received)
+
+		// Subscribers that kept up are not dropped
+		s.PublishTransaction(TransactionUpdate{TransactionID: "other"})
+		event, ok := <-other.Events()
+		require.True(t, ok)
+		assert.Equal(t, "other", event.TransactionID)
+
+		// Closing a dropped subscription again is harmless
+		slow.Close()
+	})
+
+	t.Run("Should close every subscription on Close", func(t *testing.T) {
+		s := NewStream(10)
+		subscription, _, _ := s.Subscribe(StreamFilter{}, "")
+		s.Close()
+
+		_, ok := <-subscription.Events()
+		assert.False(t, ok)
+
+		s.PublishTransaction(TransactionUpdate{TransactionID: "a"})
+		late, missed, complete := s.Subscribe(StreamFilter{}, "")
+		_, ok = <-late.Events()
+		assert.False(t, ok)
+		assert.Empty(t, missed)
+		assert.True(t, complete)
+	})
+
+	t.Run("Should keep no history with a size of 0", func(t *testing.T) {
+		s := NewStream(0)
+		ids := publishTransactions(t, s, "a", "b")
+
+		subscription, missed, complete := s.Subscribe(StreamFilter{}, ids[0])
+		defer subscription.Close()
+		assert.Empty(t, missed)
+		assert.False(t, complete)
+	})
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onflow/kitty-items/lib/go/kittyitems"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every span until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// spanNamed returns the ended span called name
+func spanNamed(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "no %s span", name)
+	return nil
+}
+
+func TestServiceSpans(t *testing.T) {
+	t.Run("Should emit a span for a mint with its allowance check as a child", func(t *testing.T) {
+		recorder := recordSpans(t)
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		fake.SetScriptResult(kittyitems.KibbleGetMinterAllowanceScript, testUFix64(t, "100.0"))
+
+		_, _, err := kibbles.Mint(context.Background(), testRecipientAddress, 10)
+		require.NoError(t, err)
+
+		mint := spanNamed(t, recorder, "KibblesService.Mint")
+		assert.Contains(t, mint.Attributes(), attribute.String("flow_address", testRecipientAddress.Hex()))
+		assert.Contains(t, mint.Attributes(), attribute.Int64("amount", 10))
+		assert.Equal(t, codes.Unset, mint.Status().Code)
+
+		allowance := spanNamed(t, recorder, "KibblesService.MinterAllowance")
+		assert.Equal(t, mint.SpanContext().TraceID(), allowance.SpanContext().TraceID())
+		assert.Equal(t, mint.SpanContext().SpanID(), allowance.Parent().SpanID())
+	})
+
+	t.Run("Should record the error of a failed call on its span", func(t *testing.T) {
+		recorder := recordSpans(t)
+		kibbles, fake := newTestKibbles(t, LimitsConfig{})
+		fake.SetScriptError(kittyitems.KibbleGetBalanceScript, errors.New("access node unavailable"))
+
+		_, _, err := kibbles.Balance(context.Background(), testRecipientAddress, BlockRef{})
+		require.Error(t, err)
+
+		balance := spanNamed(t, recorder, "KibblesService.Balance")
+		assert.Contains(t, balance.Attributes(), attribute.String("block", BlockRef{}.String()))
+		assert.Equal(t, codes.Error, balance.Status().Code)
+		assert.Equal(t, err.Error(), balance.Status().Description)
+		require.Len(t, balance.Events(), 1)
+		assert.Equal(t, "exception", balance.Events()[0].Name)
+	})
+}
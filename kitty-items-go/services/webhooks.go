@@ -0,0 +1,541 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go-sdk"
+)
+
+// Headers set on every webhook delivery
+const (
+	WebhookEventHeader     = "X-Kitty-Items-Event"
+	WebhookDeliveryHeader  = "X-Kitty-Items-Delivery"
+	WebhookTimestampHeader = "X-Kitty-Items-Timestamp"
+	// WebhookSignatureHeader is `sha256=<hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret>`
+	WebhookSignatureHeader = "X-Kitty-Items-Signature"
+)
+
+var (
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrDeliveryNotFound  = errors.New("delivery not found")
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidSignature  = errors.New("invalid webhook signature")
+	errDeliveryPermanent = errors.New("delivery rejected")
+)
+
+const webhookSignaturePrefix = "sha256="
+
+type WebhookConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	Workers        int
+}
+
+// Webhook is a registered receiver of the indexed events matching its filter. The secret is kept in clear
+// since it is needed to sign every delivery, the file holding it is only readable by its owner.
+type Webhook struct {
+	ID         string         `json:"id"`
+	URL        string         `json:"url"`
+	EventTypes []string       `json:"event_types"`
+	Addresses  []flow.Address `json:"addresses"`
+	Secret     string         `json:"secret,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Matches reports whether the webhook receives event. Addresses match the accounts named by any event of the
+// transaction, so a seller filtering on SaleOfferAccepted is notified even though the event only names the item.
+func (w *Webhook) Matches(event IndexedEvent) bool {
+	addresses := event.TransactionAddresses
+	if addresses == nil {
+		addresses = event.Addresses
+	}
+	filter := StreamFilter{Addresses: w.Addresses, EventTypes: w.EventTypes}
+	return filter.Matches(StreamEvent{Type: event.Type, Addresses: addresses})
+}
+
+// WebhookDelivery is an event on its way to a webhook. Deliveries failing MaxAttempts times, or rejected
+// with a 4xx status, are kept as dead letters until they are replayed.
+type WebhookDelivery struct {
+	ID            string       `json:"id"`
+	WebhookID     string       `json:"webhook_id"`
+	Event         IndexedEvent `json:"event"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	LastAttemptAt *time.Time   `json:"last_attempt_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to webhooks
+type WebhookPayload struct {
+	DeliveryID string       `json:"delivery_id"`
+	WebhookID  string       `json:"webhook_id"`
+	Event      IndexedEvent `json:"event"`
+}
+
+type webhooksFile struct {
+	Webhooks    []*Webhook         `json:"webhooks"`
+	DeadLetters []*WebhookDelivery `json:"dead_letters"`
+}
+
+// WebhookService delivers the indexed events to the registered webhooks. Webhooks and dead letters are kept
+// in a local JSON file, deliveries still being retried only live in memory and are lost on restart.
+type WebhookService struct {
+	path   string
+	conf   WebhookConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	webhooks    []*Webhook
+	deadLetters []*WebhookDelivery
+	queue       []*WebhookDelivery
+	wake        chan struct{}
+}
+
+func NewWebhooks(path string, conf WebhookConfig) (*WebhookService, error) {
+	w := &WebhookService{
+		path:   path,
+		conf:   conf,
+		client: &http.Client{Timeout: conf.Timeout},
+		wake:   make(chan struct{}, 1),
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading webhooks file = %w", err)
+	}
+
+	var file webhooksFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("error decoding webhooks file = %w", err)
+	}
+	w.webhooks, w.deadLetters = file.Webhooks, file.DeadLetters
+	webhookDeadLetters.Set(float64(len(w.deadLetters)))
+
+	return w, nil
+}
+
+// Register adds a webhook receiving the events of eventTypes naming one of addresses, either filter may be
+// empty to match everything. A secret is generated when none is given.
+func (w *WebhookService) Register(rawURL string, eventTypes []string, addresses []flow.Address, secret string) (*Webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		if secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &Webhook{
+		ID:         id,
+		URL:        rawURL,
+		EventTypes: eventTypes,
+		Addresses:  addresses,
+		Secret:     secret,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.webhooks = append(w.webhooks, webhook)
+	if err := w.save(); err != nil {
+		return nil, err
+	}
+
+	copied := *webhook
+	return &copied, nil
+}
+
+// Delete removes a webhook with its dead letters
+func (w *WebhookService) Delete(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, webhook := range w.webhooks {
+		if webhook.ID != id {
+			continue
+		}
+		w.webhooks = append(w.webhooks[:i], w.webhooks[i+1:]...)
+
+		deadLetters := w.deadLetters[:0]
+		for _, delivery := range w.deadLetters {
+			if delivery.WebhookID != id {
+				deadLetters = append(deadLetters, delivery)
+			}
+		}
+		w.deadLetters = deadLetters
+		webhookDeadLetters.Set(float64(len(w.deadLetters)))
+
+		return w.save()
+	}
+
+	return ErrWebhookNotFound
+}
+
+// List returns every webhook without its secret
+func (w *WebhookService) List() []Webhook {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	webhooks := make([]Webhook, 0, len(w.webhooks))
+	for _, webhook := range w.webhooks {
+		copied := *webhook
+		copied.Secret = ""
+		webhooks = append(webhooks, copied)
+	}
+	return webhooks
+}
+
+// Get returns the webhook with the given id, without its secret
+func (w *WebhookService) Get(id string) (*Webhook, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	webhook := w.find(id)
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	copied := *webhook
+	copied.Secret = ""
+	return &copied, nil
+}
+
+// DeadLetters returns the deliveries to the webhook that were given up on
+func (w *WebhookService) DeadLetters(webhookID string) ([]WebhookDelivery, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.find(webhookID) == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, delivery := range w.deadLetters {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// ReplayDeadLetter takes a dead letter of the webhook out of the list and delivers it again
+func (w *WebhookService) ReplayDeadLetter(webhookID, deliveryID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, delivery := range w.deadLetters {
+		if delivery.ID != deliveryID || delivery.WebhookID != webhookID {
+			continue
+		}
+		w.deadLetters = append(w.deadLetters[:i], w.deadLetters[i+1:]...)
+		webhookDeadLetters.Set(float64(len(w.deadLetters)))
+		if err := w.save(); err != nil {
+			return err
+		}
+
+		delivery.Attempts = 0
+		w.enqueue(delivery)
+		return nil
+	}
+
+	return ErrDeliveryNotFound
+}
+
+// ReplayEvents delivers the events matching the webhook again, e.g. past events fetched from the indexer,
+// and returns how many deliveries were queued
+func (w *WebhookService) ReplayEvents(webhookID string, events []IndexedEvent) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	webhook := w.find(webhookID)
+	if webhook == nil {
+		return 0, ErrWebhookNotFound
+	}
+
+	queued := 0
+	for _, event := range events {
+		if !webhook.Matches(event) {
+			continue
+		}
+		delivery, err := newWebhookDelivery(webhook, event)
+		if err != nil {
+			return queued, err
+		}
+		w.enqueue(delivery)
+		queued++
+	}
+	return queued, nil
+}
+
+// HandleEvent queues a delivery of event to every matching webhook, it is meant to be subscribed to the indexer
+func (w *WebhookService) HandleEvent(event IndexedEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, webhook := range w.webhooks {
+		if !webhook.Matches(event) {
+			continue
+		}
+		delivery, err := newWebhookDelivery(webhook, event)
+		if err != nil {
+			Logger(context.Background()).Error().Err(err).Str("webhook_id", webhook.ID).Msg("error creating webhook delivery")
+			continue
+		}
+		w.enqueue(delivery)
+	}
+}
+
+// Run delivers the queued events with the configured number of workers until ctx is done.
+// Deliveries still queued or waiting for a retry at that point are logged and dropped.
+func (w *WebhookService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.conf.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				delivery, ok := w.next(ctx)
+				if !ok {
+					return
+				}
+				w.attempt(ctx, delivery)
+			}
+		}()
+	}
+	wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.queue) > 0 {
+		Logger(ctx).Warn().Int("deliveries", len(w.queue)).Msg("webhook deliveries still queued at shutdown")
+	}
+}
+
+// next waits for a queued delivery, it returns false when ctx is done
+func (w *WebhookService) next(ctx context.Context) (*WebhookDelivery, bool) {
+	for {
+		w.mu.Lock()
+		if len(w.queue) > 0 {
+			delivery := w.queue[0]
+			w.queue = w.queue[1:]
+			webhookQueued.Set(float64(len(w.queue)))
+			more := len(w.queue) > 0
+			w.mu.Unlock()
+			if more {
+				w.signal()
+			}
+			return delivery, true
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *WebhookService) attempt(ctx context.Context, delivery *WebhookDelivery) {
+	logger := Logger(ctx).With().Str("webhook_id", delivery.WebhookID).Str("delivery_id", delivery.ID).Logger()
+
+	w.mu.Lock()
+	webhook := w.find(delivery.WebhookID)
+	var target Webhook
+	if webhook != nil {
+		target = *webhook
+	}
+	w.mu.Unlock()
+	if webhook == nil {
+		// The webhook was deleted while the delivery was queued
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	err := w.deliver(ctx, &target, delivery)
+	if err == nil {
+		webhookDeliveries.WithLabelValues("delivered").Inc()
+		logger.Info().Int("attempts", delivery.Attempts).Msg("webhook delivered")
+		return
+	}
+	delivery.LastError = err.Error()
+
+	if errors.Is(err, errDeliveryPermanent) || delivery.Attempts >= w.conf.MaxAttempts {
+		webhookDeliveries.WithLabelValues("dead").Inc()
+		logger.Warn().Err(err).Int("attempts", delivery.Attempts).Msg("webhook delivery moved to dead letters")
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.deadLetters = append(w.deadLetters, delivery)
+		webhookDeadLetters.Set(float64(len(w.deadLetters)))
+		if err := w.save(); err != nil {
+			logger.Error().Err(err).Msg("error saving webhook dead letters")
+		}
+		return
+	}
+
+	backoff := w.backoff(delivery.Attempts)
+	webhookDeliveries.WithLabelValues("retried").Inc()
+	logger.Info().Err(err).Int("attempts", delivery.Attempts).Dur("backoff", backoff).Msg("webhook delivery failed, retrying")
+
+	time.AfterFunc(backoff, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.enqueue(delivery)
+	})
+}
+
+// deliver POSTs the signed payload. Network errors, 429 and 5xx statuses are retried, other statuses are permanent.
+func (w *WebhookService) deliver(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery) error {
+	body, err := json.Marshal(&WebhookPayload{delivery.ID, delivery.WebhookID, delivery.Event})
+	if err != nil {
+		return fmt.Errorf("%w = %s", errDeliveryPermanent, err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w = %s", errDeliveryPermanent, err)
+	}
+	request = request.WithContext(ctx)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, shortEventType(delivery.Event.Type))
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, body))
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64<<10))
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return fmt.Errorf("webhook responded %s", response.Status)
+	default:
+		return fmt.Errorf("%w = webhook responded %s", errDeliveryPermanent, response.Status)
+	}
+}
+
+// backoff doubles the wait after every attempt up to MaxBackoff, with up to 20% of jitter
+func (w *WebhookService) backoff(attempts int) time.Duration {
+	backoff := w.conf.InitialBackoff
+	for i := 1; i < attempts && backoff < w.conf.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > w.conf.MaxBackoff {
+		backoff = w.conf.MaxBackoff
+	}
+	return backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+}
+
+// enqueue adds a delivery and wakes a worker, must be called with the lock held
+func (w *WebhookService) enqueue(delivery *WebhookDelivery) {
+	w.queue = append(w.queue, delivery)
+	webhookQueued.Set(float64(len(w.queue)))
+	w.signal()
+}
+
+func (w *WebhookService) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// find returns the webhook with the given id, must be called with the lock held
+func (w *WebhookService) find(id string) *Webhook {
+	for _, webhook := range w.webhooks {
+		if webhook.ID == id {
+			return webhook
+		}
+	}
+	return nil
+}
+
+// save writes the webhooks and dead letters to disk, must be called with the lock held
+func (w *WebhookService) save() error {
+	contents, err := json.MarshalIndent(&webhooksFile{w.webhooks, w.deadLetters}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(w.path, contents, 0600); err != nil {
+		return fmt.Errorf("error writing webhooks file = %w", err)
+	}
+
+	return nil
+}
+
+func newWebhookDelivery(webhook *Webhook, event IndexedEvent) (*WebhookDelivery, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookDelivery{ID: id, WebhookID: webhook.ID, Event: event, CreatedAt: time.Now().UTC()}, nil
+}
+
+// SignWebhook returns the signature header value of a delivery body sent at timestamp
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the signature of a delivery body, and that it was sent less than maxAge ago
+func VerifyWebhook(secret, timestamp, signature string, body []byte, maxAge time.Duration) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(sentAt, 0)); age > maxAge || age < -maxAge {
+		return fmt.Errorf("%w = timestamp is %s old", ErrInvalidSignature, age.Round(time.Second))
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
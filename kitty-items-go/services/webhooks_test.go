@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReceiver is a webhook receiver answering every delivery with status, it counts the deliveries
+// and checks their signature
+type testReceiver struct {
+	*httptest.Server
+	t *testing.T
+
+	mu         sync.Mutex
+	status     int
+	secret     string
+	deliveries int
+}
+
+func newTestReceiver(t *testing.T, status int) *testReceiver {
+	r := &testReceiver{t: t, status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		body, err := ioutil.ReadAll(request.Body)
+		require.NoError(t, err)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		assert.NoError(t, VerifyWebhook(r.secret, request.Header.Get(WebhookTimestampHeader), request.Header.Get(WebhookSignatureHeader), body, time.Minute))
+		r.deliveries++
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *testReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *testReceiver) deliveryCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries
+}
+
+// newTestWebhooks returns a running service keeping its file in a temporary directory, with a webhook registered
+// for everything sent to receiver
+func newTestWebhooks(t *testing.T, path string, receiver *testReceiver) (*WebhookService, *Webhook) {
+	w, err := NewWebhooks(path, WebhookConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		Timeout:        time.Second,
+		Workers:        2,
+	})
+	require.NoError(t, err)
+
+	webhook, err := w.Register(receiver.URL, nil, nil, "secret")
+	require.NoError(t, err)
+	receiver.secret = webhook.Secret
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return w, webhook
+}
+
+// awaitDeadLetters waits until the webhook has count dead letters and returns them
+func awaitDeadLetters(t *testing.T, w *WebhookService, webhookID string, count int) []WebhookDelivery {
+	var deadLetters []WebhookDelivery
+	require.Eventually(t, func() bool {
+		var err error
+		deadLetters, err = w.DeadLetters(webhookID)
+		require.NoError(t, err)
+		return len(deadLetters) == count
+	}, 5*time.Second, time.Millisecond)
+	return deadLetters
+}
+
+func TestWebhookServiceDelivery(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		// attempts is how many times the delivery is made, dead is whether it ends in the dead letters
+		attempts int
+		dead     bool
+	}{
+		{"Should deliver an event once to a webhook that accepts it", http.StatusNoContent, 1, false},
+		{"Should retry a server error up to the maximum attempts", http.StatusInternalServerError, 3, true},
+		{"Should retry an unavailable webhook up to the maximum attempts", http.StatusServiceUnavailable, 3, true},
+		{"Should retry a rate limited delivery up to the maximum attempts", http.StatusTooManyRequests, 3, true},
+		{"Should not retry a delivery the webhook rejects", http.StatusBadRequest, 1, true},
+		{"Should not retry a delivery to a webhook that is gone", http.StatusGone, 1, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			receiver := newTestReceiver(t, c.status)
+			w, webhook := newTestWebhooks(t, filepath.Join(t.TempDir(), "webhooks.json"), receiver)
+
+			w.HandleEvent(IndexedEvent{Type: testEventType, TransactionID: "a"})
+
+			require.Eventually(t, func() bool { return receiver.deliveryCount() == c.attempts }, 5*time.Second, time.Millisecond)
+			if !c.dead {
+				time.Sleep(20 * time.Millisecond)
+				assert.Equal(t, c.attempts, receiver.deliveryCount(), "no delivery is repeated")
+				deadLetters, err := w.DeadLetters(webhook.ID)
+				require.NoError(t, err)
+				assert.Empty(t, deadLetters)
+				return
+			}
+
+			deadLetters := awaitDeadLetters(t, w, webhook.ID, 1)
+			assert.Equal(t, c.attempts, deadLetters[0].Attempts)
+			assert.Contains(t, deadLetters[0].LastError, http.StatusText(c.status))
+			assert.Equal(t, "a", deadLetters[0].Event.TransactionID)
+			assert.Equal(t, c.attempts, receiver.deliveryCount(), "no delivery is made once dead")
+		})
+	}
+}
+
+func TestWebhookServiceBackoff(t *testing.T) {
+	w := &WebhookService{conf: WebhookConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+
+	cases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{20, 5 * time.Second},
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			backoff := w.backoff(c.attempts)
+			assert.GreaterOrEqual(t, int64(backoff), int64(c.expected), "attempt %d", c.attempts)
+			assert.LessOrEqual(t, int64(backoff), int64(c.expected+c.expected/5), "attempt %d", c.attempts)
+		}
+	}
+}
+
+func TestWebhookServiceDeadLetters(t *testing.T) {
+	t.Run("Should deliver a replayed dead letter again from its first attempt", func(t *testing.T) {
+		receiver := newTestReceiver(t, http.StatusBadRequest)
+		w, webhook := newTestWebhooks(t, filepath.Join(t.TempDir(), "webhooks.json"), receiver)
+		w.HandleEvent(IndexedEvent{Type: testEventType})
+		deadLetter := awaitDeadLetters(t, w, webhook.ID, 1)[0]
+
+		// Failing again, the replay gets the maximum attempts anew
+		receiver.setStatus(http.StatusInternalServerError)
+		require.NoError(t, w.ReplayDeadLetter(webhook.ID, deadLetter.ID))
+
+		replayed := awaitDeadLetters(t, w, webhook.ID, 1)[0]
+		assert.Equal(t, deadLetter.ID, replayed.ID)
+		assert.Equal(t, 3, replayed.Attempts)
+		assert.Equal(t, 4, receiver.deliveryCount())
+
+		receiver.setStatus(http.StatusOK)
+		require.NoError(t, w.ReplayDeadLetter(webhook.ID, deadLetter.ID))
+		require.Eventually(t, func() bool { return receiver.deliveryCount() == 5 }, 5*time.Second, time.Millisecond)
+		deadLetters, err := w.DeadLetters(webhook.ID)
+		require.NoError(t, err)
+		assert.Empty(t, deadLetters)
+	})
+
+	t.Run("Should not replay a dead letter of another webhook", func(t *testing.T) {
+		receiver := newTestReceiver(t, http.StatusBadRequest)
+		w, webhook := newTestWebhooks(t, filepath.Join(t.TempDir(), "webhooks.json"), receiver)
+		w.HandleEvent(IndexedEvent{Type: testEventType})
+		deadLetter := awaitDeadLetters(t, w, webhook.ID, 1)[0]
+
+		other, err := w.Register(receiver.URL, nil, nil, "")
+		require.NoError(t, err)
+		assert.True(t, errors.Is(w.ReplayDeadLetter(other.ID, deadLetter.ID), ErrDeliveryNotFound))
+		assert.True(t, errors.Is(w.ReplayDeadLetter(webhook.ID, "unknown"), ErrDeliveryNotFound))
+	})
+
+	t.Run("Should keep the webhooks and their dead letters across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "webhooks.json")
+		receiver := newTestReceiver(t, http.StatusBadRequest)
+		w, webhook := newTestWebhooks(t, path, receiver)
+		w.HandleEvent(IndexedEvent{Type: testEventType, TransactionID: "a"})
+		deadLetter := awaitDeadLetters(t, w, webhook.ID, 1)[0]
+
+		restarted, err := NewWebhooks(path, WebhookConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, w.List(), restarted.List())
+		deadLetters, err := restarted.DeadLetters(webhook.ID)
+		require.NoError(t, err)
+		require.Len(t, deadLetters, 1)
+		assert.Equal(t, deadLetter.ID, deadLetters[0].ID)
+		assert.Equal(t, "a", deadLetters[0].Event.TransactionID)
+		assert.Equal(t, "secret", restarted.find(webhook.ID).Secret, "the secret is kept to sign the deliveries")
+	})
+
+	t.Run("Should delete the dead letters of a deleted webhook", func(t *testing.T) {
+		receiver := newTestReceiver(t, http.StatusBadRequest)
+		w, webhook := newTestWebhooks(t, filepath.Join(t.TempDir(), "webhooks.json"), receiver)
+		w.HandleEvent(IndexedEvent{Type: testEventType})
+		awaitDeadLetters(t, w, webhook.ID, 1)
+
+		require.NoError(t, w.Delete(webhook.ID))
+		_, err := w.DeadLetters(webhook.ID)
+		assert.True(t, errors.Is(err, ErrWebhookNotFound))
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		assert.Empty(t, w.deadLetters)
+	})
+}
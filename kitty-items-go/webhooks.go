@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/dapperlabs/kitty-items-go/services"
+)
+
+const webhooksUsage = `usage: kitty-items-go webhooks <command> [flags]
+
+commands:
+  receive -secret <secret> [-listen :9090]   run a local receiver that checks signatures and prints deliveries`
+
+// webhookMaxAge is how old a delivery timestamp may be before the receiver rejects it as a replay
+const webhookMaxAge = 5 * time.Minute
+
+// runWebhooksCommand implements the `webhooks` subcommand, a local receiver to try webhooks against
+func runWebhooksCommand(args []string) error {
+	fs := flag.NewFlagSet("webhooks", flag.ExitOnError)
+	listen := fs.String("listen", ":9090", "address the receiver listens on")
+	secret := fs.String("secret", "", "secret of the webhook, as returned on registration")
+	fail := fs.Bool("fail", false, "answer 500 to every delivery, to watch retries and dead letters")
+
+	if len(args) == 0 || args[0] != "receive" {
+		return errors.New(webhooksUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *secret == "" {
+		return errors.New(webhooksUsage)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "error reading body", http.StatusBadRequest)
+			return
+		}
+
+		err = services.VerifyWebhook(*secret, r.Header.Get(services.WebhookTimestampHeader), r.Header.Get(services.WebhookSignatureHeader), body, webhookMaxAge)
+		if err != nil {
+			fmt.Printf("rejected delivery %s: %s\n", r.Header.Get(services.WebhookDeliveryHeader), err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var indented bytes.Buffer
+		json.Indent(&indented, body, "", "  ")
+		fmt.Printf("%s delivery %s\n%s\n", r.Header.Get(services.WebhookEventHeader), r.Header.Get(services.WebhookDeliveryHeader), indented.String())
+
+		if *fail {
+			http.Error(w, "failing on purpose", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	fmt.Printf("receiving webhooks on %s\n", *listen)
+	return http.ListenAndServe(*listen, handler)
+}